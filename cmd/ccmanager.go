@@ -3,31 +3,248 @@ package main
 // CCmanager - Cloud Control instance manager
 
 import (
+	"ccmanager/internal"
 	"ccmanager/internal/adapters"
+	"ccmanager/internal/config"
+	"ccmanager/internal/headless"
 	"ccmanager/internal/models"
+	"ccmanager/internal/versions"
 	"fmt"
 	"github.com/alexflint/go-arg"
 	"github.com/charmbracelet/bubbles/list"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/docker/compose/v2/pkg/api"
 	"os"
+	"time"
 )
 
+// instanceArgs holds the arguments of subcommands working on a single instance
+type instanceArgs struct {
+	Name string `arg:"positional,required" help:"Name of the instance or its path if the name is not unique"`
+}
+
+// logsArgs holds the arguments of the logs subcommand
+type logsArgs struct {
+	instanceArgs
+	Follow  bool     `arg:"-f,--follow" help:"Follow new log lines"`
+	Service []string `arg:"--service,separate" help:"Only show the log of this service (can be used multiple times)"`
+	Since   string   `arg:"--since" help:"Only show log lines since a timestamp (e.g. 2024-01-02T13:23:37Z) or relative duration (e.g. 42m)"`
+	Tail    string   `arg:"-n,--tail" help:"Number of lines to show from the end of the log of each container"`
+}
+
+// execArgs holds the arguments of the exec subcommand
+type execArgs struct {
+	instanceArgs
+	Service string   `arg:"-s,--service" default:"cli" help:"Service running the command"`
+	Command []string `arg:"positional" help:"Command to run, separated from the options by -- (defaults to a shell)"`
+}
+
 func main() {
 	var args struct {
-		BasePath           []string `arg:"required,env:CCMANAGER_BASEPATH,separate" help:"Paths where to find CloudControl docker compose folders"`
-		ContainerSeparator string   `default:"-" arg:"env:CCMANAGER_SEP" help:"Separator used in docker compose container names"`
+		Config             string        `arg:"--config,env:CCMANAGER_CONFIG" help:"Path to the configuration file (defaults to ccmanager/config.yaml in the user config directory)"`
+		BasePath           []string      `arg:"env:CCMANAGER_BASEPATH,separate" help:"Paths where to find CloudControl docker compose folders"`
+		ContainerSeparator string        `arg:"env:CCMANAGER_SEP" help:"Separator used in docker compose container names [default: -]"`
+		Engine             string        `arg:"env:CCMANAGER_ENGINE" help:"Container engine to use (docker or podman) [default: docker]"`
+		PodmanSocket       string        `arg:"--podman-socket,env:CCMANAGER_PODMAN_SOCKET" help:"Path to the Podman API socket (defaults to the rootless or rootful Podman socket)"`
+		Output             string        `arg:"-o,--output" help:"Print the state of instances as json, yaml or table instead of running the TUI"`
+		Parallelism        int           `arg:"--parallelism,env:CCMANAGER_PARALLELISM" help:"Maximum number of instances processed at the same time by actions on marked instances [default: 4]"`
+		StatusParallelism  int           `arg:"--status-parallelism,env:CCMANAGER_STATUS_PARALLELISM" help:"Maximum number of instances whose status is loaded at the same time [default: 8]"`
+		RefreshInterval    time.Duration `arg:"--refresh-interval,env:CCMANAGER_REFRESH_INTERVAL" help:"Interval in which the instances are refreshed [default: 5s]"`
+		StatusTimeout      time.Duration `arg:"--status-timeout,env:CCMANAGER_STATUS_TIMEOUT" help:"Timeout for fetching the status of an instance [default: 10s]"`
+		ActionTimeout      time.Duration `arg:"--action-timeout,env:CCMANAGER_ACTION_TIMEOUT" help:"Timeout for starting and stopping an instance [default: 10m]"`
+		LogTimeout         time.Duration `arg:"--log-timeout,env:CCMANAGER_LOG_TIMEOUT" help:"Timeout for opening the log of an instance in the log screen and for fetching it using the logs subcommand [default: 30s]"`
+		LogSince           string        `arg:"--log-since,env:CCMANAGER_LOG_SINCE" help:"Only show log lines since a timestamp or relative duration in the log screen"`
+		LogTail            string        `arg:"--log-tail,env:CCMANAGER_LOG_TAIL" help:"Number of lines to show from the end of the log of each container in the log screen"`
+		ShowResources      *bool         `arg:"--show-resources,env:CCMANAGER_SHOW_RESOURCES" help:"Show the CPU and memory usage of running instances in the instance list, overriding the configuration file (use --show-resources=false to hide it)"`
+		UpdateCheck        *bool         `arg:"--update-check,env:CCMANAGER_UPDATE_CHECK" help:"Check the registry for newer CloudControl images, overriding the configuration file (use --update-check=false to disable the check)"`
+		NoUpdateCheck      bool          `arg:"--no-update-check,env:CCMANAGER_NO_UPDATE_CHECK" help:"Don't check the registry for newer CloudControl images (same as --update-check=false)"`
+		Theme              string        `arg:"--theme,env:CCMANAGER_THEME" help:"Colour theme (auto, dark, light, high-contrast, no-colour or the name or path of a theme file) [default: auto]"`
+		TemplateDir        string        `arg:"--template-dir,env:CCMANAGER_TEMPLATE_DIR" help:"Directory with templates overriding the embedded new instance templates (defaults to ccmanager/templates in the user config directory)"`
+		RecordSessions     *bool         `arg:"--record-sessions,env:CCMANAGER_RECORD_SESSIONS" help:"Record exec sessions in instances as asciicast files, overriding the configuration file (use --record-sessions=false to disable recording)"`
+		RecordInput        *bool         `arg:"--record-input,env:CCMANAGER_RECORD_INPUT" help:"Also record the input of recorded sessions, including passwords typed into them, overriding the configuration file (use --record-input=false to disable it)"`
+		RecordingDir       string        `arg:"--recording-dir,env:CCMANAGER_RECORDING_DIR" help:"Directory holding the session recordings (defaults to ccmanager/recordings in the user config directory)"`
+		NoEmbedSessions    bool          `arg:"--no-embed-sessions,env:CCMANAGER_NO_EMBED_SESSIONS" help:"Hand the whole terminal over to exec sessions instead of showing them as tabs next to the instance list"`
+		DetachableSessions *bool         `arg:"--detachable-sessions,env:CCMANAGER_DETACHABLE_SESSIONS" help:"Run CloudControl, shells and commands in the cli service in tmux sessions that keep running when CCmanager is closed, overriding the configuration file (use --detachable-sessions=false to disable them)"`
+		HistoryFile        string        `arg:"--history-file,env:CCMANAGER_HISTORY_FILE" help:"File holding the history of commands run in instances (defaults to ccmanager/history in the user config directory)"`
+
+		List    *struct{}     `arg:"subcommand:list" help:"List all instances and their state"`
+		Status  *instanceArgs `arg:"subcommand:status" help:"Show the status of an instance"`
+		Start   *instanceArgs `arg:"subcommand:start" help:"Start an instance"`
+		Stop    *instanceArgs `arg:"subcommand:stop" help:"Stop an instance"`
+		Restart *instanceArgs `arg:"subcommand:restart" help:"Restart an instance"`
+		Logs    *logsArgs     `arg:"subcommand:logs" help:"Show the log of an instance"`
+		Shell   *instanceArgs `arg:"subcommand:shell" help:"Run CloudControl in an instance"`
+		Exec    *execArgs     `arg:"subcommand:exec" help:"Run a command in a service of an instance"`
+		Cfg     *struct{}     `arg:"subcommand:config" help:"Print the effective configuration"`
+	}
+	p := arg.MustParse(&args)
+
+	// Flags and environment variables override the configuration file
+
+	configPath := args.Config
+	if configPath == "" {
+		configPath = config.DefaultPath()
+	}
+	c, err := config.Load(configPath, args.Config != "")
+	if err != nil {
+		p.Fail(err.Error())
+	}
+	if len(args.BasePath) > 0 {
+		c.BasePath = args.BasePath
+	}
+	if args.ContainerSeparator != "" {
+		c.Separator = args.ContainerSeparator
+	}
+	if args.Engine != "" {
+		c.Engine = args.Engine
+	}
+	if args.PodmanSocket != "" {
+		c.PodmanSocket = args.PodmanSocket
+	}
+	if args.Parallelism != 0 {
+		c.Parallelism = args.Parallelism
+	}
+	if args.StatusParallelism != 0 {
+		c.StatusParallelism = args.StatusParallelism
+	}
+	if args.RefreshInterval != 0 {
+		c.RefreshInterval = args.RefreshInterval
+	}
+	if args.StatusTimeout != 0 {
+		c.StatusTimeout = args.StatusTimeout
+	}
+	if args.ActionTimeout != 0 {
+		c.ActionTimeout = args.ActionTimeout
+	}
+	if args.LogTimeout != 0 {
+		c.LogTimeout = args.LogTimeout
+	}
+	if args.LogSince != "" {
+		c.LogSince = args.LogSince
+	}
+	if args.LogTail != "" {
+		c.LogTail = args.LogTail
+	}
+	if args.UpdateCheck != nil {
+		c.UpdateCheck = *args.UpdateCheck
+	}
+	if args.NoUpdateCheck {
+		c.UpdateCheck = false
+	}
+	if args.ShowResources != nil {
+		c.ShowResources = *args.ShowResources
+	}
+	if args.TemplateDir != "" {
+		c.TemplateDir = args.TemplateDir
+	}
+	if args.RecordSessions != nil {
+		c.RecordSessions = *args.RecordSessions
+	}
+	if args.RecordInput != nil {
+		c.RecordInput = *args.RecordInput
+	}
+	if args.RecordingDir != "" {
+		c.RecordingDir = args.RecordingDir
+	}
+	if args.NoEmbedSessions {
+		c.EmbedSessions = false
+	}
+	if args.DetachableSessions != nil {
+		c.DetachableSessions = *args.DetachableSessions
+	}
+	if args.HistoryFile != "" {
+		c.HistoryFile = args.HistoryFile
+	}
+	if args.Theme != "" {
+		c.Theme = args.Theme
+	}
+
+	if err := c.Validate(); err != nil {
+		p.Fail(err.Error())
+	}
+
+	if args.Output != "" && !headless.ValidOutput(args.Output) {
+		p.Fail(fmt.Sprintf("unknown output format %s", args.Output))
+	}
+
+	if args.Cfg != nil {
+		os.Exit(headless.PrintConfig(os.Stdout, c))
+	}
+
+	if len(c.BasePath) == 0 {
+		p.Fail("--basepath or basePath in the configuration file is required")
 	}
-	arg.MustParse(&args)
 
 	var items []list.Item
 
-	api.Separator = args.ContainerSeparator
+	api.Separator = c.Separator
+
+	var adapter adapters.BaseAdapter
+	switch c.Engine {
+	case "docker":
+		adapter = &adapters.DockerAdapter{Hosts: c.DockerHosts()}
+	case "podman":
+		if len(c.DockerHosts().All()) > 1 {
+			p.Fail("remote hosts are only supported by the docker engine")
+		}
+		adapter = adapters.NewPodmanAdapter(c.PodmanSocket)
+	default:
+		p.Fail(fmt.Sprintf("unknown engine %s", c.Engine))
+	}
+
+	r := headless.NewRunner(adapter, c.BasePath, args.Output, c.Timeouts())
+	if c.RecordSessions {
+		r.RecordingDir = c.RecordingDir
+		r.RecordInput = c.RecordInput
+	}
+	r.DetachableSessions = c.DetachableSessions
+	switch {
+	case args.List != nil, args.Output != "" && p.Subcommand() == nil:
+		os.Exit(r.List())
+	case args.Status != nil:
+		os.Exit(r.Status(args.Status.Name))
+	case args.Start != nil:
+		os.Exit(r.Start(args.Start.Name))
+	case args.Stop != nil:
+		os.Exit(r.Stop(args.Stop.Name))
+	case args.Restart != nil:
+		os.Exit(r.Restart(args.Restart.Name))
+	case args.Logs != nil:
+		os.Exit(r.Logs(args.Logs.Name, adapters.LogOptions{
+			Follow:   args.Logs.Follow,
+			Services: args.Logs.Service,
+			Since:    args.Logs.Since,
+			Tail:     args.Logs.Tail,
+		}))
+	case args.Shell != nil:
+		os.Exit(r.Shell(args.Shell.Name))
+	case args.Exec != nil:
+		command := args.Exec.Command
+		if len(command) == 0 {
+			command = adapters.ShellCommand
+		}
+		os.Exit(r.Exec(args.Exec.Name, args.Exec.Service, command))
+	}
+
+	// The checker is always created because instances can enable the check even if it is disabled globally
+	checker := versions.NewChecker(versions.RegistryTagLister{}, versions.DefaultCheckInterval)
 
-	d := adapters.DockerAdapter{}
+	if t, err := internal.LoadTheme(c.Theme, internal.DefaultThemeDir()); err != nil {
+		p.Fail(err.Error())
+	} else {
+		internal.ApplyTheme(t)
+	}
 
-	program := tea.NewProgram(models.NewMainModel(&d, args.BasePath, items))
-	if _, err := program.Run(); err != nil {
+	model, err := models.NewMainModel(adapter, items, c, checker)
+	if err != nil {
+		p.Fail(err.Error())
+	}
+	program := tea.NewProgram(model)
+	final, err := program.Run()
+	if m, ok := final.(models.MainModel); ok {
+		m.Close()
+	}
+	if err != nil {
 		fmt.Println("Error running program:", err)
 		os.Exit(1)
 	}
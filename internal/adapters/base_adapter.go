@@ -1,15 +1,18 @@
 package adapters
 
 import (
+	"context"
+	"fmt"
 	tea "github.com/charmbracelet/bubbletea"
 	"io"
+	"time"
 )
 
 type PortMap struct {
 	// ContainerPort holds the port inside the container
-	ContainerPort string
+	ContainerPort string `json:"containerPort" yaml:"containerPort"`
 	// HostPort holds the mapped port accessible from the host
-	HostPort string
+	HostPort string `json:"hostPort" yaml:"hostPort"`
 }
 
 // CloudControlStatus holds various information about a cloudcontrol instance
@@ -18,6 +21,9 @@ type CloudControlStatus struct {
 	Error error
 	// Running says whether the instance is running
 	Running bool
+	// Host holds the name of the machine the Docker engine of the instance runs on. It is empty for instances on the
+	// local machine
+	Host string
 	// Image holds the image name the instance is using
 	Image string
 	// Tag holds the image tag the instance is using
@@ -28,6 +34,43 @@ type CloudControlStatus struct {
 	CCCStatus CCCStatus
 	// PortMappings holds additional portmappings (aside from the CCCport)
 	PortMappings []PortMap
+	// Health holds the health check status of the cli container (e.g. healthy). It is empty if the container has
+	// no health check
+	Health string
+	// StartedAt holds the time the cli container was started
+	StartedAt time.Time
+	// RestartCount holds the number of times the cli container was restarted
+	RestartCount int
+	// Usage holds the resource usage of the cli container if it has been loaded using BaseAdapter.GetResourceUsage
+	Usage *ResourceUsage
+	// Volumes holds the sizes of the volumes if they have been loaded using BaseAdapter.GetVolumeSizes
+	Volumes []VolumeSize
+}
+
+// ResourceUsage holds the resource usage of a container
+type ResourceUsage struct {
+	// CPUPercent is the CPU usage in percent of one CPU
+	CPUPercent float64
+	// MemoryUsage is the used memory in bytes
+	MemoryUsage uint64
+	// MemoryLimit is the memory limit in bytes
+	MemoryLimit uint64
+	// NetworkRx is the number of bytes received over the network since the container was started
+	NetworkRx uint64
+	// NetworkTx is the number of bytes sent over the network since the container was started
+	NetworkTx uint64
+	// BlockRead is the number of bytes read from block devices since the container was started
+	BlockRead uint64
+	// BlockWrite is the number of bytes written to block devices since the container was started
+	BlockWrite uint64
+}
+
+// VolumeSize holds the size of a volume of an instance
+type VolumeSize struct {
+	// Name is the name of the volume
+	Name string
+	// Size is the size of the volume in bytes or -1 if it is unknown
+	Size int64
 }
 
 // CCCStatus holds the instance status as returned by the CCC
@@ -48,22 +91,147 @@ const (
 	CCCExited
 )
 
-// BaseAdapter describes the required functions to connect CCManager with container environments
+// String returns a short lowercase name of the status
+func (c CCCStatus) String() string {
+	switch c {
+	case CCCDown:
+		return "down"
+	case CCCInit:
+		return "initializing"
+	case CCCReady:
+		return "ready"
+	case CCCErr:
+		return "error"
+	case CCCExited:
+		return "exited"
+	default:
+		return "undefined"
+	}
+}
+
+// MarshalText returns the name of the status as returned by String
+func (c CCCStatus) MarshalText() ([]byte, error) {
+	return []byte(c.String()), nil
+}
+
+// UnmarshalText parses a status name as returned by String
+func (c *CCCStatus) UnmarshalText(text []byte) error {
+	for s := CCCUndef; s <= CCCExited; s++ {
+		if s.String() == string(text) {
+			*c = s
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown CCC status: %s", text)
+}
+
+// BaseAdapter describes the required functions to connect CCManager with container environments. Every function
+// accepts a context.Context which can be used to cancel the call or limit its duration
 type BaseAdapter interface {
 	// GetContainerStatus fetches a CloudControlStatus from its backend. The instance is identified by
 	// a basePath and the name of the instance
-	GetContainerStatus(basePath string, name string) (CloudControlStatus, error)
+	GetContainerStatus(ctx context.Context, basePath string, name string) (CloudControlStatus, error)
 	// RunCloudControl starts CloudControl in the given instance identified by basePath and
 	// name. The consoleWidth and consoleHeight specify the width and height of the console window
-	// that runs CloudControl. It returns a ContainerExec struct
-	RunCloudControl(basePath string, name string, consoleWidth uint, consoleHeight uint) (*ContainerExec, error)
+	// that runs CloudControl. It returns a ContainerExec struct. The context is used for the whole session
+	RunCloudControl(ctx context.Context, basePath string, name string, consoleWidth uint, consoleHeight uint) (*ContainerExec, error)
+	// Exec runs a command in a service of the instance identified by basePath and name using an interactive
+	// terminal. It returns a ContainerExec struct. The context is used for the whole session
+	Exec(ctx context.Context, basePath string, name string, options ExecOptions) (*ContainerExec, error)
 	// StartCloudControl starts the CloudControl instance identified by basePath and name
-	StartCloudControl(basePath string, name string) error
+	StartCloudControl(ctx context.Context, basePath string, name string) error
 	// StopCloudControl stops the CloudControl instance identified by basePath and name. The remove parameter
 	// is set to true if the instance should be cleaned up after stopping
-	StopCloudControl(basePath string, name string, remove bool) error
+	StopCloudControl(ctx context.Context, basePath string, name string, remove bool) error
 	// GetLogs returns the complete log of an instance identified by basePath and name
-	GetLogs(basePath string, name string) (string, error)
+	GetLogs(ctx context.Context, basePath string, name string) (string, error)
+	// StreamLogs returns a reader streaming the log of an instance identified by basePath and name. If
+	// LogOptions.Follow is set, new log lines are streamed until the context is cancelled or the reader is closed
+	StreamLogs(ctx context.Context, basePath string, name string, options LogOptions) (io.ReadCloser, error)
+	// GetServices returns the names of the services of an instance identified by basePath and name
+	GetServices(ctx context.Context, basePath string, name string) ([]string, error)
+	// Events streams the start, stop, die and health events of the containers of all instances until the context is
+	// cancelled. If the stream fails, the error is sent to the error channel and both channels are closed. Adapters
+	// using several engines keep streaming the events of the other engines instead and subscribe the failed engine
+	// again on their own
+	Events(ctx context.Context) (<-chan InstanceEvent, <-chan error)
+	// GetResourceUsage returns the CPU and memory usage of the cli container of a running instance identified by
+	// basePath and name
+	GetResourceUsage(ctx context.Context, basePath string, name string) (ResourceUsage, error)
+	// StreamResourceUsage streams the resource usage of the cli container of a running instance identified by
+	// basePath and name until the context is cancelled. If the stream fails, the error is sent to the error channel
+	// and both channels are closed
+	StreamResourceUsage(ctx context.Context, basePath string, name string) (<-chan ResourceUsage, <-chan error)
+	// GetVolumeSizes returns the sizes of the volumes of an instance identified by basePath and name
+	GetVolumeSizes(ctx context.Context, basePath string, name string) ([]VolumeSize, error)
+	// ForwardPorts makes ports published by an instance identified by basePath and name reachable from the local
+	// machine. Ports on machines reached over SSH are forwarded through tunnels that stay open until the context is
+	// cancelled, other ports are returned with their published URL
+	ForwardPorts(ctx context.Context, basePath string, name string, ports []string) ([]PortForward, error)
+}
+
+// InstanceEvent describes a change of a container belonging to an instance
+type InstanceEvent struct {
+	// BasePath is the base path of the instance
+	BasePath string
+	// Name is the name of the instance
+	Name string
+	// Action is the action that happened to the container (e.g. start, die or health_status)
+	Action string
+}
+
+// instanceEventActions are the container events that change the status of an instance
+var instanceEventActions = []string{"start", "stop", "die", "health_status"}
+
+// CloudControlCommand is the command that runs CloudControl in the cli service
+var CloudControlCommand = []string{"/usr/local/bin/cloudcontrol", "run"}
+
+// ShellCommand is the command that opens a shell. Bash is used if it is available
+var ShellCommand = []string{"/bin/sh", "-c", "if command -v bash >/dev/null; then exec bash; else exec sh; fi"}
+
+// ExecOptions configures the command run by BaseAdapter.Exec
+type ExecOptions struct {
+	// Service is the service whose container runs the command
+	Service string
+	// Command holds the command and its arguments
+	Command []string
+	// ConsoleWidth is the width of the console window that runs the command
+	ConsoleWidth uint
+	// ConsoleHeight is the height of the console window that runs the command
+	ConsoleHeight uint
+	// RecordingFile is the path of the asciicast v2 file the session is recorded into. The session isn't recorded
+	// if it is empty
+	RecordingFile string
+	// RecordInput tells whether the input of the session is recorded as well. Only the output and the terminal size
+	// changes are recorded otherwise, so passwords typed into the session don't end up in the recording
+	RecordInput bool
+	// Session is the name of the detachable session the command runs in. If the session is already running, the
+	// exec session attaches to it instead, an empty Command only attaches. The command isn't detachable if Session
+	// is empty
+	Session string
+}
+
+// LogOptions configures the log returned by BaseAdapter.StreamLogs
+type LogOptions struct {
+	// Follow keeps the log stream open and streams new log lines
+	Follow bool
+	// Services limits the log to the given services. All services are included if it is empty
+	Services []string
+	// Since only includes log lines since the given timestamp (e.g. 2024-01-02T13:23:37Z) or relative
+	// duration (e.g. 42m)
+	Since string
+	// Tail limits the log to the given number of lines from the end of the log of each container
+	Tail string
+}
+
+// Timeouts holds the maximum durations of adapter calls
+type Timeouts struct {
+	// Status is the timeout for fetching the status of an instance
+	Status time.Duration
+	// Action is the timeout for starting and stopping an instance
+	Action time.Duration
+	// Logs is the timeout for fetching the log of an instance
+	Logs time.Duration
 }
 
 // ContainerExec implements a tea.ExecCommand specialized for CCmanager
@@ -72,15 +240,41 @@ type ContainerExec struct {
 	stdin io.Reader
 	// stdout represents the writer to write output to
 	stdout io.Writer
-	exec   func(reader io.Reader, writer io.Writer) error
+	// resized receives the console sizes requested using Resize
+	resized chan ttySize
+	exec    func(reader io.Reader, writer io.Writer, resized <-chan ttySize) error
+}
+
+// ttySize is the size of the console window of an exec session
+type ttySize struct {
+	width  uint
+	height uint
 }
 
 var _ tea.ExecCommand = &ContainerExec{}
 
+// newContainerExec creates a ContainerExec running the given exec function
+func newContainerExec(exec func(reader io.Reader, writer io.Writer, resized <-chan ttySize) error) *ContainerExec {
+	return &ContainerExec{
+		resized: make(chan ttySize, 1),
+		exec:    exec,
+	}
+}
+
 // Run executes the command. In this case the adapter starts CloudControl using
 // stdin and stdout. StdErr is not used because we only use an interactive terminal
 func (d *ContainerExec) Run() error {
-	return d.exec(d.stdin, d.stdout)
+	return d.exec(d.stdin, d.stdout, d.resized)
+}
+
+// Resize resizes the tty of the session. It is used if stdin isn't a terminal whose size is followed automatically.
+// Only the latest size is kept if the session hasn't started yet
+func (d *ContainerExec) Resize(width uint, height uint) {
+	select {
+	case <-d.resized:
+	default:
+	}
+	d.resized <- ttySize{width: width, height: height}
 }
 
 func (d *ContainerExec) SetStdin(reader io.Reader) {
@@ -94,3 +288,25 @@ func (d *ContainerExec) SetStdout(writer io.Writer) {
 // SetStderr intentionally does nothing. We don't use stderr.
 func (d *ContainerExec) SetStderr(_ io.Writer) {
 }
+
+// cancelReadCloser is an io.ReadCloser that cancels the context of a stream when it is closed
+type cancelReadCloser struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (c *cancelReadCloser) Close() error {
+	c.cancel()
+	return c.ReadCloser.Close()
+}
+
+// streamTo runs write in the background and returns a reader with everything written. The context passed to write
+// is cancelled when the reader is closed
+func streamTo(ctx context.Context, write func(ctx context.Context, w io.Writer) error) io.ReadCloser {
+	ctx, cancel := context.WithCancel(ctx)
+	r, w := io.Pipe()
+	go func() {
+		_ = w.CloseWithError(write(ctx, w))
+	}()
+	return &cancelReadCloser{ReadCloser: r, cancel: cancel}
+}
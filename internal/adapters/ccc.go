@@ -0,0 +1,50 @@
+package adapters
+
+import (
+	"context"
+	"fmt"
+	resty "github.com/go-resty/resty/v2"
+	"net"
+	"net/http"
+)
+
+// dialFunc opens a connection to an address like net.Dialer.DialContext
+type dialFunc func(ctx context.Context, network string, address string) (net.Conn, error)
+
+// getCCCStatus retrieves status information as a CCCStatus struct from CCC listening on the given host and port. An
+// empty host is the local machine. If dial is set, it opens the connections to CCC instead of connecting directly,
+// e.g. through an SSH tunnel
+func getCCCStatus(ctx context.Context, host string, port string, dial dialFunc) (CCCStatus, error) {
+	type cccBackendStatus struct {
+		Status string
+	}
+	c := resty.New()
+	c.SetCloseConnection(true)
+	if dial != nil {
+		c.SetTransport(&http.Transport{DialContext: dial})
+	}
+	statusResult := cccBackendStatus{}
+	if resp, err := c.R().SetContext(ctx).SetResult(&statusResult).Get(fmt.Sprintf("%s/api/status", PublishedURL(host, port))); err != nil {
+		return CCCErr, err
+	} else {
+		if resp.IsError() {
+			return CCCErr, nil
+		}
+		switch statusResult.Status {
+		case "INIT":
+			return CCCInit, nil
+		case "INITIALIZED":
+			return CCCReady, nil
+		default:
+			return CCCErr, fmt.Errorf("unknown CCC state: %s", statusResult.Status)
+		}
+	}
+}
+
+// PublishedURL returns the URL of a web server published on a port of a host. An empty host is the local machine
+func PublishedURL(host string, port string) string {
+	if host == "" {
+		host = "localhost"
+	}
+	return fmt.Sprintf("http://%s", net.JoinHostPort(host, port))
+}
@@ -0,0 +1,33 @@
+package adapters
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestGetCCCStatusWithDialer(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = fmt.Fprint(w, `{"status": "INITIALIZED"}`)
+	}))
+	defer server.Close()
+
+	// The host can't be resolved, so the status can only be read through the dialer
+	dialed := 0
+	dial := func(ctx context.Context, network string, _ string) (net.Conn, error) {
+		dialed++
+		var d net.Dialer
+		return d.DialContext(ctx, network, server.Listener.Addr().String())
+	}
+	s, err := getCCCStatus(context.Background(), "buildvm.invalid", "8080", dial)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s != CCCReady || dialed != 1 {
+		t.Errorf("got status %v after %d dials, want %v after 1 dial", s, dialed, CCCReady)
+	}
+}
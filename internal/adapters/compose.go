@@ -0,0 +1,163 @@
+package adapters
+
+import (
+	"fmt"
+	"github.com/compose-spec/compose-go/cli"
+	composeTypes "github.com/compose-spec/compose-go/types"
+	"github.com/docker/compose/v2/pkg/api"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// cliContainerName returns the name of the container running the cli service of an instance
+func cliContainerName(name string) string {
+	return serviceContainerName(name, "cli")
+}
+
+// serviceContainerName returns the name of the first container of a service in an instance
+func serviceContainerName(name string, service string) string {
+	return fmt.Sprintf("%s%s%s%s1", name, api.Separator, service, api.Separator)
+}
+
+// composeFile returns the path to the docker compose file of an instance identified by basePath and name
+func composeFile(basePath string, name string) (string, error) {
+	yamlFiles := []string{
+		fmt.Sprintf("%s/docker-compose.yaml", filepath.Join(basePath, name)),
+		fmt.Sprintf("%s/docker-compose.yml", filepath.Join(basePath, name)),
+	}
+	var yamlFile string
+	for _, file := range yamlFiles {
+		if _, err := os.Stat(file); err == nil {
+			yamlFile = file
+		}
+	}
+
+	if yamlFile == "" {
+		return "", fmt.Errorf("can't find docker-compose file for %s at path %s", name, basePath)
+	}
+	return yamlFile, nil
+}
+
+// getProject loads a docker compose project for an instance identified by basePath and name
+func getProject(basePath string, name string) (*composeTypes.Project, error) {
+	yamlFile, err := composeFile(basePath, name)
+	if err != nil {
+		return nil, err
+	}
+
+	var project *composeTypes.Project
+
+	if p, err := cli.ProjectFromOptions(&cli.ProjectOptions{
+		WorkingDir:  filepath.Join(basePath, name),
+		ConfigPaths: []string{yamlFile},
+		Environment: map[string]string{},
+	}); err != nil {
+		return nil, err
+	} else {
+		p.Name = name
+		project = p
+		for i, s := range project.Services {
+			s.CustomLabels = map[string]string{
+				api.ProjectLabel:     project.Name,
+				api.ServiceLabel:     s.Name,
+				api.VersionLabel:     api.ComposeVersion,
+				api.WorkingDirLabel:  project.WorkingDir,
+				api.ConfigFilesLabel: strings.Join(project.ComposeFiles, ","),
+				api.OneoffLabel:      "False", // default, will be overridden by `run` command
+			}
+			project.Services[i] = s
+		}
+	}
+	return project, nil
+}
+
+// getContainerStatusFromCompose is used if not enough information can be resolved from a running container
+func getContainerStatusFromCompose(basePath string, name string) (CloudControlStatus, error) {
+	var project *composeTypes.Project
+
+	if p, err := getProject(basePath, name); err != nil {
+		return CloudControlStatus{Error: err}, err
+	} else {
+		project = p
+	}
+
+	var cliService composeTypes.ServiceConfig
+	if c, err := project.GetService("cli"); err != nil {
+		return CloudControlStatus{Error: err}, err
+	} else {
+		cliService = c
+	}
+	image, tag := splitImage(cliService.Image)
+	return CloudControlStatus{
+		Running:   false,
+		Image:     image,
+		Tag:       tag,
+		CCCStatus: CCCDown,
+		CCCPort:   "n/a",
+	}, nil
+}
+
+// getServices returns the sorted service names of an instance identified by basePath and name
+func getServices(basePath string, name string) ([]string, error) {
+	project, err := getProject(basePath, name)
+	if err != nil {
+		return nil, err
+	}
+	services := project.ServiceNames()
+	sort.Strings(services)
+	return services, nil
+}
+
+// getVolumeNames returns the sorted names of the volumes of an instance identified by basePath and name
+func getVolumeNames(basePath string, name string) ([]string, error) {
+	project, err := getProject(basePath, name)
+	if err != nil {
+		return nil, err
+	}
+	var volumes []string
+	for key := range project.Volumes {
+		volumes = append(volumes, volumeName(project, key))
+	}
+	sort.Strings(volumes)
+	return volumes, nil
+}
+
+// newVolumeSizes returns the sizes of the given volumes using the sizes reported by the container engine
+func newVolumeSizes(volumes []string, sizes map[string]int64) []VolumeSize {
+	var volumeSizes []VolumeSize
+	for _, v := range volumes {
+		size, ok := sizes[v]
+		if !ok {
+			size = -1
+		}
+		volumeSizes = append(volumeSizes, VolumeSize{Name: v, Size: size})
+	}
+	return volumeSizes
+}
+
+// splitImage splits an image reference into the image name and its tag
+func splitImage(image string) (string, string) {
+	if i := strings.LastIndex(image, ":"); i > strings.LastIndex(image, "/") {
+		return image[:i], image[i+1:]
+	}
+	return image, "latest"
+}
+
+// newInstanceEvent creates an InstanceEvent from the labels of a compose container. It returns false if the labels
+// don't identify an instance
+func newInstanceEvent(action string, labels map[string]string) (InstanceEvent, bool) {
+	workingDir := labels[api.WorkingDirLabel]
+	if workingDir == "" {
+		return InstanceEvent{}, false
+	}
+	basePath, name := filepath.Split(filepath.Clean(workingDir))
+	// Health events are reported as "health_status: healthy" by Docker
+	action, _, _ = strings.Cut(action, ":")
+	return InstanceEvent{
+		BasePath: filepath.Clean(basePath),
+		Name:     name,
+		Action:   action,
+	}, true
+}
@@ -0,0 +1,573 @@
+package adapters
+
+import (
+	"bytes"
+	"fmt"
+	"gopkg.in/yaml.v3"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// InstanceConfig holds the editable configuration of the cli service of an instance
+type InstanceConfig struct {
+	// Tag is the image tag of the cli service
+	Tag string
+	// Environment holds the environment variables of the cli service
+	Environment map[string]string
+	// Ports holds the port mappings of the cli service in the short compose syntax (e.g. 8080:8080)
+	Ports []string
+}
+
+// GetInstanceConfig loads the configuration of the cli service of an instance identified by basePath and name
+func GetInstanceConfig(basePath string, name string) (InstanceConfig, error) {
+	project, err := getProject(basePath, name)
+	if err != nil {
+		return InstanceConfig{}, err
+	}
+	cliService, err := project.GetService("cli")
+	if err != nil {
+		return InstanceConfig{}, err
+	}
+
+	_, tag := splitImage(cliService.Image)
+	config := InstanceConfig{
+		Tag:         tag,
+		Environment: map[string]string{},
+	}
+	for key, value := range cliService.Environment {
+		if value != nil {
+			config.Environment[key] = *value
+		} else {
+			config.Environment[key] = ""
+		}
+	}
+	for _, p := range cliService.Ports {
+		port := fmt.Sprintf("%d", p.Target)
+		if p.Published != "" {
+			port = fmt.Sprintf("%s:%s", p.Published, port)
+		}
+		if p.HostIP != "" {
+			port = fmt.Sprintf("%s:%s", p.HostIP, port)
+		}
+		if p.Protocol != "" && p.Protocol != "tcp" {
+			port = fmt.Sprintf("%s/%s", port, p.Protocol)
+		}
+		config.Ports = append(config.Ports, port)
+	}
+	return config, nil
+}
+
+// SaveInstanceConfig writes the changes between oldConfig and newConfig into the compose file of an instance
+// identified by basePath and name. The compose file is edited in place: only the bytes of changed values are replaced,
+// so comments, formatting, anchors and variables everywhere else are kept exactly as they are. Tags set by a variable
+// (e.g. ${TAG} or an image like ${IMAGE} that may include a tag) can't be changed
+func SaveInstanceConfig(basePath string, name string, oldConfig InstanceConfig, newConfig InstanceConfig) error {
+	yamlFile, err := composeFile(basePath, name)
+	if err != nil {
+		return err
+	}
+	content, err := os.ReadFile(yamlFile)
+	if err != nil {
+		return fmt.Errorf("can not read compose file: %w", err)
+	}
+	var document yaml.Node
+	if err := yaml.Unmarshal(content, &document); err != nil {
+		return fmt.Errorf("can not parse compose file: %w", err)
+	}
+	if len(document.Content) == 0 {
+		return fmt.Errorf("compose file %s is empty", yamlFile)
+	}
+
+	services := mappingValue(document.Content[0], "services")
+	cliKey, cliService := mappingKey(services, "cli"), mappingValue(services, "cli")
+	if cliService == nil || cliService.Kind != yaml.MappingNode || cliService.Style&yaml.FlowStyle != 0 ||
+		len(cliService.Content) == 0 {
+		return fmt.Errorf("can not find cli service in %s", yamlFile)
+	}
+
+	editor := newComposeEditor(content)
+	if newConfig.Tag != oldConfig.Tag {
+		image := mappingValue(cliService, "image")
+		if image == nil || image.Kind != yaml.ScalarNode {
+			return fmt.Errorf("can not find image of cli service in %s", yamlFile)
+		}
+		imageName, tag := splitRawImage(image.Value)
+		if strings.Contains(tag, "$") || imageName == image.Value && strings.Contains(imageName, "$") {
+			return fmt.Errorf("can not change the tag of image %s in %s because it is set by a variable", image.Value, yamlFile)
+		}
+		if err := editor.replaceScalar(image, fmt.Sprintf("%s:%s", imageName, newConfig.Tag)); err != nil {
+			return err
+		}
+	}
+	if !reflect.DeepEqual(newConfig.Environment, oldConfig.Environment) {
+		if err := editor.setEnvironment(cliKey, cliService, oldConfig.Environment, newConfig.Environment); err != nil {
+			return err
+		}
+	}
+	if !reflect.DeepEqual(newConfig.Ports, oldConfig.Ports) {
+		if err := editor.setPorts(cliKey, cliService, newConfig.Ports); err != nil {
+			return err
+		}
+	}
+
+	mode := os.FileMode(0600)
+	if info, err := os.Stat(yamlFile); err == nil {
+		mode = info.Mode()
+	}
+	if err := os.WriteFile(yamlFile, editor.apply(), mode); err != nil {
+		return fmt.Errorf("can not write compose file: %w", err)
+	}
+	return nil
+}
+
+// splitRawImage splits an image as written in a compose file into name and tag. Other than splitImage it ignores
+// colons inside of variables like ${TAG:-latest}
+func splitRawImage(image string) (string, string) {
+	colon, slash, depth := -1, -1, 0
+	for i := 0; i < len(image); i++ {
+		switch {
+		case strings.HasPrefix(image[i:], "${"):
+			depth++
+			i++
+		case image[i] == '}' && depth > 0:
+			depth--
+		case depth == 0 && image[i] == '/':
+			slash = i
+		case depth == 0 && image[i] == ':':
+			colon = i
+		}
+	}
+	if colon > slash {
+		return image[:colon], image[colon+1:]
+	}
+	return image, "latest"
+}
+
+// mappingKey returns the key node of key in a YAML mapping node or nil if the key doesn't exist
+func mappingKey(node *yaml.Node, key string) *yaml.Node {
+	if node == nil || node.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i]
+		}
+	}
+	return nil
+}
+
+// mappingValue returns the value of key in a YAML mapping node or nil if the key doesn't exist
+func mappingValue(node *yaml.Node, key string) *yaml.Node {
+	if node == nil || node.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// isBlockCollection tells whether node is a non-empty mapping or sequence in block style, whose entries can be
+// edited line by line
+func isBlockCollection(node *yaml.Node) bool {
+	return node != nil && (node.Kind == yaml.MappingNode || node.Kind == yaml.SequenceNode) &&
+		node.Style&yaml.FlowStyle == 0 && len(node.Content) > 0
+}
+
+// renderScalar renders a string as YAML scalar in the given style. Values that would be read as another type or that
+// span multiple lines are quoted
+func renderScalar(value string, style yaml.Style) (string, error) {
+	style &= yaml.DoubleQuotedStyle | yaml.SingleQuotedStyle
+	if strings.Contains(value, "\n") {
+		style = yaml.DoubleQuotedStyle
+	}
+	out, err := yaml.Marshal(&yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Style: style, Value: value})
+	if err != nil {
+		return "", fmt.Errorf("can not render %q: %w", value, err)
+	}
+	return strings.TrimSuffix(string(out), "\n"), nil
+}
+
+// composeEdit replaces the bytes between start and end of a compose file with text
+type composeEdit struct {
+	start int
+	end   int
+	text  string
+}
+
+// composeEditor collects edits of a compose file and applies them to its original content. Positions are taken from
+// the line and column of the parsed YAML nodes
+type composeEditor struct {
+	content    []byte
+	lineStarts []int
+	edits      []composeEdit
+}
+
+// newComposeEditor creates a composeEditor for the content of a compose file
+func newComposeEditor(content []byte) *composeEditor {
+	e := &composeEditor{content: content, lineStarts: []int{0}}
+	for i, c := range content {
+		if c == '\n' && i+1 < len(content) {
+			e.lineStarts = append(e.lineStarts, i+1)
+		}
+	}
+	return e
+}
+
+// lineStart returns the offset of the first byte of a line. Lines are counted from 1 like in yaml.Node
+func (e *composeEditor) lineStart(line int) int {
+	if line > len(e.lineStarts) {
+		return len(e.content)
+	}
+	return e.lineStarts[line-1]
+}
+
+// line returns the content of a line without its line break
+func (e *composeEditor) line(line int) string {
+	return strings.TrimRight(string(e.content[e.lineStart(line):e.lineStart(line+1)]), "\r\n")
+}
+
+// indent returns the number of spaces a line is indented with
+func (e *composeEditor) indent(line int) int {
+	text := e.line(line)
+	return len(text) - len(strings.TrimLeft(text, " "))
+}
+
+// offset returns the offset of a line and column as reported by yaml.Node. Columns count characters, not bytes
+func (e *composeEditor) offset(line int, column int) int {
+	offset := e.lineStart(line)
+	for i := 1; i < column && offset < len(e.content); i++ {
+		_, size := utf8.DecodeRune(e.content[offset:])
+		offset += size
+	}
+	return offset
+}
+
+// blockEnd returns the offset of the first line after the block starting in line. The block contains all following
+// lines indented deeper than indent and, if dashes is set, sequence entries indented by exactly indent, which is how
+// sequences under a mapping key may be written. Blank lines at the end of the block are not part of it
+func (e *composeEditor) blockEnd(line int, indent int, dashes bool) int {
+	blank := 0
+	for l := line + 1; l <= len(e.lineStarts); l++ {
+		text := strings.TrimSpace(e.line(l))
+		switch {
+		case text == "":
+			if blank == 0 {
+				blank = l
+			}
+			continue
+		case e.indent(l) > indent, dashes && e.indent(l) == indent && strings.HasPrefix(text, "-"):
+			blank = 0
+			continue
+		}
+		if blank != 0 {
+			return e.lineStart(blank)
+		}
+		return e.lineStart(l)
+	}
+	if blank != 0 {
+		return e.lineStart(blank)
+	}
+	return len(e.content)
+}
+
+// scalarEnd returns the offset of the first byte after a scalar node
+func (e *composeEditor) scalarEnd(node *yaml.Node) (int, error) {
+	start := e.offset(node.Line, node.Column)
+	switch {
+	case node.Style&yaml.DoubleQuotedStyle != 0:
+		for i := start + 1; i < len(e.content); i++ {
+			switch e.content[i] {
+			case '\\':
+				i++
+			case '"':
+				return i + 1, nil
+			}
+		}
+	case node.Style&yaml.SingleQuotedStyle != 0:
+		for i := start + 1; i < len(e.content); i++ {
+			if e.content[i] == '\'' {
+				if i+1 < len(e.content) && e.content[i+1] == '\'' {
+					i++
+					continue
+				}
+				return i + 1, nil
+			}
+		}
+	case node.Style&(yaml.LiteralStyle|yaml.FoldedStyle) != 0:
+		end := e.blockEnd(node.Line, e.indent(node.Line), false)
+		if end > start && e.content[end-1] == '\n' {
+			end--
+		}
+		return end, nil
+	default:
+		if !strings.Contains(node.Value, "\n") && bytes.HasPrefix(e.content[start:], []byte(node.Value)) {
+			return start + len(node.Value), nil
+		}
+	}
+	return 0, fmt.Errorf("can not find the end of the value in line %d", node.Line)
+}
+
+// replaceScalar replaces a scalar node with value, keeping its quoting style
+func (e *composeEditor) replaceScalar(node *yaml.Node, value string) error {
+	text, err := renderScalar(value, node.Style)
+	if err != nil {
+		return err
+	}
+	end, err := e.scalarEnd(node)
+	if err != nil {
+		return err
+	}
+	e.edits = append(e.edits, composeEdit{start: e.offset(node.Line, node.Column), end: end, text: text})
+	return nil
+}
+
+// replaceNullValue sets the value of a mapping entry without value (e.g. "KEY:")
+func (e *composeEditor) replaceNullValue(key *yaml.Node, value string) error {
+	text, err := renderScalar(value, 0)
+	if err != nil {
+		return err
+	}
+	keyEnd, err := e.scalarEnd(key)
+	if err != nil {
+		return err
+	}
+	colon := bytes.IndexByte(e.content[keyEnd:], ':')
+	if colon < 0 {
+		return fmt.Errorf("can not find the value of %s in line %d", key.Value, key.Line)
+	}
+	e.edits = append(e.edits, composeEdit{start: keyEnd, end: keyEnd + colon + 1, text: ": " + text})
+	return nil
+}
+
+// replaceLines replaces the bytes between start and end with lines
+func (e *composeEditor) replaceLines(start int, end int, lines []string) {
+	text := strings.Join(lines, "\n") + "\n"
+	if start == len(e.content) && start > 0 && e.content[start-1] != '\n' {
+		text = "\n" + text
+	}
+	e.edits = append(e.edits, composeEdit{start: start, end: end, text: text})
+}
+
+// replaceEntry replaces a block mapping entry identified by its key node, including its value, with lines
+func (e *composeEditor) replaceEntry(key *yaml.Node, lines []string) {
+	e.replaceLines(e.lineStart(key.Line), e.blockEnd(key.Line, key.Column-1, true), lines)
+}
+
+// appendEntry adds lines after the last entry of a block mapping
+func (e *composeEditor) appendEntry(mapping *yaml.Node, lines []string) {
+	last := mapping.Content[len(mapping.Content)-2]
+	end := e.blockEnd(last.Line, last.Column-1, true)
+	e.replaceLines(end, end, lines)
+}
+
+// removeEntry removes a block mapping entry identified by its key node, including its value
+func (e *composeEditor) removeEntry(key *yaml.Node) {
+	e.edits = append(e.edits, composeEdit{start: e.lineStart(key.Line), end: e.blockEnd(key.Line, key.Column-1, true)})
+}
+
+// removeItem removes an entry of a block sequence
+func (e *composeEditor) removeItem(item *yaml.Node) {
+	e.edits = append(e.edits, composeEdit{
+		start: e.lineStart(item.Line),
+		end:   e.blockEnd(item.Line, e.indent(item.Line), false),
+	})
+}
+
+// childIndent returns the indentation new entries of the cli service get nested under one of its keys
+func childIndent(cliKey *yaml.Node, cliService *yaml.Node) (string, string) {
+	indent := cliService.Content[0].Column - 1
+	step := indent - (cliKey.Column - 1)
+	if step <= 0 {
+		step = 2
+	}
+	return strings.Repeat(" ", indent), strings.Repeat(" ", indent+step)
+}
+
+// setEnvironment writes the changes between oldEnvironment and newEnvironment into the environment of the cli
+// service, which can either be a mapping or a list of KEY=VALUE entries
+func (e *composeEditor) setEnvironment(cliKey *yaml.Node, cliService *yaml.Node, oldEnvironment map[string]string,
+	newEnvironment map[string]string) error {
+	key, environment := mappingKey(cliService, "environment"), mappingValue(cliService, "environment")
+	if len(newEnvironment) == 0 {
+		if key != nil {
+			e.removeEntry(key)
+		}
+		return nil
+	}
+
+	names := make([]string, 0, len(newEnvironment))
+	for name := range newEnvironment {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	if !isBlockCollection(environment) {
+		// A missing, empty or flow style environment is written as block mapping as a whole
+		indent, itemIndent := childIndent(cliKey, cliService)
+		lines := []string{indent + "environment:"}
+		for _, name := range names {
+			value, err := renderScalar(newEnvironment[name], 0)
+			if err != nil {
+				return err
+			}
+			lines = append(lines, fmt.Sprintf("%s%s: %s", itemIndent, name, value))
+		}
+		if key != nil {
+			e.replaceEntry(key, lines)
+		} else {
+			e.appendEntry(cliService, lines)
+		}
+		return nil
+	}
+
+	var added []string
+	if environment.Kind == yaml.SequenceNode {
+		last := environment.Content[len(environment.Content)-1]
+		indent := strings.Repeat(" ", e.indent(last.Line))
+		for _, name := range names {
+			if oldValue, ok := oldEnvironment[name]; ok && oldValue == newEnvironment[name] {
+				continue
+			}
+			entry := fmt.Sprintf("%s=%s", name, newEnvironment[name])
+			if item := environmentItem(environment, name); item != nil {
+				if err := e.replaceScalar(item, entry); err != nil {
+					return err
+				}
+				continue
+			}
+			text, err := renderScalar(entry, 0)
+			if err != nil {
+				return err
+			}
+			added = append(added, fmt.Sprintf("%s- %s", indent, text))
+		}
+		for name := range oldEnvironment {
+			if _, ok := newEnvironment[name]; !ok {
+				if item := environmentItem(environment, name); item != nil {
+					e.removeItem(item)
+				}
+			}
+		}
+		if len(added) > 0 {
+			end := e.blockEnd(last.Line, e.indent(last.Line), false)
+			e.replaceLines(end, end, added)
+		}
+		return nil
+	}
+
+	indent := strings.Repeat(" ", environment.Content[0].Column-1)
+	for _, name := range names {
+		if oldValue, ok := oldEnvironment[name]; ok && oldValue == newEnvironment[name] {
+			continue
+		}
+		if value := mappingValue(environment, name); value != nil {
+			var err error
+			switch {
+			case value.Kind != yaml.ScalarNode:
+				err = fmt.Errorf("can not edit environment variable %s in line %d", name, value.Line)
+			case value.Tag == "!!null" && value.Value == "":
+				err = e.replaceNullValue(mappingKey(environment, name), newEnvironment[name])
+			default:
+				err = e.replaceScalar(value, newEnvironment[name])
+			}
+			if err != nil {
+				return err
+			}
+			continue
+		}
+		value, err := renderScalar(newEnvironment[name], 0)
+		if err != nil {
+			return err
+		}
+		added = append(added, fmt.Sprintf("%s%s: %s", indent, name, value))
+	}
+	for name := range oldEnvironment {
+		if _, ok := newEnvironment[name]; !ok {
+			if key := mappingKey(environment, name); key != nil {
+				e.removeEntry(key)
+			}
+		}
+	}
+	if len(added) > 0 {
+		e.appendEntry(environment, added)
+	}
+	return nil
+}
+
+// environmentItem returns the entry of an environment variable in a list of KEY=VALUE entries
+func environmentItem(environment *yaml.Node, name string) *yaml.Node {
+	for _, n := range environment.Content {
+		if n.Kind == yaml.ScalarNode && (n.Value == name || strings.HasPrefix(n.Value, name+"=")) {
+			return n
+		}
+	}
+	return nil
+}
+
+// setPorts replaces the ports of the cli service
+func (e *composeEditor) setPorts(cliKey *yaml.Node, cliService *yaml.Node, ports []string) error {
+	key, value := mappingKey(cliService, "ports"), mappingValue(cliService, "ports")
+	if len(ports) == 0 {
+		if key != nil {
+			e.removeEntry(key)
+		}
+		return nil
+	}
+
+	indent, itemIndent := childIndent(cliKey, cliService)
+	if isBlockCollection(value) && value.Kind == yaml.SequenceNode {
+		// Keep the ports key with its comments and only replace the entries
+		first, last := value.Content[0], value.Content[len(value.Content)-1]
+		itemIndent = strings.Repeat(" ", e.indent(first.Line))
+		lines, err := portLines(itemIndent, ports)
+		if err != nil {
+			return err
+		}
+		e.replaceLines(e.lineStart(first.Line), e.blockEnd(last.Line, len(itemIndent), false), lines)
+		return nil
+	}
+
+	items, err := portLines(itemIndent, ports)
+	if err != nil {
+		return err
+	}
+	lines := append([]string{indent + "ports:"}, items...)
+	if key != nil {
+		e.replaceEntry(key, lines)
+	} else {
+		e.appendEntry(cliService, lines)
+	}
+	return nil
+}
+
+// portLines renders ports as entries of a block sequence
+func portLines(indent string, ports []string) ([]string, error) {
+	var lines []string
+	for _, port := range ports {
+		text, err := renderScalar(port, yaml.DoubleQuotedStyle)
+		if err != nil {
+			return nil, err
+		}
+		lines = append(lines, fmt.Sprintf("%s- %s", indent, text))
+	}
+	return lines, nil
+}
+
+// apply returns the content of the compose file with all edits applied
+func (e *composeEditor) apply() []byte {
+	edits := append([]composeEdit{}, e.edits...)
+	sort.SliceStable(edits, func(i, j int) bool {
+		return edits[i].start > edits[j].start
+	})
+	content := append([]byte{}, e.content...)
+	for _, edit := range edits {
+		content = append(content[:edit.start], append([]byte(edit.text), content[edit.end:]...)...)
+	}
+	return content
+}
@@ -0,0 +1,246 @@
+package adapters
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// editableComposeFile is a compose file using comments, anchors, variables and different quoting styles, which all
+// have to survive editing the instance configuration
+const editableComposeFile = `# CloudControl for the dev cluster
+x-common: &common
+  restart: "no"   # don't restart automatically
+
+services:
+  cli:
+    <<: *common
+    image: "ghcr.io/dodevops/cloudcontrol-${FLAVOUR:-azure}:5.0.0"
+    hostname: cli
+    environment:
+      # Azure settings
+      AZ_SUBSCRIPTION: 'my-subscription'
+      ENVIRONMENT: dev # the stage
+      DEBUG: "false"
+      EMPTY:
+    ports:   # CCC and the API proxy
+      - "8080:8080"
+      - 8443:8443
+    volumes:
+      - home:/home/cloudcontrol
+
+  db:
+    image: postgres:16   # keep in sync with production
+    environment:
+      - POSTGRES_PASSWORD=secret
+volumes:
+  home: {}
+`
+
+// saveAndRead saves newConfig for an instance with editableComposeFile and returns the resulting compose file
+func saveAndRead(t *testing.T, newConfig func(c *InstanceConfig)) (string, InstanceConfig) {
+	t.Helper()
+	basePath := writeTestInstance(t, "test", editableComposeFile)
+	oldConfig, err := GetInstanceConfig(basePath, "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := InstanceConfig{Tag: oldConfig.Tag, Environment: map[string]string{}}
+	for k, v := range oldConfig.Environment {
+		config.Environment[k] = v
+	}
+	config.Ports = append(config.Ports, oldConfig.Ports...)
+	newConfig(&config)
+
+	if err := SaveInstanceConfig(basePath, "test", oldConfig, config); err != nil {
+		t.Fatal(err)
+	}
+	content, err := os.ReadFile(filepath.Join(basePath, "test", "docker-compose.yml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	savedConfig, err := GetInstanceConfig(basePath, "test")
+	if err != nil {
+		t.Fatalf("can not load saved compose file: %v\n%s", err, content)
+	}
+	if !reflect.DeepEqual(savedConfig, config) {
+		t.Errorf("loaded %+v after saving %+v", savedConfig, config)
+	}
+	return string(content), config
+}
+
+func TestSaveInstanceConfigUnchanged(t *testing.T) {
+	content, _ := saveAndRead(t, func(c *InstanceConfig) {})
+	if content != editableComposeFile {
+		t.Errorf("compose file changed without changes:\n%s", content)
+	}
+}
+
+func TestSaveInstanceConfigValues(t *testing.T) {
+	content, _ := saveAndRead(t, func(c *InstanceConfig) {
+		c.Tag = "5.1.0"
+		c.Environment["AZ_SUBSCRIPTION"] = "other subscription"
+		c.Environment["ENVIRONMENT"] = "prod"
+		c.Environment["DEBUG"] = "true"
+		c.Environment["EMPTY"] = "set"
+	})
+	want := `# CloudControl for the dev cluster
+x-common: &common
+  restart: "no"   # don't restart automatically
+
+services:
+  cli:
+    <<: *common
+    image: "ghcr.io/dodevops/cloudcontrol-${FLAVOUR:-azure}:5.1.0"
+    hostname: cli
+    environment:
+      # Azure settings
+      AZ_SUBSCRIPTION: 'other subscription'
+      ENVIRONMENT: prod # the stage
+      DEBUG: "true"
+      EMPTY: set
+    ports:   # CCC and the API proxy
+      - "8080:8080"
+      - 8443:8443
+    volumes:
+      - home:/home/cloudcontrol
+
+  db:
+    image: postgres:16   # keep in sync with production
+    environment:
+      - POSTGRES_PASSWORD=secret
+volumes:
+  home: {}
+`
+	if content != want {
+		t.Errorf("got compose file\n%s\nwant\n%s", content, want)
+	}
+}
+
+func TestSaveInstanceConfigEntries(t *testing.T) {
+	content, _ := saveAndRead(t, func(c *InstanceConfig) {
+		delete(c.Environment, "ENVIRONMENT")
+		delete(c.Environment, "EMPTY")
+		c.Environment["NEW"] = "true"
+		c.Ports = []string{"9090:8080"}
+	})
+	want := `# CloudControl for the dev cluster
+x-common: &common
+  restart: "no"   # don't restart automatically
+
+services:
+  cli:
+    <<: *common
+    image: "ghcr.io/dodevops/cloudcontrol-${FLAVOUR:-azure}:5.0.0"
+    hostname: cli
+    environment:
+      # Azure settings
+      AZ_SUBSCRIPTION: 'my-subscription'
+      DEBUG: "false"
+      NEW: "true"
+    ports:   # CCC and the API proxy
+      - "9090:8080"
+    volumes:
+      - home:/home/cloudcontrol
+
+  db:
+    image: postgres:16   # keep in sync with production
+    environment:
+      - POSTGRES_PASSWORD=secret
+volumes:
+  home: {}
+`
+	if content != want {
+		t.Errorf("got compose file\n%s\nwant\n%s", content, want)
+	}
+}
+
+func TestSaveInstanceConfigNewKeys(t *testing.T) {
+	basePath := writeTestInstance(t, "test", testComposeFile)
+	oldConfig, err := GetInstanceConfig(basePath, "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	newConfig := InstanceConfig{Tag: oldConfig.Tag, Environment: map[string]string{"DEBUG": "true"}}
+	if err := SaveInstanceConfig(basePath, "test", oldConfig, newConfig); err != nil {
+		t.Fatal(err)
+	}
+	content, err := os.ReadFile(filepath.Join(basePath, "test", "docker-compose.yml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `services:
+  cli:
+    image: ghcr.io/dodevops/cloudcontrol-azure:latest
+    volumes:
+      - home:/home/cloudcontrol
+    environment:
+      DEBUG: "true"
+  db:
+    image: postgres:16
+volumes:
+  home:
+`
+	if string(content) != want {
+		t.Errorf("got compose file\n%s\nwant\n%s", content, want)
+	}
+}
+
+func TestSaveInstanceConfigEnvironmentList(t *testing.T) {
+	basePath := writeTestInstance(t, "test", `services:
+  cli:
+    image: ghcr.io/dodevops/cloudcontrol-azure:latest
+    environment:
+    - A=1
+    - B=2   # second
+    - C
+`)
+	oldConfig, err := GetInstanceConfig(basePath, "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	newConfig := InstanceConfig{Tag: oldConfig.Tag, Environment: map[string]string{"B": "3", "C": "x", "D": "4"}}
+	if err := SaveInstanceConfig(basePath, "test", oldConfig, newConfig); err != nil {
+		t.Fatal(err)
+	}
+	content, err := os.ReadFile(filepath.Join(basePath, "test", "docker-compose.yml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `services:
+  cli:
+    image: ghcr.io/dodevops/cloudcontrol-azure:latest
+    environment:
+    - B=3   # second
+    - C=x
+    - D=4
+`
+	if string(content) != want {
+		t.Errorf("got compose file\n%s\nwant\n%s", content, want)
+	}
+}
+
+func TestSaveInstanceConfigVariableTag(t *testing.T) {
+	for _, image := range []string{"${CCC_IMAGE:-ghcr.io/dodevops/cloudcontrol-azure:5.0.0}", "ghcr.io/dodevops/cloudcontrol-azure:${TAG:-5.0.0}"} {
+		t.Run(image, func(t *testing.T) {
+			composeFile := "services:\n  cli:\n    image: " + image + "\n"
+			basePath := writeTestInstance(t, "test", composeFile)
+			oldConfig, err := GetInstanceConfig(basePath, "test")
+			if err != nil {
+				t.Fatal(err)
+			}
+			newConfig := InstanceConfig{Tag: "5.1.0", Environment: oldConfig.Environment}
+			if err := SaveInstanceConfig(basePath, "test", oldConfig, newConfig); err == nil {
+				t.Error("the tag set by a variable was changed")
+			}
+			content, err := os.ReadFile(filepath.Join(basePath, "test", "docker-compose.yml"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(content) != composeFile {
+				t.Errorf("compose file changed:\n%s", content)
+			}
+		})
+	}
+}
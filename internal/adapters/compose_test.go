@@ -0,0 +1,51 @@
+package adapters
+
+import (
+	"github.com/docker/compose/v2/pkg/api"
+	"testing"
+)
+
+func TestNewInstanceEvent(t *testing.T) {
+	tests := []struct {
+		name   string
+		action string
+		labels map[string]string
+		want   InstanceEvent
+		wantOk bool
+	}{
+		{
+			name:   "start",
+			action: "start",
+			labels: map[string]string{api.WorkingDirLabel: "/cc/dev"},
+			want:   InstanceEvent{BasePath: "/cc", Name: "dev", Action: "start"},
+			wantOk: true,
+		},
+		{
+			name:   "health status",
+			action: "health_status: unhealthy",
+			labels: map[string]string{api.WorkingDirLabel: "/cc/dev/"},
+			want:   InstanceEvent{BasePath: "/cc", Name: "dev", Action: "health_status"},
+			wantOk: true,
+		},
+		{
+			name:   "unclean working directory",
+			action: "die",
+			labels: map[string]string{api.WorkingDirLabel: "/cc//other/../dev"},
+			want:   InstanceEvent{BasePath: "/cc", Name: "dev", Action: "die"},
+			wantOk: true,
+		},
+		{
+			name:   "no compose project",
+			action: "start",
+			labels: map[string]string{"other": "label"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, ok := newInstanceEvent(test.action, test.labels)
+			if ok != test.wantOk || got != test.want {
+				t.Errorf("newInstanceEvent() = %+v, %v, want %+v, %v", got, ok, test.want, test.wantOk)
+			}
+		})
+	}
+}
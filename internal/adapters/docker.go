@@ -1,28 +1,25 @@
 package adapters
 
 import (
-	"bufio"
-	"bytes"
 	"context"
+	"encoding/json"
 	"fmt"
-	"github.com/compose-spec/compose-go/cli"
 	composeTypes "github.com/compose-spec/compose-go/types"
 	"github.com/docker/cli/cli/command"
+	"github.com/docker/cli/cli/context/docker"
 	"github.com/docker/cli/cli/flags"
 	"github.com/docker/compose/v2/cmd/formatter"
 	"github.com/docker/compose/v2/pkg/api"
 	"github.com/docker/compose/v2/pkg/compose"
 	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/events"
+	"github.com/docker/docker/api/types/filters"
 	"github.com/docker/docker/client"
-	resty "github.com/go-resty/resty/v2"
-	"github.com/moby/term"
-	"github.com/sirupsen/logrus"
 	"io"
-	"net"
-	"os"
-	"path/filepath"
 	"regexp"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 )
 
@@ -30,21 +27,56 @@ var _ BaseAdapter = &DockerAdapter{}
 
 // DockerAdapter implements CCmanager with docker and docker compose
 type DockerAdapter struct {
-	// dockerCLI holds the connection to a docker client
-	dockerCLI *client.Client
+	// Hosts configures the Docker engines the instances run on
+	Hosts DockerHosts
+	// lock guards endpoints
+	lock sync.Mutex
+	// endpoints holds the connections to the Docker engines by host
+	endpoints map[string]*endpointConnection
+	// connect connects to the Docker engine of a host. newDockerEndpoint is used if it is nil
+	connect func(host string) (*dockerEndpoint, error)
+}
+
+// endpointRetryInterval is the time after which connecting to a Docker engine is tried again after it failed
+const endpointRetryInterval = 10 * time.Second
+
+// endpointConnection holds the result of connecting to the Docker engine of a host
+type endpointConnection struct {
+	// done is closed when connecting has finished and endpoint or err are set
+	done     chan struct{}
+	endpoint *dockerEndpoint
+	err      error
+	// finished is the time connecting finished
+	finished time.Time
+}
+
+// dockerEndpoint holds the connections to a Docker engine
+type dockerEndpoint struct {
+	// dockerCLI holds the connection to the Docker API
+	dockerCLI client.APIClient
 	// composeBackend holds the connection to the docker compose service
-	composeBackend *api.Service
+	composeBackend api.Service
+	// hostName holds the name of the machine ports published by the engine are reachable on. It is empty for the
+	// local machine
+	hostName string
+	// tunnel forwards the published ports of engines reached over SSH. It is nil for other engines
+	tunnel *sshTunnel
 }
 
-func (d *DockerAdapter) GetContainerStatus(basePath string, name string) (CloudControlStatus, error) {
-	containerName := fmt.Sprintf("%s%scli%s1", name, api.Separator, api.Separator)
-	c := d.getClient()
+func (d *DockerAdapter) GetContainerStatus(ctx context.Context, basePath string, name string) (CloudControlStatus, error) {
+	containerName := cliContainerName(name)
+	e, err := d.getEndpoint(basePath, name)
+	if err != nil {
+		return CloudControlStatus{Error: err, Host: d.hostLabel(basePath, name)}, err
+	}
 	notFound := regexp.MustCompile("No such container")
-	if i, err := c.ContainerInspect(context.Background(), containerName); err != nil {
+	if i, err := e.dockerCLI.ContainerInspect(ctx, containerName); err != nil {
 		if notFound.Match([]byte(err.Error())) {
-			return d.getContainerStatusFromCompose(basePath, name)
+			s, err := getContainerStatusFromCompose(basePath, name)
+			s.Host = e.hostName
+			return s, err
 		}
-		return CloudControlStatus{Error: err}, fmt.Errorf("can not inspect container %s: %w", name, err)
+		return CloudControlStatus{Error: err, Host: e.hostName}, fmt.Errorf("can not inspect container %s: %w", name, err)
 	} else {
 		p := "n/a"
 		cs := CCCUndef
@@ -53,7 +85,7 @@ func (d *DockerAdapter) GetContainerStatus(basePath string, name string) (CloudC
 			if len(i.NetworkSettings.Ports["8080/tcp"]) == 1 {
 				p = i.NetworkSettings.Ports["8080/tcp"][0].HostPort
 				if i.State != nil && i.State.Running {
-					cs, err = d.getCCCStatus(p)
+					cs, err = getCCCStatus(ctx, e.hostName, p, e.tunnel.dialer(p))
 				}
 			} else {
 				cs = CCCErr
@@ -64,6 +96,7 @@ func (d *DockerAdapter) GetContainerStatus(basePath string, name string) (CloudC
 			err = fmt.Errorf("container status: %s (Exit Code %d) %s", i.State.Status, i.State.ExitCode, i.State.Error)
 		}
 		var portMappings []PortMap
+		image, tag := splitImage(i.Config.Image)
 
 		for port, bindings := range i.NetworkSettings.Ports {
 			if port != "8080/tcp" && len(bindings) > 0 {
@@ -73,326 +106,485 @@ func (d *DockerAdapter) GetContainerStatus(basePath string, name string) (CloudC
 				})
 			}
 		}
+		var health string
+		if i.State.Health != nil {
+			health = i.State.Health.Status
+		}
+		startedAt, _ := time.Parse(time.RFC3339Nano, i.State.StartedAt)
 		return CloudControlStatus{
 			Error:        err,
+			Host:         e.hostName,
 			Running:      i.State != nil && i.State.Running,
-			Image:        strings.Split(i.Config.Image, ":")[0],
-			Tag:          strings.Split(i.Config.Image, ":")[1],
+			Image:        image,
+			Tag:          tag,
 			CCCPort:      p,
 			CCCStatus:    cs,
 			PortMappings: portMappings,
+			Health:       health,
+			StartedAt:    startedAt,
+			RestartCount: i.RestartCount,
 		}, nil
 	}
 }
 
-func (d *DockerAdapter) RunCloudControl(_ string, name string, consoleWidth uint, consoleHeight uint) (*ContainerExec, error) {
-	containerName := fmt.Sprintf("%s%scli%s1", name, api.Separator, api.Separator)
-	consoleSize := [2]uint{consoleHeight, consoleWidth}
-	return &ContainerExec{
-		exec: func(stdin io.Reader, stdout io.Writer) error {
-			quitWriter := make(chan bool)
-			quitReader := make(chan bool)
-			quitTermResize := make(chan bool)
-			dockerCli := d.getClient()
-			var executeID string
-			if idResponse, err := dockerCli.ContainerExecCreate(context.Background(), containerName, types.ExecConfig{
-				AttachStdout: true,
-				AttachStderr: true,
-				AttachStdin:  true,
-				Tty:          true,
-				ConsoleSize:  &consoleSize,
-				Cmd:          []string{"/usr/local/bin/cloudcontrol", "run"},
-			}); err != nil {
-				return fmt.Errorf("can not create exec in container %s: %w", containerName, err)
-			} else {
-				executeID = idResponse.ID
-			}
-
-			var execResponse types.HijackedResponse
-			if resp, err := dockerCli.ContainerExecAttach(
-				context.Background(),
-				executeID,
-				types.ExecStartCheck{Tty: true, ConsoleSize: &consoleSize},
-			); err != nil {
-				return fmt.Errorf("can not attach to exec in container %s: %w", containerName, err)
-			} else {
-				execResponse = resp
-				defer execResponse.Close()
-			}
-			fd, _ := term.GetFdInfo(stdin)
-			var originalState *term.State
-			if s, err := term.SetRawTerminal(fd); err != nil {
-				return fmt.Errorf("can not set terminal to raw: %w", err)
-			} else {
-				originalState = s
-			}
+func (d *DockerAdapter) RunCloudControl(ctx context.Context, basePath string, name string, consoleWidth uint, consoleHeight uint) (*ContainerExec, error) {
+	return d.Exec(ctx, basePath, name, ExecOptions{
+		Service:       "cli",
+		Command:       CloudControlCommand,
+		ConsoleWidth:  consoleWidth,
+		ConsoleHeight: consoleHeight,
+	})
+}
 
-			if err := dockerCli.ContainerExecResize(context.Background(), executeID, types.ResizeOptions{
-				Width:  consoleWidth,
-				Height: consoleHeight,
-			}); err != nil {
-				return fmt.Errorf("can not resize tty of exec in container %s: %w", containerName, err)
-			}
+func (d *DockerAdapter) Exec(ctx context.Context, basePath string, name string, options ExecOptions) (*ContainerExec, error) {
+	containerName := serviceContainerName(name, options.Service)
+	consoleWidth, consoleHeight := options.ConsoleWidth, options.ConsoleHeight
+	consoleSize := [2]uint{consoleHeight, consoleWidth}
+	return newContainerExec(func(stdin io.Reader, stdout io.Writer, resized <-chan ttySize) error {
+		e, err := d.getEndpoint(basePath, name)
+		if err != nil {
+			return err
+		}
+		dockerCli := e.dockerCLI
+		var executeID string
+		if idResponse, err := dockerCli.ContainerExecCreate(ctx, containerName, types.ExecConfig{
+			AttachStdout: true,
+			AttachStderr: true,
+			AttachStdin:  true,
+			Tty:          true,
+			ConsoleSize:  &consoleSize,
+			Cmd:          execCommand(options),
+		}); err != nil {
+			return fmt.Errorf("can not create exec in container %s: %w", containerName, err)
+		} else {
+			executeID = idResponse.ID
+		}
 
-			go func(c net.Conn) {
-				active := true
-				s := bufio.NewReader(c)
-				for {
-					select {
-					case <-quitWriter:
-						return
-					default:
-						if active {
-							if b, err := s.ReadByte(); err != nil {
-								logrus.Errorf("error reading from Docker: %s", err)
-								active = false
-							} else {
-								if _, err := stdout.Write([]byte{b}); err != nil {
-									logrus.Errorf("error writing to stdout: %s", err)
-								}
-							}
-						}
-					}
-				}
-			}(execResponse.Conn)
-
-			go func(c net.Conn) {
-				active := true
-				s := bufio.NewReader(stdin)
-				for {
-					select {
-					case <-quitReader:
-						return
-					default:
-						if active {
-							if b, err := s.ReadByte(); err != nil {
-								logrus.Errorf("error reading from Docker: %s", err)
-								active = false
-							} else {
-								if _, err := c.Write([]byte{b}); err != nil {
-									logrus.Errorf("error writing to Docker: %s", err)
-									active = false
-								}
-							}
-						}
-					}
-				}
-			}(execResponse.Conn)
-
-			go func() {
-				active := true
-				width := consoleWidth
-				height := consoleHeight
-				for {
-					select {
-					case <-quitTermResize:
-						return
-					default:
-						if active {
-							if w, err := term.GetWinsize(fd); err != nil {
-								logrus.Errorf("error getting terminal size: %s", err)
-								active = false
-							} else {
-								if w.Width != uint16(width) || w.Height != uint16(height) {
-									width = uint(w.Width)
-									height = uint(w.Height)
-									if err := dockerCli.ContainerExecResize(context.Background(), executeID, types.ResizeOptions{
-										Width:  width,
-										Height: height,
-									}); err != nil {
-										logrus.Errorf("error resizing container terminal: %s", err)
-										active = false
-									}
-								}
-							}
-						}
-					}
-				}
-			}()
+		var execResponse types.HijackedResponse
+		if resp, err := dockerCli.ContainerExecAttach(
+			ctx,
+			executeID,
+			types.ExecStartCheck{Tty: true, ConsoleSize: &consoleSize},
+		); err != nil {
+			return fmt.Errorf("can not attach to exec in container %s: %w", containerName, err)
+		} else {
+			execResponse = resp
+			defer execResponse.Close()
+		}
 
-			for {
-				if execInspect, err := dockerCli.ContainerExecInspect(context.Background(), executeID); err != nil {
-					return fmt.Errorf("error running in container %s: %w", containerName, err)
+		return bridgeExec(execBridge{
+			containerName: containerName,
+			command:       options.Command,
+			recordingFile: options.RecordingFile,
+			recordInput:   options.RecordInput,
+			resized:       resized,
+			conn:          execResponse.Conn,
+			stdin:         stdin,
+			stdout:        stdout,
+			width:         consoleWidth,
+			height:        consoleHeight,
+			resize: func(width uint, height uint) error {
+				return dockerCli.ContainerExecResize(ctx, executeID, types.ResizeOptions{
+					Width:  width,
+					Height: height,
+				})
+			},
+			inspect: func() (bool, int, error) {
+				if execInspect, err := dockerCli.ContainerExecInspect(ctx, executeID); err != nil {
+					return false, 0, err
 				} else {
-					if execInspect.Running {
-						time.Sleep(2 * time.Second)
-						continue
-					}
-					print("CloudControl closed. Press any key to proceed.")
-					quitWriter <- true
-					quitReader <- true
-					quitTermResize <- true
-					if err := term.RestoreTerminal(fd, originalState); err != nil {
-						return fmt.Errorf("can not restore terminal: %w", err)
-					}
-					if execInspect.ExitCode != 0 {
-						return fmt.Errorf("error running CloudControl in container %s: %s", containerName, "")
-					}
-					return nil
+					return execInspect.Running, execInspect.ExitCode, nil
 				}
-			}
-		},
-	}, nil
+			},
+		})
+	}), nil
 }
 
-func (d DockerAdapter) StartCloudControl(basePath string, name string) error {
-	return d.up(basePath, name, true)
+func (d *DockerAdapter) StartCloudControl(ctx context.Context, basePath string, name string) error {
+	return d.up(ctx, basePath, name, true)
 }
 
-func (d DockerAdapter) StopCloudControl(basePath string, name string, _ bool) error {
-	return d.down(basePath, name)
+func (d *DockerAdapter) StopCloudControl(ctx context.Context, basePath string, name string, _ bool) error {
+	return d.down(ctx, basePath, name)
 }
-func (d *DockerAdapter) GetLogs(basePath string, name string) (string, error) {
+func (d *DockerAdapter) GetLogs(ctx context.Context, basePath string, name string) (string, error) {
+	r, err := d.StreamLogs(ctx, basePath, name, LogOptions{})
+	if err != nil {
+		return "", err
+	}
+	defer r.Close()
+	l, err := io.ReadAll(r)
+	return string(l), err
+}
+
+func (d *DockerAdapter) StreamLogs(ctx context.Context, basePath string, name string, options LogOptions) (io.ReadCloser, error) {
 	var project *composeTypes.Project
 
-	if p, err := d.getProject(basePath, name); err != nil {
-		return "", err
+	if p, err := getProject(basePath, name); err != nil {
+		return nil, err
 	} else {
 		project = p
 	}
-	c := d.getComposeBackend()
-	lS := bytes.NewBufferString("")
-	lC := formatter.NewLogConsumer(context.Background(), lS, lS, false, false, true)
-	if err := c.Logs(context.Background(), project.Name, lC, api.LogOptions{
-		Project: project,
-	}); err != nil {
-		return "", err
+	e, err := d.getEndpoint(basePath, name)
+	if err != nil {
+		return nil, err
 	}
-	return lS.String(), nil
+	c := e.composeBackend
+	return streamTo(ctx, func(ctx context.Context, w io.Writer) error {
+		lC := formatter.NewLogConsumer(ctx, w, w, false, false, true)
+		return c.Logs(ctx, project.Name, lC, api.LogOptions{
+			Project:  project,
+			Services: options.Services,
+			Follow:   options.Follow,
+			Since:    options.Since,
+			Tail:     options.Tail,
+		})
+	}), nil
 }
 
-// getClient returns an already created connection to the docker client or creates one
-func (d *DockerAdapter) getClient() client.Client {
-	if d.dockerCLI == nil {
-		if dockerCli, err := client.NewClientWithOpts(client.FromEnv); err != nil {
-			panic(fmt.Sprintf("Can not connect to Docker API: %s", err.Error()))
-		} else {
-			dockerCli.NegotiateAPIVersion(context.Background())
-			d.dockerCLI = dockerCli
-		}
+func (d *DockerAdapter) GetServices(_ context.Context, basePath string, name string) ([]string, error) {
+	return getServices(basePath, name)
+}
+
+func (d *DockerAdapter) Events(ctx context.Context) (<-chan InstanceEvent, <-chan error) {
+	args := filters.NewArgs(
+		filters.Arg("type", events.ContainerEventType),
+		filters.Arg("label", api.ProjectLabel),
+	)
+	for _, action := range instanceEventActions {
+		args.Add("event", action)
 	}
-	return *d.dockerCLI
+
+	// The events of all engines used by the instances are merged. A failing engine doesn't affect the others
+	hosts := d.Hosts.Used()
+	instanceEvents := make(chan InstanceEvent)
+	instanceErrs := make(chan error, len(hosts))
+	var wg sync.WaitGroup
+	for _, host := range hosts {
+		wg.Add(1)
+		go func(host string) {
+			defer wg.Done()
+			d.watchEvents(ctx, host, args, instanceEvents, instanceErrs)
+		}(host)
+	}
+	go func() {
+		wg.Wait()
+		close(instanceEvents)
+		close(instanceErrs)
+	}()
+	return instanceEvents, instanceErrs
 }
 
-// getComposeBackend retuns an already open connection to the docker compose service or
-// creates one
-func (d *DockerAdapter) getComposeBackend() api.Service {
-	if d.composeBackend == nil {
-		cl := d.getClient()
-		if c, err := command.NewDockerCli(command.WithAPIClient(&cl), command.WithDefaultContextStoreConfig()); err != nil {
-			panic(fmt.Sprintf("Can not connect to Docker API: %s", err.Error()))
-		} else {
-			if err := c.Initialize(flags.NewClientOptions()); err != nil {
-				panic(fmt.Sprintf("Can not initialize docker cli: %s", err.Error()))
+// hostEventRetryInterval is the time after which the events of a Docker engine are subscribed again after the stream
+// failed
+const hostEventRetryInterval = 30 * time.Second
+
+// watchEvents sends the instance events of the engine of a host until ctx is done. A lost stream is subscribed again
+// after hostEventRetryInterval and receives the events missed in the meantime. Failures are sent to instanceErrs
+// unless the previous subscription failed right away as well, so an unreachable host is only reported once
+func (d *DockerAdapter) watchEvents(ctx context.Context, host string, args filters.Args, instanceEvents chan<- InstanceEvent, instanceErrs chan<- error) {
+	var since time.Time
+	// failing tells whether the stream has been lost before
+	failing := false
+	for {
+		started := time.Now()
+		err := d.streamEvents(ctx, host, since, args, instanceEvents)
+		if ctx.Err() != nil {
+			return
+		}
+		if !failing || time.Since(started) > hostEventRetryInterval {
+			since = time.Now()
+			if err != nil {
+				select {
+				case instanceErrs <- err:
+				default:
+				}
 			}
-			s := compose.NewComposeService(c)
-			d.composeBackend = &s
 		}
+		failing = true
+		select {
+		case <-time.After(hostEventRetryInterval):
+		case <-ctx.Done():
+			return
+		}
+	}
+}
 
+// streamEvents sends the instance events of the engine of a host that happened after since (or from now on if since
+// is zero) until ctx is done or the stream fails
+func (d *DockerAdapter) streamEvents(ctx context.Context, host string, since time.Time, args filters.Args, instanceEvents chan<- InstanceEvent) error {
+	e, err := d.getHostEndpoint(host)
+	if err != nil {
+		return err
+	}
+	options := types.EventsOptions{Filters: args}
+	if !since.IsZero() {
+		options.Since = strconv.FormatInt(since.Unix(), 10)
+	}
+	messages, errs := e.dockerCLI.Events(ctx, options)
+	for {
+		select {
+		case m := <-messages:
+			if event, ok := newInstanceEvent(m.Action, m.Actor.Attributes); ok {
+				select {
+				case instanceEvents <- event:
+				case <-ctx.Done():
+					return nil
+				}
+			}
+		case err := <-errs:
+			if err != nil && ctx.Err() == nil {
+				if host != "" {
+					return fmt.Errorf("can not stream docker events of %s: %w", host, err)
+				}
+				return fmt.Errorf("can not stream docker events: %w", err)
+			}
+			return nil
+		}
 	}
-	return *d.composeBackend
 }
 
-// getCCCStatus retrieves status information as a CCCStatus struct from CCC listening on the given port
-func (d DockerAdapter) getCCCStatus(port string) (CCCStatus, error) {
-	type cccBackendStatus struct {
-		Status string
+func (d *DockerAdapter) GetResourceUsage(ctx context.Context, basePath string, name string) (ResourceUsage, error) {
+	containerName := cliContainerName(name)
+	e, err := d.getEndpoint(basePath, name)
+	if err != nil {
+		return ResourceUsage{}, err
 	}
-	c := resty.New()
-	c.SetCloseConnection(true)
-	statusResult := cccBackendStatus{}
-	if resp, err := c.R().SetResult(&statusResult).Get(fmt.Sprintf("http://localhost:%s/api/status", port)); err != nil {
-		return CCCErr, err
-	} else {
-		if resp.IsError() {
-			return CCCErr, nil
+	resp, err := e.dockerCLI.ContainerStats(ctx, containerName, false)
+	if err != nil {
+		return ResourceUsage{}, fmt.Errorf("can not get stats of container %s: %w", containerName, err)
+	}
+	defer resp.Body.Close()
+	var stats types.StatsJSON
+	if err := json.NewDecoder(resp.Body).Decode(&stats); err != nil {
+		return ResourceUsage{}, fmt.Errorf("can not read stats of container %s: %w", containerName, err)
+	}
+	return dockerResourceUsage(stats), nil
+}
+
+func (d *DockerAdapter) StreamResourceUsage(ctx context.Context, basePath string, name string) (<-chan ResourceUsage, <-chan error) {
+	containerName := cliContainerName(name)
+	usages := make(chan ResourceUsage)
+	errs := make(chan error, 1)
+	go func() {
+		defer close(usages)
+		defer close(errs)
+		err := func() error {
+			e, err := d.getEndpoint(basePath, name)
+			if err != nil {
+				return err
+			}
+			resp, err := e.dockerCLI.ContainerStats(ctx, containerName, true)
+			if err != nil {
+				return err
+			}
+			defer resp.Body.Close()
+			decoder := json.NewDecoder(resp.Body)
+			for {
+				var stats types.StatsJSON
+				if err := decoder.Decode(&stats); err != nil {
+					return err
+				}
+				select {
+				case usages <- dockerResourceUsage(stats):
+				case <-ctx.Done():
+					return nil
+				}
+			}
+		}()
+		if ctx.Err() == nil {
+			errs <- fmt.Errorf("can not stream stats of container %s: %w", containerName, err)
 		}
-		switch statusResult.Status {
-		case "INIT":
-			return CCCInit, nil
-		case "INITIALIZED":
-			return CCCReady, nil
-		default:
-			return CCCErr, fmt.Errorf("unknown CCC state: %s", statusResult.Status)
+	}()
+	return usages, errs
+}
+
+func (d *DockerAdapter) GetVolumeSizes(ctx context.Context, basePath string, name string) ([]VolumeSize, error) {
+	volumes, err := getVolumeNames(basePath, name)
+	if err != nil {
+		return nil, err
+	}
+	e, err := d.getEndpoint(basePath, name)
+	if err != nil {
+		return nil, err
+	}
+	du, err := e.dockerCLI.DiskUsage(ctx, types.DiskUsageOptions{Types: []types.DiskUsageObject{types.VolumeObject}})
+	if err != nil {
+		return nil, fmt.Errorf("can not get disk usage: %w", err)
+	}
+	sizes := map[string]int64{}
+	for _, v := range du.Volumes {
+		if v.UsageData != nil {
+			sizes[v.Name] = v.UsageData.Size
 		}
 	}
+	return newVolumeSizes(volumes, sizes), nil
 }
 
-// getContainerStatusFromCompose is used if not enough information can be resolved from a running container
-func (d DockerAdapter) getContainerStatusFromCompose(basePath string, name string) (CloudControlStatus, error) {
-	var project *composeTypes.Project
+func (d *DockerAdapter) ForwardPorts(ctx context.Context, basePath string, name string, ports []string) ([]PortForward, error) {
+	e, err := d.getEndpoint(basePath, name)
+	if err != nil {
+		return nil, err
+	}
+	if e.tunnel == nil {
+		return directForwards(e.hostName, ports), nil
+	}
+	return e.tunnel.forward(ctx, ports)
+}
 
-	if p, err := d.getProject(basePath, name); err != nil {
-		return CloudControlStatus{Error: err}, err
-	} else {
-		project = p
+// dockerResourceUsage calculates the resource usage from container stats the same way docker stats does
+func dockerResourceUsage(stats types.StatsJSON) ResourceUsage {
+	usage := ResourceUsage{
+		MemoryUsage: stats.MemoryStats.Usage,
+		MemoryLimit: stats.MemoryStats.Limit,
 	}
+	// The page cache is not counted as used memory (inactive_file on cgroup v2, total_inactive_file on cgroup v1)
+	for _, cache := range []string{"inactive_file", "total_inactive_file"} {
+		if v, ok := stats.MemoryStats.Stats[cache]; ok && v < usage.MemoryUsage {
+			usage.MemoryUsage -= v
+			break
+		}
+	}
+	cpuDelta := float64(stats.CPUStats.CPUUsage.TotalUsage) - float64(stats.PreCPUStats.CPUUsage.TotalUsage)
+	systemDelta := float64(stats.CPUStats.SystemUsage) - float64(stats.PreCPUStats.SystemUsage)
+	cpus := float64(stats.CPUStats.OnlineCPUs)
+	if cpus == 0 {
+		cpus = float64(len(stats.CPUStats.CPUUsage.PercpuUsage))
+	}
+	if cpuDelta > 0 && systemDelta > 0 {
+		usage.CPUPercent = cpuDelta / systemDelta * cpus * 100
+	}
+	for _, n := range stats.Networks {
+		usage.NetworkRx += n.RxBytes
+		usage.NetworkTx += n.TxBytes
+	}
+	for _, b := range stats.BlkioStats.IoServiceBytesRecursive {
+		switch strings.ToLower(b.Op) {
+		case "read":
+			usage.BlockRead += b.Value
+		case "write":
+			usage.BlockWrite += b.Value
+		}
+	}
+	return usage
+}
 
-	var cliService composeTypes.ServiceConfig
-	if c, err := project.GetService("cli"); err != nil {
-		return CloudControlStatus{Error: err}, err
-	} else {
-		cliService = c
-	}
-	image := strings.Split(cliService.Image, ":")[0]
-	tag := strings.Split(cliService.Image, ":")[1]
-	return CloudControlStatus{
-		Running:   false,
-		Image:     image,
-		Tag:       tag,
-		CCCStatus: CCCDown,
-		CCCPort:   "n/a",
-	}, nil
+// getEndpoint returns the connections to the Docker engine of an instance
+func (d *DockerAdapter) getEndpoint(basePath string, name string) (*dockerEndpoint, error) {
+	return d.getHostEndpoint(d.Hosts.Host(basePath, name))
 }
 
-// getProject loads a docker compose project for an instance identified by basePath and name
-func (d DockerAdapter) getProject(basePath string, name string) (*composeTypes.Project, error) {
-	yamlFiles := []string{
-		fmt.Sprintf("%s/docker-compose.yaml", filepath.Join(basePath, name)),
-		fmt.Sprintf("%s/docker-compose.yml", filepath.Join(basePath, name)),
+// getHostEndpoint returns the already created connections to the Docker engine of a host or creates them. Only one
+// caller connects to a host at a time, the others wait for its result without blocking callers using other hosts.
+// Failures are returned to all callers until endpointRetryInterval has passed
+func (d *DockerAdapter) getHostEndpoint(host string) (*dockerEndpoint, error) {
+	d.lock.Lock()
+	c, ok := d.endpoints[host]
+	if ok && c.expired() {
+		ok = false
 	}
-	var yamlFile string
-	for _, file := range yamlFiles {
-		if _, err := os.Stat(file); err == nil {
-			yamlFile = file
-		}
+	if ok {
+		d.lock.Unlock()
+		<-c.done
+		return c.endpoint, c.err
+	}
+	c = &endpointConnection{done: make(chan struct{})}
+	if d.endpoints == nil {
+		d.endpoints = map[string]*endpointConnection{}
 	}
+	d.endpoints[host] = c
+	d.lock.Unlock()
 
-	if yamlFile == "" {
-		err := fmt.Errorf("can't find docker-compose file for %s at path %s", name, basePath)
-		return nil, err
+	connect := d.connect
+	if connect == nil {
+		connect = newDockerEndpoint
 	}
+	c.endpoint, c.err = connect(host)
+	c.finished = time.Now()
+	close(c.done)
+	return c.endpoint, c.err
+}
 
-	var project *composeTypes.Project
+// expired tells whether connecting has failed longer than endpointRetryInterval ago, so it should be tried again
+func (c *endpointConnection) expired() bool {
+	select {
+	case <-c.done:
+		return c.err != nil && time.Since(c.finished) > endpointRetryInterval
+	default:
+		return false
+	}
+}
 
-	if p, err := cli.ProjectFromOptions(&cli.ProjectOptions{
-		WorkingDir:  filepath.Join(basePath, name),
-		ConfigPaths: []string{yamlFile},
-		Environment: map[string]string{},
-	}); err != nil {
-		return nil, err
+// hostLabel returns the name of the machine the Docker engine of an instance runs on without connecting to it
+func (d *DockerAdapter) hostLabel(basePath string, name string) string {
+	host := d.Hosts.Host(basePath, name)
+	if isHostURL(host) {
+		return hostName(host)
+	}
+	return host
+}
+
+// newDockerEndpoint connects to a Docker engine. The local engine is configured by the environment (e.g. DOCKER_HOST),
+// other engines by the URL of their daemon or the name of their Docker context
+func newDockerEndpoint(host string) (*dockerEndpoint, error) {
+	options := flags.NewClientOptions()
+	var dockerCli client.APIClient
+	daemonURL := host
+	if host == "" {
+		c, err := client.NewClientWithOpts(client.FromEnv)
+		if err != nil {
+			return nil, fmt.Errorf("can not connect to Docker API: %w", err)
+		}
+		c.NegotiateAPIVersion(context.Background())
+		dockerCli = c
+	} else if isHostURL(host) {
+		options.Hosts = []string{host}
 	} else {
-		p.Name = name
-		project = p
-		for i, s := range project.Services {
-			s.CustomLabels = map[string]string{
-				api.ProjectLabel:     project.Name,
-				api.ServiceLabel:     s.Name,
-				api.VersionLabel:     api.ComposeVersion,
-				api.WorkingDirLabel:  project.WorkingDir,
-				api.ConfigFilesLabel: strings.Join(project.ComposeFiles, ","),
-				api.OneoffLabel:      "False", // default, will be overridden by `run` command
-			}
-			project.Services[i] = s
+		options.Context = host
+	}
+
+	cli, err := command.NewDockerCli(command.WithDefaultContextStoreConfig())
+	if err != nil {
+		return nil, fmt.Errorf("can not connect to Docker API: %w", err)
+	}
+	if err := cli.Initialize(options); err != nil {
+		return nil, fmt.Errorf("can not initialize docker cli: %w", err)
+	}
+	if host != "" && !isHostURL(host) {
+		metadata, err := cli.ContextStore().GetMetadata(host)
+		if err != nil {
+			return nil, fmt.Errorf("can not load Docker context %s: %w", host, err)
 		}
+		endpoint, err := docker.EndpointFromContext(metadata)
+		if err != nil {
+			return nil, fmt.Errorf("can not load Docker context %s: %w", host, err)
+		}
+		daemonURL = endpoint.Host
+	}
+	if dockerCli == nil {
+		if dockerCli, err = command.NewAPIClientFromFlags(options, cli.ConfigFile()); err != nil {
+			return nil, fmt.Errorf("can not connect to Docker host %s: %w", host, err)
+		}
+	}
+	if err := cli.Apply(command.WithAPIClient(dockerCli)); err != nil {
+		return nil, fmt.Errorf("can not initialize docker cli: %w", err)
 	}
-	return project, nil
+	tunnel, err := newSSHTunnel(daemonURL)
+	if err != nil {
+		return nil, err
+	}
+	return &dockerEndpoint{
+		dockerCLI:      dockerCli,
+		composeBackend: compose.NewComposeService(cli),
+		hostName:       hostName(daemonURL),
+		tunnel:         tunnel,
+	}, nil
 }
 
 // up calls docker compose up on an instance
-func (d *DockerAdapter) up(path string, name string, pull bool) error {
+func (d *DockerAdapter) up(ctx context.Context, path string, name string, pull bool) error {
 	var project *composeTypes.Project
-	if p, err := d.getProject(path, name); err != nil {
+	if p, err := getProject(path, name); err != nil {
 		return err
 	} else {
 		project = p
@@ -403,23 +595,29 @@ func (d *DockerAdapter) up(path string, name string, pull bool) error {
 			service.PullPolicy = "Always"
 		}
 	}
-	c := d.getComposeBackend()
-	return c.Up(context.Background(), project, api.UpOptions{
+	e, err := d.getEndpoint(path, name)
+	if err != nil {
+		return err
+	}
+	return e.composeBackend.Up(ctx, project, api.UpOptions{
 		Create: api.CreateOptions{QuietPull: true, RemoveOrphans: true, Recreate: api.RecreateDiverged},
 		Start:  api.StartOptions{Wait: true, Project: project},
 	})
 }
 
 // down calls docker compose down on an instance
-func (d *DockerAdapter) down(path string, name string) error {
+func (d *DockerAdapter) down(ctx context.Context, path string, name string) error {
 	var project *composeTypes.Project
-	if p, err := d.getProject(path, name); err != nil {
+	if p, err := getProject(path, name); err != nil {
 		return err
 	} else {
 		project = p
 	}
-	c := d.getComposeBackend()
-	return c.Down(context.Background(), project.Name, api.DownOptions{
+	e, err := d.getEndpoint(path, name)
+	if err != nil {
+		return err
+	}
+	return e.composeBackend.Down(ctx, project.Name, api.DownOptions{
 		RemoveOrphans: true,
 		Project:       project,
 		Volumes:       true,
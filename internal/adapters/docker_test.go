@@ -0,0 +1,131 @@
+package adapters
+
+import (
+	"context"
+	"errors"
+	"github.com/docker/compose/v2/pkg/api"
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/events"
+	"github.com/docker/docker/client"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestGetHostEndpoint(t *testing.T) {
+	var calls atomic.Int32
+	release := make(chan struct{})
+	d := &DockerAdapter{connect: func(host string) (*dockerEndpoint, error) {
+		calls.Add(1)
+		if host == "slow" {
+			<-release
+		}
+		return &dockerEndpoint{hostName: host}, nil
+	}}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if e, err := d.getHostEndpoint("slow"); err != nil || e.hostName != "slow" {
+				t.Errorf("getHostEndpoint() = %v, %v", e, err)
+			}
+		}()
+	}
+
+	// Connecting to another host isn't blocked by the slow one
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		if _, err := d.getHostEndpoint("fast"); err != nil {
+			t.Error(err)
+		}
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("connecting to a host waited for another host")
+	}
+
+	close(release)
+	wg.Wait()
+	if n := calls.Load(); n != 2 {
+		t.Errorf("connected %d times, want once per host", n)
+	}
+}
+
+func TestGetHostEndpointCachesErrors(t *testing.T) {
+	calls := 0
+	d := &DockerAdapter{connect: func(host string) (*dockerEndpoint, error) {
+		calls++
+		return nil, errors.New("host unreachable")
+	}}
+	for i := 0; i < 3; i++ {
+		if _, err := d.getHostEndpoint("down"); err == nil {
+			t.Fatal("getHostEndpoint() didn't return the error")
+		}
+	}
+	if calls != 1 {
+		t.Errorf("connected %d times within the retry interval, want 1", calls)
+	}
+
+	d.endpoints["down"].finished = time.Now().Add(-2 * endpointRetryInterval)
+	_, _ = d.getHostEndpoint("down")
+	if calls != 2 {
+		t.Errorf("connected %d times after the retry interval, want 2", calls)
+	}
+}
+
+// fakeEventsClient streams the events sent to its channel
+type fakeEventsClient struct {
+	client.APIClient
+	events chan events.Message
+}
+
+func (c fakeEventsClient) Events(context.Context, types.EventsOptions) (<-chan events.Message, <-chan error) {
+	return c.events, make(chan error)
+}
+
+func TestEventsWithFailingHost(t *testing.T) {
+	local := fakeEventsClient{events: make(chan events.Message)}
+	d := &DockerAdapter{
+		Hosts: DockerHosts{
+			BasePaths: map[string]string{"/remote": "ssh://unreachable"},
+			Paths:     []string{"/local", "/remote"},
+		},
+		connect: func(host string) (*dockerEndpoint, error) {
+			if host == "" {
+				return &dockerEndpoint{dockerCLI: local}, nil
+			}
+			return nil, errors.New("host unreachable")
+		},
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	instanceEvents, errs := d.Events(ctx)
+
+	select {
+	case err := <-errs:
+		if err == nil || !strings.Contains(err.Error(), "unreachable") {
+			t.Fatalf("got error %v, want the error of the unreachable host", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("the failing host wasn't reported")
+	}
+
+	// The events of the local engine are still received
+	local.events <- events.Message{Action: "start", Actor: events.Actor{Attributes: map[string]string{
+		api.WorkingDirLabel: "/local/test",
+	}}}
+	select {
+	case event := <-instanceEvents:
+		if event.BasePath != "/local" || event.Name != "test" || event.Action != "start" {
+			t.Errorf("got event %+v", event)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("the events of the local engine stopped with the failing host")
+	}
+}
@@ -0,0 +1,195 @@
+package adapters
+
+import (
+	"ccmanager/internal/recordings"
+	"errors"
+	"fmt"
+	"github.com/moby/term"
+	"github.com/muesli/cancelreader"
+	"io"
+	"net"
+	"os"
+	"strings"
+	"time"
+)
+
+// exitCodeTimeout is the time to wait for the container engine to report the exit code after the exec stream closed
+const exitCodeTimeout = 5 * time.Second
+
+// ExecExitError is returned by ContainerExec.Run if the command exited with a non-zero exit code
+type ExecExitError struct {
+	// ContainerName is the name of the container the command ran in
+	ContainerName string
+	// ExitCode is the exit code of the command
+	ExitCode int
+}
+
+func (e ExecExitError) Error() string {
+	return fmt.Sprintf("command in container %s exited with code %d", e.ContainerName, e.ExitCode)
+}
+
+// execBridge holds everything needed to connect the local terminal with an exec session in a container
+type execBridge struct {
+	// containerName is the name of the container the exec runs in
+	containerName string
+	// command holds the command of the exec session
+	command []string
+	// recordingFile is the path of the file the session is recorded into. The session isn't recorded if it is empty
+	recordingFile string
+	// recordInput tells whether the input is recorded as well
+	recordInput bool
+	// conn is the hijacked connection to the exec session
+	conn net.Conn
+	// stdin is the reader to read terminal input from
+	stdin io.Reader
+	// stdout is the writer to write the exec output to
+	stdout io.Writer
+	// width is the initial width of the console
+	width uint
+	// height is the initial height of the console
+	height uint
+	// resize resizes the tty of the exec session
+	resize func(width uint, height uint) error
+	// inspect returns whether the exec session is still running and its exit code
+	inspect func() (bool, int, error)
+	// resized receives sizes the tty should be resized to
+	resized <-chan ttySize
+}
+
+// bridgeExec copies input and output between the local terminal and an exec session until the session ends. The
+// session has ended when the container engine closes the output stream. The tty of the session follows the size of
+// the local terminal. If a recording file is set, output and resizes are recorded, the input only if recordInput is
+// set. Failing to record doesn't end the session, the problem is written to the output instead
+func bridgeExec(e execBridge) error {
+	fd, isTerminal := term.GetFdInfo(e.stdin)
+	if isTerminal {
+		originalState, err := term.SetRawTerminal(fd)
+		if err != nil {
+			return fmt.Errorf("can not set terminal to raw: %w", err)
+		}
+		defer func() {
+			_ = term.RestoreTerminal(fd, originalState)
+		}()
+	}
+
+	if err := e.resize(e.width, e.height); err != nil {
+		return fmt.Errorf("can not resize tty of exec in container %s: %w", e.containerName, err)
+	}
+
+	stdout := e.stdout
+	var recorder *recordings.Recorder
+	if e.recordingFile != "" {
+		r, err := recordings.Create(e.recordingFile, recordings.Header{
+			Width:   e.width,
+			Height:  e.height,
+			Command: strings.Join(e.command, " "),
+			Title:   e.containerName,
+			Env:     map[string]string{"TERM": os.Getenv("TERM")},
+		})
+		if err != nil {
+			// A failed recording doesn't prevent the session, the problem is shown in front of its output
+			_, _ = fmt.Fprintf(e.stdout, "Session is not recorded: %s\r\n", err.Error())
+		} else {
+			defer r.Close()
+			recorder = r
+			stdout = io.MultiWriter(stdout, r.Writer(recordings.EventOutput))
+		}
+	}
+
+	// The input is read using a cancelable reader, so reading stops as soon as the session has ended instead of
+	// swallowing the next key press
+	var input io.Reader = e.stdin
+	inputDone := make(chan struct{})
+	if r, err := cancelreader.NewReader(e.stdin); err == nil {
+		input = r
+		defer func() {
+			if r.Cancel() {
+				<-inputDone
+			}
+			_ = r.Close()
+		}()
+	}
+	if recorder != nil && e.recordInput {
+		input = io.TeeReader(input, recorder.Writer(recordings.EventInput))
+	}
+	go func() {
+		defer close(inputDone)
+		if _, err := io.Copy(e.conn, input); err == nil {
+			// Pass the end of the input on to the session
+			if c, ok := e.conn.(interface{ CloseWrite() error }); ok {
+				_ = c.CloseWrite()
+			}
+		}
+	}()
+
+	// The tty follows the size of the local terminal or the sizes requested using ContainerExec.Resize
+	var terminalResized <-chan struct{}
+	if isTerminal {
+		resized, stop := notifyResize()
+		defer stop()
+		terminalResized = resized
+	}
+	resizeDone := make(chan struct{})
+	defer close(resizeDone)
+	go func() {
+		current := ttySize{width: e.width, height: e.height}
+		for {
+			var size ttySize
+			select {
+			case <-terminalResized:
+				w, err := term.GetWinsize(fd)
+				if err != nil {
+					continue
+				}
+				size = ttySize{width: uint(w.Width), height: uint(w.Height)}
+			case size = <-e.resized:
+			case <-resizeDone:
+				return
+			}
+			if size == current {
+				continue
+			}
+			current = size
+			_ = e.resize(size.width, size.height)
+			if recorder != nil {
+				_ = recorder.Resize(size.width, size.height)
+			}
+		}
+	}()
+
+	if _, err := io.Copy(stdout, e.conn); err != nil && !errors.Is(err, net.ErrClosed) {
+		return fmt.Errorf("can not read output of exec in container %s: %w", e.containerName, err)
+	}
+	if recorder != nil && recorder.Err() != nil {
+		// Like a failed start, a recording that stopped during the session is only reported
+		_, _ = fmt.Fprintf(e.stdout, "\r\nSession recording stopped: %s\r\n", recorder.Err().Error())
+	}
+
+	exitCode, err := e.exitCode()
+	if err != nil {
+		return fmt.Errorf("can not get exit code of exec in container %s: %w", e.containerName, err)
+	}
+	if exitCode != 0 {
+		return ExecExitError{ContainerName: e.containerName, ExitCode: exitCode}
+	}
+	return nil
+}
+
+// exitCode returns the exit code of the exec session. The container engine may report the session as running for
+// a short time after its stream has been closed
+func (e execBridge) exitCode() (int, error) {
+	deadline := time.Now().Add(exitCodeTimeout)
+	for {
+		running, exitCode, err := e.inspect()
+		if err != nil {
+			return 0, err
+		}
+		if !running {
+			return exitCode, nil
+		}
+		if time.Now().After(deadline) {
+			return 0, fmt.Errorf("exec is still running")
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+}
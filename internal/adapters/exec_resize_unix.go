@@ -0,0 +1,37 @@
+//go:build !windows
+
+package adapters
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// notifyResize returns a channel receiving a value whenever the terminal window has been resized (SIGWINCH) and a
+// function that stops the notifications and closes the channel
+func notifyResize() (<-chan struct{}, func()) {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGWINCH)
+	resized := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(resized)
+		for {
+			select {
+			case <-signals:
+				select {
+				case resized <- struct{}{}:
+				case <-done:
+					return
+				}
+			case <-done:
+				return
+			}
+		}
+	}()
+	return resized, func() {
+		signal.Stop(signals)
+		close(done)
+	}
+}
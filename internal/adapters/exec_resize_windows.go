@@ -0,0 +1,38 @@
+//go:build windows
+
+package adapters
+
+import (
+	"time"
+)
+
+// resizeCheckInterval is the interval in which the terminal size is checked. Windows has no signal for resized
+// console windows
+const resizeCheckInterval = 250 * time.Millisecond
+
+// notifyResize returns a channel receiving a value in which the terminal size has to be checked and a function that
+// stops the notifications and closes the channel
+func notifyResize() (<-chan struct{}, func()) {
+	ticker := time.NewTicker(resizeCheckInterval)
+	resized := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(resized)
+		for {
+			select {
+			case <-ticker.C:
+				select {
+				case resized <- struct{}{}:
+				case <-done:
+					return
+				}
+			case <-done:
+				return
+			}
+		}
+	}()
+	return resized, func() {
+		ticker.Stop()
+		close(done)
+	}
+}
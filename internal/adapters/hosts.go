@@ -0,0 +1,87 @@
+package adapters
+
+import (
+	"net/url"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// DockerHosts configures the Docker engines instances run on. A host is either the URL of a Docker daemon (e.g.
+// ssh://user@buildvm or tcp://buildvm:2376) or the name of a Docker context. Instances without a host use the local
+// Docker engine
+type DockerHosts struct {
+	// BasePaths holds the hosts of the instances in a base path by base path
+	BasePaths map[string]string
+	// Instances holds the hosts of single instances by instance name. They override the host of the base path
+	Instances map[string]string
+	// Paths holds the base paths instances are loaded from
+	Paths []string
+}
+
+// Host returns the host of an instance identified by basePath and name or an empty string for the local engine
+func (h DockerHosts) Host(basePath string, name string) string {
+	if host, ok := h.Instances[name]; ok && host != "" {
+		return host
+	}
+	for p, host := range h.BasePaths {
+		if filepath.Clean(p) == filepath.Clean(basePath) {
+			return host
+		}
+	}
+	return ""
+}
+
+// All returns the local engine (an empty string) and all configured hosts in sorted order
+func (h DockerHosts) All() []string {
+	hosts := []string{""}
+	for _, m := range []map[string]string{h.BasePaths, h.Instances} {
+		for _, host := range m {
+			if !slices.Contains(hosts, host) {
+				hosts = append(hosts, host)
+			}
+		}
+	}
+	sort.Strings(hosts)
+	return hosts
+}
+
+// Used returns the hosts used by the base paths in Paths or by single instances in sorted order. The local engine (an
+// empty string) is only included if a base path doesn't have a host
+func (h DockerHosts) Used() []string {
+	var hosts []string
+	for _, p := range h.Paths {
+		if host := h.Host(p, ""); !slices.Contains(hosts, host) {
+			hosts = append(hosts, host)
+		}
+	}
+	for _, host := range h.Instances {
+		if host != "" && !slices.Contains(hosts, host) {
+			hosts = append(hosts, host)
+		}
+	}
+	sort.Strings(hosts)
+	return hosts
+}
+
+// isHostURL tells whether a host is the URL of a Docker daemon instead of the name of a Docker context
+func isHostURL(host string) bool {
+	return strings.Contains(host, "://")
+}
+
+// hostName returns the name of the machine ports published by the Docker daemon listening on a URL are reachable on.
+// It is empty for daemons on the local machine
+func hostName(daemonURL string) string {
+	u, err := url.Parse(daemonURL)
+	if err != nil {
+		return ""
+	}
+	switch u.Scheme {
+	case "tcp", "ssh", "http", "https":
+		if h := u.Hostname(); h != "localhost" && h != "127.0.0.1" && h != "::1" {
+			return h
+		}
+	}
+	return ""
+}
@@ -0,0 +1,44 @@
+package adapters
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestDockerHostsUsed(t *testing.T) {
+	tests := []struct {
+		name  string
+		hosts DockerHosts
+		want  []string
+	}{
+		{
+			name:  "local only",
+			hosts: DockerHosts{Paths: []string{"/a"}},
+			want:  []string{""},
+		},
+		{
+			name: "remote only",
+			hosts: DockerHosts{
+				BasePaths: map[string]string{"/a/": "ssh://vm", "/unused": "tcp://other:2376"},
+				Paths:     []string{"/a"},
+			},
+			want: []string{"ssh://vm"},
+		},
+		{
+			name: "instance hosts",
+			hosts: DockerHosts{
+				BasePaths: map[string]string{"/a": "ssh://vm"},
+				Instances: map[string]string{"x": "ctx"},
+				Paths:     []string{"/a", "/b"},
+			},
+			want: []string{"", "ctx", "ssh://vm"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := test.hosts.Used(); !slices.Equal(got, test.want) {
+				t.Errorf("Used() = %q, want %q", got, test.want)
+			}
+		})
+	}
+}
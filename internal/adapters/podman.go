@@ -0,0 +1,910 @@
+package adapters
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	composeTypes "github.com/compose-spec/compose-go/types"
+	"github.com/docker/compose/v2/pkg/api"
+	"github.com/docker/docker/pkg/stdcopy"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var _ BaseAdapter = &PodmanAdapter{}
+
+// podmanAPIBase is the base URL of the libpod REST API. The host part is ignored because all requests go
+// through the unix socket
+const podmanAPIBase = "http://podman/v4.0.0/libpod"
+
+// errPodmanNotFound is returned when the Podman API answers with 404
+var errPodmanNotFound = errors.New("no such object")
+
+// PodmanAdapter implements CCmanager with the Podman REST API
+type PodmanAdapter struct {
+	// socketPath holds the path to the unix socket of the Podman service
+	socketPath string
+	// client holds the http client connected to the Podman socket
+	client *http.Client
+}
+
+// NewPodmanAdapter creates a PodmanAdapter talking to the Podman service listening on the unix socket socketPath.
+// If socketPath is empty, DefaultPodmanSocket is used
+func NewPodmanAdapter(socketPath string) *PodmanAdapter {
+	if socketPath == "" {
+		socketPath = DefaultPodmanSocket()
+	}
+	p := &PodmanAdapter{socketPath: socketPath}
+	p.client = &http.Client{
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				return p.dial(ctx)
+			},
+		},
+	}
+	return p
+}
+
+// DefaultPodmanSocket returns the socket configured in CONTAINER_HOST or the socket of the rootless Podman service
+// if it exists. It falls back to the socket of the rootful service
+func DefaultPodmanSocket() string {
+	if h := os.Getenv("CONTAINER_HOST"); strings.HasPrefix(h, "unix://") {
+		return strings.TrimPrefix(h, "unix://")
+	}
+	if r := os.Getenv("XDG_RUNTIME_DIR"); r != "" {
+		s := filepath.Join(r, "podman", "podman.sock")
+		if _, err := os.Stat(s); err == nil {
+			return s
+		}
+	}
+	return "/run/podman/podman.sock"
+}
+
+// podmanEvent is an entry of the Podman event stream
+type podmanEvent struct {
+	Action string
+	Actor  struct {
+		Attributes map[string]string
+	}
+}
+
+// podmanContainer holds the parts of the Podman container inspect response CCmanager uses
+type podmanContainer struct {
+	State struct {
+		Running   bool
+		Status    string
+		ExitCode  int
+		Error     string
+		StartedAt time.Time
+		Health    struct {
+			Status string
+		}
+	}
+	RestartCount int
+	Config       struct {
+		Image string
+	}
+	NetworkSettings struct {
+		Ports map[string][]struct {
+			HostIp   string
+			HostPort string
+		}
+	}
+}
+
+// podmanStatsReport is a response of the Podman container stats endpoint
+type podmanStatsReport struct {
+	Error interface{}
+	Stats []podmanStats
+}
+
+// podmanStats holds the parts of the Podman container stats CCmanager uses
+type podmanStats struct {
+	CPU         float64
+	MemUsage    uint64
+	MemLimit    uint64
+	NetInput    uint64
+	NetOutput   uint64
+	BlockInput  uint64
+	BlockOutput uint64
+}
+
+// resourceUsage converts the stats into a ResourceUsage
+func (s podmanStats) resourceUsage() ResourceUsage {
+	return ResourceUsage{
+		CPUPercent:  s.CPU,
+		MemoryUsage: s.MemUsage,
+		MemoryLimit: s.MemLimit,
+		NetworkRx:   s.NetInput,
+		NetworkTx:   s.NetOutput,
+		BlockRead:   s.BlockInput,
+		BlockWrite:  s.BlockOutput,
+	}
+}
+
+// podmanContainerListEntry holds the parts of a Podman container list entry CCmanager uses
+type podmanContainerListEntry struct {
+	Id     string
+	Names  []string
+	Labels map[string]string
+}
+
+// podmanPortMapping is a port mapping in a Podman container spec
+type podmanPortMapping struct {
+	ContainerPort uint16 `json:"container_port"`
+	HostPort      uint16 `json:"host_port,omitempty"`
+	HostIP        string `json:"host_ip,omitempty"`
+	Protocol      string `json:"protocol,omitempty"`
+}
+
+// podmanNamedVolume is a named volume in a Podman container spec
+type podmanNamedVolume struct {
+	Name    string
+	Dest    string
+	Options []string `json:",omitempty"`
+}
+
+// podmanMount is a bind or tmpfs mount in a Podman container spec
+type podmanMount struct {
+	Destination string
+	Source      string `json:",omitempty"`
+	Type        string
+	Options     []string `json:",omitempty"`
+}
+
+// podmanContainerSpec is the subset of the Podman SpecGenerator used to create compose service containers
+type podmanContainerSpec struct {
+	Name         string              `json:"name"`
+	Image        string              `json:"image"`
+	Hostname     string              `json:"hostname,omitempty"`
+	Env          map[string]string   `json:"env,omitempty"`
+	Labels       map[string]string   `json:"labels,omitempty"`
+	Command      []string            `json:"command,omitempty"`
+	Entrypoint   []string            `json:"entrypoint,omitempty"`
+	PortMappings []podmanPortMapping `json:"portmappings,omitempty"`
+	Volumes      []podmanNamedVolume `json:"volumes,omitempty"`
+	Mounts       []podmanMount       `json:"mounts,omitempty"`
+	// Networks holds the networks the container is attached to by network name
+	Networks map[string]podmanNetworkOptions `json:"Networks,omitempty"`
+}
+
+// podmanNetworkOptions holds the options of a container attached to a network
+type podmanNetworkOptions struct {
+	Aliases []string `json:"aliases,omitempty"`
+}
+
+// podmanNetwork is the subset of the Podman network create request used to create compose networks
+type podmanNetwork struct {
+	Name     string            `json:"name"`
+	Driver   string            `json:"driver,omitempty"`
+	Internal bool              `json:"internal,omitempty"`
+	Labels   map[string]string `json:"labels,omitempty"`
+}
+
+func (p *PodmanAdapter) GetContainerStatus(ctx context.Context, basePath string, name string) (CloudControlStatus, error) {
+	containerName := cliContainerName(name)
+	var i podmanContainer
+	if err := p.call(ctx, http.MethodGet, fmt.Sprintf("/containers/%s/json", containerName), nil, nil, &i); err != nil {
+		if errors.Is(err, errPodmanNotFound) {
+			return getContainerStatusFromCompose(basePath, name)
+		}
+		return CloudControlStatus{Error: err}, fmt.Errorf("can not inspect container %s: %w", name, err)
+	}
+	port := "n/a"
+	cs := CCCUndef
+	var err error
+	if i.State.Running {
+		if len(i.NetworkSettings.Ports["8080/tcp"]) == 1 {
+			port = i.NetworkSettings.Ports["8080/tcp"][0].HostPort
+			cs, err = getCCCStatus(ctx, "", port, nil)
+		} else {
+			cs = CCCErr
+			err = fmt.Errorf("CCC port not found or invalid")
+		}
+	} else if i.State.ExitCode != 0 {
+		cs = CCCExited
+		err = fmt.Errorf("container status: %s (Exit Code %d) %s", i.State.Status, i.State.ExitCode, i.State.Error)
+	}
+	var portMappings []PortMap
+
+	for containerPort, bindings := range i.NetworkSettings.Ports {
+		if containerPort != "8080/tcp" && len(bindings) > 0 {
+			portMappings = append(portMappings, PortMap{
+				ContainerPort: strings.Split(containerPort, "/")[0],
+				HostPort:      bindings[0].HostPort,
+			})
+		}
+	}
+	image, tag := splitImage(i.Config.Image)
+	return CloudControlStatus{
+		Error:        err,
+		Running:      i.State.Running,
+		Image:        image,
+		Tag:          tag,
+		CCCPort:      port,
+		CCCStatus:    cs,
+		PortMappings: portMappings,
+		Health:       i.State.Health.Status,
+		StartedAt:    i.State.StartedAt,
+		RestartCount: i.RestartCount,
+	}, nil
+}
+
+func (p *PodmanAdapter) RunCloudControl(ctx context.Context, basePath string, name string, consoleWidth uint, consoleHeight uint) (*ContainerExec, error) {
+	return p.Exec(ctx, basePath, name, ExecOptions{
+		Service:       "cli",
+		Command:       CloudControlCommand,
+		ConsoleWidth:  consoleWidth,
+		ConsoleHeight: consoleHeight,
+	})
+}
+
+func (p *PodmanAdapter) Exec(ctx context.Context, _ string, name string, options ExecOptions) (*ContainerExec, error) {
+	containerName := serviceContainerName(name, options.Service)
+	consoleWidth, consoleHeight := options.ConsoleWidth, options.ConsoleHeight
+	return newContainerExec(func(stdin io.Reader, stdout io.Writer, resized <-chan ttySize) error {
+		var execCreated struct {
+			Id string
+		}
+		if err := p.call(ctx, http.MethodPost, fmt.Sprintf("/containers/%s/exec", containerName), nil, map[string]interface{}{
+			"AttachStdin":  true,
+			"AttachStdout": true,
+			"AttachStderr": true,
+			"Tty":          true,
+			"Cmd":          execCommand(options),
+		}, &execCreated); err != nil {
+			return fmt.Errorf("can not create exec in container %s: %w", containerName, err)
+		}
+
+		conn, err := p.hijack(ctx, fmt.Sprintf("/exec/%s/start", execCreated.Id), map[string]interface{}{
+			"Detach": false,
+			"Tty":    true,
+			"h":      consoleHeight,
+			"w":      consoleWidth,
+		})
+		if err != nil {
+			return fmt.Errorf("can not attach to exec in container %s: %w", containerName, err)
+		}
+		defer conn.Close()
+
+		return bridgeExec(execBridge{
+			containerName: containerName,
+			command:       options.Command,
+			recordingFile: options.RecordingFile,
+			recordInput:   options.RecordInput,
+			resized:       resized,
+			conn:          conn,
+			stdin:         stdin,
+			stdout:        stdout,
+			width:         consoleWidth,
+			height:        consoleHeight,
+			resize: func(width uint, height uint) error {
+				q := url.Values{}
+				q.Set("w", strconv.FormatUint(uint64(width), 10))
+				q.Set("h", strconv.FormatUint(uint64(height), 10))
+				return p.call(ctx, http.MethodPost, fmt.Sprintf("/exec/%s/resize", execCreated.Id), q, nil, nil)
+			},
+			inspect: func() (bool, int, error) {
+				var execInspect struct {
+					Running  bool
+					ExitCode int
+				}
+				if err := p.call(ctx, http.MethodGet, fmt.Sprintf("/exec/%s/json", execCreated.Id), nil, nil, &execInspect); err != nil {
+					return false, 0, err
+				}
+				return execInspect.Running, execInspect.ExitCode, nil
+			},
+		})
+	}), nil
+}
+
+func (p *PodmanAdapter) StartCloudControl(ctx context.Context, basePath string, name string) error {
+	var project *composeTypes.Project
+	if pr, err := getProject(basePath, name); err != nil {
+		return err
+	} else {
+		project = pr
+	}
+
+	if err := p.createNetworks(ctx, project); err != nil {
+		return err
+	}
+	for _, service := range project.Services {
+		containerName := p.containerName(project, service)
+		if err := p.call(ctx, http.MethodGet, fmt.Sprintf("/containers/%s/exists", containerName), nil, nil, nil); err != nil {
+			if !errors.Is(err, errPodmanNotFound) {
+				return fmt.Errorf("can not check container %s: %w", containerName, err)
+			}
+			if err := p.pull(ctx, service.Image); err != nil {
+				return fmt.Errorf("can not pull image %s: %w", service.Image, err)
+			}
+			if err := p.call(ctx, http.MethodPost, "/containers/create", nil, p.containerSpec(project, service), nil); err != nil {
+				return fmt.Errorf("can not create container %s: %w", containerName, err)
+			}
+		}
+		if err := p.call(ctx, http.MethodPost, fmt.Sprintf("/containers/%s/start", containerName), nil, nil, nil); err != nil {
+			return fmt.Errorf("can not start container %s: %w", containerName, err)
+		}
+	}
+	return nil
+}
+
+func (p *PodmanAdapter) StopCloudControl(ctx context.Context, basePath string, name string, remove bool) error {
+	var project *composeTypes.Project
+	if pr, err := getProject(basePath, name); err != nil {
+		return err
+	} else {
+		project = pr
+	}
+
+	containers, err := p.projectContainers(ctx, project.Name)
+	if err != nil {
+		return err
+	}
+	for _, c := range containers {
+		if err := p.call(ctx, http.MethodPost, fmt.Sprintf("/containers/%s/stop", c.Id), nil, nil, nil); err != nil && !errors.Is(err, errPodmanNotFound) {
+			return fmt.Errorf("can not stop container %s: %w", strings.Join(c.Names, ","), err)
+		}
+		if remove {
+			q := url.Values{}
+			q.Set("force", "true")
+			q.Set("v", "true")
+			if err := p.call(ctx, http.MethodDelete, fmt.Sprintf("/containers/%s", c.Id), q, nil, nil); err != nil && !errors.Is(err, errPodmanNotFound) {
+				return fmt.Errorf("can not remove container %s: %w", strings.Join(c.Names, ","), err)
+			}
+		}
+	}
+	if remove {
+		for key, volume := range project.Volumes {
+			if volume.External.External {
+				continue
+			}
+			if err := p.call(ctx, http.MethodDelete, fmt.Sprintf("/volumes/%s", volumeName(project, key)), nil, nil, nil); err != nil && !errors.Is(err, errPodmanNotFound) {
+				return fmt.Errorf("can not remove volume %s: %w", key, err)
+			}
+		}
+		for key, network := range project.Networks {
+			if network.External.External {
+				continue
+			}
+			if err := p.call(ctx, http.MethodDelete, fmt.Sprintf("/networks/%s", networkName(project, key)), nil, nil, nil); err != nil && !errors.Is(err, errPodmanNotFound) {
+				return fmt.Errorf("can not remove network %s: %w", key, err)
+			}
+		}
+	}
+	return nil
+}
+
+func (p *PodmanAdapter) GetLogs(ctx context.Context, basePath string, name string) (string, error) {
+	r, err := p.StreamLogs(ctx, basePath, name, LogOptions{})
+	if err != nil {
+		return "", err
+	}
+	defer r.Close()
+	l, err := io.ReadAll(r)
+	return string(l), err
+}
+
+func (p *PodmanAdapter) StreamLogs(ctx context.Context, basePath string, name string, options LogOptions) (io.ReadCloser, error) {
+	var project *composeTypes.Project
+	if pr, err := getProject(basePath, name); err != nil {
+		return nil, err
+	} else {
+		project = pr
+	}
+
+	containers, err := p.projectContainers(ctx, project.Name)
+	if err != nil {
+		return nil, err
+	}
+	q := url.Values{}
+	q.Set("stdout", "true")
+	q.Set("stderr", "true")
+	q.Set("timestamps", "true")
+	q.Set("follow", strconv.FormatBool(options.Follow))
+	if options.Since != "" {
+		q.Set("since", podmanTimestamp(options.Since))
+	}
+	if options.Tail != "" {
+		q.Set("tail", options.Tail)
+	}
+	if len(options.Services) > 0 {
+		var filtered []podmanContainerListEntry
+		for _, c := range containers {
+			for _, service := range options.Services {
+				if c.Labels[api.ServiceLabel] == service {
+					filtered = append(filtered, c)
+				}
+			}
+		}
+		containers = filtered
+	}
+	return streamTo(ctx, func(ctx context.Context, w io.Writer) error {
+		errs := make(chan error, len(containers))
+		for _, c := range containers {
+			// Logs of multiple containers are only interleaved when following them
+			if options.Follow {
+				go p.copyLogs(ctx, c, q, w, errs)
+			} else {
+				p.copyLogs(ctx, c, q, w, errs)
+			}
+		}
+		var err error
+		for range containers {
+			if e := <-errs; e != nil && err == nil {
+				err = e
+			}
+		}
+		return err
+	}), nil
+}
+
+func (p *PodmanAdapter) GetServices(_ context.Context, basePath string, name string) ([]string, error) {
+	return getServices(basePath, name)
+}
+
+func (p *PodmanAdapter) Events(ctx context.Context) (<-chan InstanceEvent, <-chan error) {
+	// Podman only supports label filters with values, so the labels are checked by newInstanceEvent
+	filters, _ := json.Marshal(map[string][]string{
+		"type":  {"container"},
+		"event": instanceEventActions,
+	})
+	q := url.Values{}
+	q.Set("stream", "true")
+	q.Set("filters", string(filters))
+
+	instanceEvents := make(chan InstanceEvent)
+	instanceErrs := make(chan error, 1)
+	go func() {
+		defer close(instanceEvents)
+		defer close(instanceErrs)
+		err := func() error {
+			resp, err := p.do(ctx, http.MethodGet, "/events", q, nil)
+			if err != nil {
+				return err
+			}
+			defer resp.Body.Close()
+			decoder := json.NewDecoder(resp.Body)
+			for {
+				var m podmanEvent
+				if err := decoder.Decode(&m); err != nil {
+					return err
+				}
+				if e, ok := newInstanceEvent(m.Action, m.Actor.Attributes); ok {
+					select {
+					case instanceEvents <- e:
+					case <-ctx.Done():
+						return nil
+					}
+				}
+			}
+		}()
+		if ctx.Err() == nil {
+			instanceErrs <- fmt.Errorf("can not stream podman events: %w", err)
+		}
+	}()
+	return instanceEvents, instanceErrs
+}
+
+func (p *PodmanAdapter) GetResourceUsage(ctx context.Context, _ string, name string) (ResourceUsage, error) {
+	containerName := cliContainerName(name)
+	q := url.Values{}
+	q.Set("containers", containerName)
+	q.Set("stream", "false")
+	var stats podmanStatsReport
+	if err := p.call(ctx, http.MethodGet, "/containers/stats", q, nil, &stats); err != nil {
+		return ResourceUsage{}, fmt.Errorf("can not get stats of container %s: %w", containerName, err)
+	}
+	if len(stats.Stats) == 0 {
+		return ResourceUsage{}, fmt.Errorf("can not get stats of container %s: %v", containerName, stats.Error)
+	}
+	return stats.Stats[0].resourceUsage(), nil
+}
+
+func (p *PodmanAdapter) StreamResourceUsage(ctx context.Context, _ string, name string) (<-chan ResourceUsage, <-chan error) {
+	containerName := cliContainerName(name)
+	q := url.Values{}
+	q.Set("containers", containerName)
+	q.Set("stream", "true")
+	q.Set("interval", "1")
+	usages := make(chan ResourceUsage)
+	errs := make(chan error, 1)
+	go func() {
+		defer close(usages)
+		defer close(errs)
+		err := func() error {
+			resp, err := p.do(ctx, http.MethodGet, "/containers/stats", q, nil)
+			if err != nil {
+				return err
+			}
+			defer resp.Body.Close()
+			decoder := json.NewDecoder(resp.Body)
+			for {
+				var stats podmanStatsReport
+				if err := decoder.Decode(&stats); err != nil {
+					return err
+				}
+				if len(stats.Stats) == 0 {
+					return fmt.Errorf("%v", stats.Error)
+				}
+				select {
+				case usages <- stats.Stats[0].resourceUsage():
+				case <-ctx.Done():
+					return nil
+				}
+			}
+		}()
+		if ctx.Err() == nil {
+			errs <- fmt.Errorf("can not stream stats of container %s: %w", containerName, err)
+		}
+	}()
+	return usages, errs
+}
+
+func (p *PodmanAdapter) ForwardPorts(_ context.Context, _ string, _ string, ports []string) ([]PortForward, error) {
+	return directForwards("", ports), nil
+}
+
+func (p *PodmanAdapter) GetVolumeSizes(ctx context.Context, basePath string, name string) ([]VolumeSize, error) {
+	volumes, err := getVolumeNames(basePath, name)
+	if err != nil {
+		return nil, err
+	}
+	var df struct {
+		Volumes []struct {
+			VolumeName string
+			Size       int64
+		}
+	}
+	if err := p.call(ctx, http.MethodGet, "/system/df", nil, nil, &df); err != nil {
+		return nil, fmt.Errorf("can not get disk usage: %w", err)
+	}
+	sizes := map[string]int64{}
+	for _, v := range df.Volumes {
+		sizes[v.VolumeName] = v.Size
+	}
+	return newVolumeSizes(volumes, sizes), nil
+}
+
+// copyLogs copies the log of a container to w and sends the result to errs
+func (p *PodmanAdapter) copyLogs(ctx context.Context, c podmanContainerListEntry, query url.Values, w io.Writer, errs chan<- error) {
+	resp, err := p.do(ctx, http.MethodGet, fmt.Sprintf("/containers/%s/logs", c.Id), query, nil)
+	if err != nil {
+		errs <- err
+		return
+	}
+	defer resp.Body.Close()
+	if err := demuxLogs(resp.Body, w); err != nil && ctx.Err() == nil {
+		errs <- fmt.Errorf("can not read log of container %s: %w", strings.Join(c.Names, ","), err)
+		return
+	}
+	errs <- nil
+}
+
+// containerName returns the name of the container for a compose service
+func (p *PodmanAdapter) containerName(project *composeTypes.Project, service composeTypes.ServiceConfig) string {
+	if service.ContainerName != "" {
+		return service.ContainerName
+	}
+	return serviceContainerName(project.Name, service.Name)
+}
+
+// containerSpec converts a compose service into a Podman container spec
+func (p *PodmanAdapter) containerSpec(project *composeTypes.Project, service composeTypes.ServiceConfig) podmanContainerSpec {
+	spec := podmanContainerSpec{
+		Name:       p.containerName(project, service),
+		Image:      service.Image,
+		Hostname:   service.Hostname,
+		Env:        map[string]string{},
+		Labels:     map[string]string{},
+		Command:    service.Command,
+		Entrypoint: service.Entrypoint,
+	}
+	for k, v := range service.Environment {
+		if v != nil {
+			spec.Env[k] = *v
+		}
+	}
+	for k, v := range service.Labels {
+		spec.Labels[k] = v
+	}
+	for k, v := range service.CustomLabels {
+		spec.Labels[k] = v
+	}
+	for _, port := range service.Ports {
+		mapping := podmanPortMapping{
+			ContainerPort: uint16(port.Target),
+			HostIP:        port.HostIP,
+			Protocol:      port.Protocol,
+		}
+		if hostPort, err := strconv.ParseUint(port.Published, 10, 16); err == nil {
+			mapping.HostPort = uint16(hostPort)
+		}
+		spec.PortMappings = append(spec.PortMappings, mapping)
+	}
+	for key, network := range service.Networks {
+		if spec.Networks == nil {
+			spec.Networks = map[string]podmanNetworkOptions{}
+		}
+		// Like with docker compose, the services of an instance reach each other by their service name
+		options := podmanNetworkOptions{Aliases: []string{service.Name}}
+		if network != nil {
+			options.Aliases = append(options.Aliases, network.Aliases...)
+		}
+		spec.Networks[networkName(project, key)] = options
+	}
+	for _, volume := range service.Volumes {
+		var options []string
+		if volume.ReadOnly {
+			options = append(options, "ro")
+		}
+		switch volume.Type {
+		case composeTypes.VolumeTypeVolume:
+			spec.Volumes = append(spec.Volumes, podmanNamedVolume{
+				Name:    volumeName(project, volume.Source),
+				Dest:    volume.Target,
+				Options: options,
+			})
+		case composeTypes.VolumeTypeBind:
+			spec.Mounts = append(spec.Mounts, podmanMount{
+				Destination: volume.Target,
+				Source:      volume.Source,
+				Type:        "bind",
+				Options:     append(options, "rbind"),
+			})
+		case composeTypes.VolumeTypeTmpfs:
+			spec.Mounts = append(spec.Mounts, podmanMount{
+				Destination: volume.Target,
+				Type:        "tmpfs",
+			})
+		}
+	}
+	return spec
+}
+
+// createNetworks creates the networks of a compose project that don't exist yet
+func (p *PodmanAdapter) createNetworks(ctx context.Context, project *composeTypes.Project) error {
+	for key, network := range project.Networks {
+		name := networkName(project, key)
+		err := p.call(ctx, http.MethodGet, fmt.Sprintf("/networks/%s/exists", name), nil, nil, nil)
+		if err == nil {
+			continue
+		}
+		if !errors.Is(err, errPodmanNotFound) {
+			return fmt.Errorf("can not check network %s: %w", name, err)
+		}
+		if network.External.External {
+			return fmt.Errorf("external network %s not found", name)
+		}
+		labels := map[string]string{
+			api.ProjectLabel: project.Name,
+			api.NetworkLabel: key,
+		}
+		for k, v := range network.Labels {
+			labels[k] = v
+		}
+		if err := p.call(ctx, http.MethodPost, "/networks/create", nil, podmanNetwork{
+			Name:     name,
+			Driver:   network.Driver,
+			Internal: network.Internal,
+			Labels:   labels,
+		}, nil); err != nil {
+			return fmt.Errorf("can not create network %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// projectContainers lists all containers belonging to the compose project with the given name
+func (p *PodmanAdapter) projectContainers(ctx context.Context, projectName string) ([]podmanContainerListEntry, error) {
+	filters, _ := json.Marshal(map[string][]string{
+		"label": {fmt.Sprintf("%s=%s", api.ProjectLabel, projectName)},
+	})
+	q := url.Values{}
+	q.Set("all", "true")
+	q.Set("filters", string(filters))
+	var containers []podmanContainerListEntry
+	if err := p.call(ctx, http.MethodGet, "/containers/json", q, nil, &containers); err != nil {
+		return nil, fmt.Errorf("can not list containers of %s: %w", projectName, err)
+	}
+	return containers, nil
+}
+
+// pull pulls the given image and waits until the pull has finished
+func (p *PodmanAdapter) pull(ctx context.Context, image string) error {
+	q := url.Values{}
+	q.Set("reference", image)
+	q.Set("policy", "always")
+	resp, err := p.do(ctx, http.MethodPost, "/images/pull", q, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	decoder := json.NewDecoder(resp.Body)
+	for {
+		var report struct {
+			Error string `json:"error"`
+		}
+		if err := decoder.Decode(&report); err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return err
+		}
+		if report.Error != "" {
+			return errors.New(report.Error)
+		}
+	}
+}
+
+// call runs a request against the Podman API and decodes the JSON response into result if it is not nil
+func (p *PodmanAdapter) call(ctx context.Context, method string, path string, query url.Values, body interface{}, result interface{}) error {
+	resp, err := p.do(ctx, method, path, query, body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if result == nil {
+		_, _ = io.Copy(io.Discard, resp.Body)
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(result)
+}
+
+// do sends a request to the Podman API and converts error responses into errors
+func (p *PodmanAdapter) do(ctx context.Context, method string, path string, query url.Values, body interface{}) (*http.Response, error) {
+	req, err := p.newRequest(ctx, method, path, query, body)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("can not connect to Podman API at %s: %w", p.socketPath, err)
+	}
+	if resp.StatusCode >= 400 {
+		defer resp.Body.Close()
+		return nil, podmanResponseError(resp)
+	}
+	return resp, nil
+}
+
+// newRequest builds a request to the Podman API
+func (p *PodmanAdapter) newRequest(ctx context.Context, method string, path string, query url.Values, body interface{}) (*http.Request, error) {
+	var reader io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		reader = bytes.NewReader(b)
+	}
+	u := podmanAPIBase + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u, reader)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	return req, nil
+}
+
+// hijack sends a request that upgrades the connection to a raw stream as used by exec sessions
+func (p *PodmanAdapter) hijack(ctx context.Context, path string, body interface{}) (net.Conn, error) {
+	req, err := p.newRequest(ctx, http.MethodPost, path, nil, body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Upgrade", "tcp")
+
+	conn, err := p.dial(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("can not connect to Podman API at %s: %w", p.socketPath, err)
+	}
+	if err := req.Write(conn); err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+	br := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(br, req)
+	if err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+	if resp.StatusCode >= 400 {
+		defer conn.Close()
+		return nil, podmanResponseError(resp)
+	}
+	return &bufferedConn{Conn: conn, reader: br}, nil
+}
+
+// dial connects to the Podman socket
+func (p *PodmanAdapter) dial(ctx context.Context) (net.Conn, error) {
+	var d net.Dialer
+	return d.DialContext(ctx, "unix", p.socketPath)
+}
+
+// podmanResponseError converts an error response of the Podman API into an error
+func podmanResponseError(resp *http.Response) error {
+	var e struct {
+		Message string `json:"message"`
+	}
+	_ = json.NewDecoder(resp.Body).Decode(&e)
+	if resp.StatusCode == http.StatusNotFound {
+		return fmt.Errorf("%w: %s", errPodmanNotFound, e.Message)
+	}
+	if e.Message == "" {
+		e.Message = resp.Status
+	}
+	return fmt.Errorf("podman API error: %s", e.Message)
+}
+
+// podmanTimestamp converts a relative duration (e.g. 42m) into a unix timestamp. Other values are returned as
+// they are
+func podmanTimestamp(since string) string {
+	if d, err := time.ParseDuration(since); err == nil {
+		return strconv.FormatInt(time.Now().Add(-d).Unix(), 10)
+	}
+	return since
+}
+
+// volumeName returns the engine name of a volume declared in a compose project
+func volumeName(project *composeTypes.Project, key string) string {
+	if v, ok := project.Volumes[key]; ok && v.Name != "" {
+		return v.Name
+	}
+	return fmt.Sprintf("%s_%s", project.Name, key)
+}
+
+// networkName returns the engine name of a network declared in a compose project
+func networkName(project *composeTypes.Project, key string) string {
+	if n, ok := project.Networks[key]; ok && n.Name != "" {
+		return n.Name
+	}
+	return fmt.Sprintf("%s_%s", project.Name, key)
+}
+
+// demuxLogs copies a container log stream to w. Logs of containers without a tty are multiplexed into stdout and
+// stderr frames, which are unpacked, logs of containers with a tty are copied as they are
+func demuxLogs(r io.Reader, w io.Writer) error {
+	br := bufio.NewReader(r)
+	if header, err := br.Peek(8); err == nil && header[0] <= byte(stdcopy.Systemerr) && header[1] == 0 && header[2] == 0 && header[3] == 0 {
+		_, err := stdcopy.StdCopy(w, w, br)
+		return err
+	}
+	_, err := io.Copy(w, br)
+	return err
+}
+
+// bufferedConn is a net.Conn that reads from a buffered reader first. It is used when data of a hijacked
+// connection was already buffered while reading the response header
+type bufferedConn struct {
+	net.Conn
+	reader *bufio.Reader
+}
+
+func (b *bufferedConn) Read(p []byte) (int, error) {
+	return b.reader.Read(p)
+}
+
+// CloseWrite closes the writing side of the connection if the underlying connection supports it
+func (b *bufferedConn) CloseWrite() error {
+	if c, ok := b.Conn.(interface{ CloseWrite() error }); ok {
+		return c.CloseWrite()
+	}
+	return nil
+}
@@ -0,0 +1,340 @@
+package adapters
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/docker/docker/pkg/stdcopy"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// testComposeFile is the compose file of the instances used in the tests
+const testComposeFile = `services:
+  cli:
+    image: ghcr.io/dodevops/cloudcontrol-azure:latest
+    ports:
+      - "8080:8080"
+    volumes:
+      - home:/home/cloudcontrol
+  db:
+    image: postgres:16
+volumes:
+  home:
+`
+
+// fakePodman is a Podman API stand-in listening on a unix socket
+type fakePodman struct {
+	lock sync.Mutex
+	// requests holds the method and path of all requests
+	requests []string
+	// bodies holds the bodies of all requests by method and path
+	bodies map[string][]byte
+	// handlers answers requests by method and path. Unknown requests are answered with 200 and an empty body
+	handlers map[string]http.HandlerFunc
+}
+
+// newFakePodman starts a fake Podman API and returns it with an adapter connected to it
+func newFakePodman(t *testing.T) (*fakePodman, *PodmanAdapter) {
+	t.Helper()
+	f := &fakePodman{bodies: map[string][]byte{}, handlers: map[string]http.HandlerFunc{}}
+	socket := filepath.Join(t.TempDir(), "podman.sock")
+	l, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewUnstartedServer(f)
+	server.Listener = l
+	server.Start()
+	t.Cleanup(server.Close)
+	return f, NewPodmanAdapter(socket)
+}
+
+func (f *fakePodman) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	key := fmt.Sprintf("%s %s", r.Method, strings.TrimPrefix(r.URL.Path, "/v4.0.0/libpod"))
+	body, _ := io.ReadAll(r.Body)
+	f.lock.Lock()
+	f.requests = append(f.requests, key)
+	f.bodies[key] = body
+	handler := f.handlers[key]
+	f.lock.Unlock()
+	if handler != nil {
+		handler(w, r)
+	}
+}
+
+// handle registers a handler for a method and path
+func (f *fakePodman) handle(key string, handler http.HandlerFunc) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.handlers[key] = handler
+}
+
+// calls returns the requests received so far
+func (f *fakePodman) calls() []string {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	return append([]string{}, f.requests...)
+}
+
+// reply returns a handler answering with a status code and a JSON body
+func reply(status int, body interface{}) http.HandlerFunc {
+	return func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		_ = json.NewEncoder(w).Encode(body)
+	}
+}
+
+// notFound answers like Podman does for missing objects
+var notFound = reply(http.StatusNotFound, map[string]string{"message": "no such object"})
+
+// newTestInstance creates an instance folder with testComposeFile and returns its base path
+func newTestInstance(t *testing.T, name string) string {
+	t.Helper()
+	return writeTestInstance(t, name, testComposeFile)
+}
+
+// writeTestInstance creates an instance folder with a compose file and returns its base path
+func writeTestInstance(t *testing.T, name string, composeFile string) string {
+	t.Helper()
+	basePath := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(basePath, name), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(basePath, name, "docker-compose.yml"), []byte(composeFile), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return basePath
+}
+
+// indexOf returns the index of a request or -1 if it hasn't been received
+func indexOf(requests []string, request string) int {
+	for i, r := range requests {
+		if r == request {
+			return i
+		}
+	}
+	return -1
+}
+
+func TestPodmanContainerStatus(t *testing.T) {
+	ccc := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/status" {
+			t.Errorf("unexpected CCC request %s", r.URL.Path)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"Status":"INITIALIZED"}`))
+	}))
+	defer ccc.Close()
+	_, cccPort, _ := net.SplitHostPort(ccc.Listener.Addr().String())
+
+	f, p := newFakePodman(t)
+	f.handle("GET /containers/test-cli-1/json", reply(http.StatusOK, map[string]interface{}{
+		"State": map[string]interface{}{
+			"Running":   true,
+			"Status":    "running",
+			"StartedAt": "2024-01-02T03:04:05Z",
+			"Health":    map[string]string{"Status": "healthy"},
+		},
+		"RestartCount": 2,
+		"Config":       map[string]string{"Image": "ghcr.io/dodevops/cloudcontrol-azure:1.2.3"},
+		"NetworkSettings": map[string]interface{}{
+			"Ports": map[string]interface{}{
+				"8080/tcp": []map[string]string{{"HostPort": cccPort}},
+				"3000/tcp": []map[string]string{{"HostPort": "13000"}},
+			},
+		},
+	}))
+
+	s, err := p.GetContainerStatus(context.Background(), newTestInstance(t, "test"), "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !s.Running || s.CCCStatus != CCCReady || s.CCCPort != cccPort {
+		t.Errorf("unexpected state running=%v ccc=%v port=%s: %v", s.Running, s.CCCStatus, s.CCCPort, s.Error)
+	}
+	if s.Image != "ghcr.io/dodevops/cloudcontrol-azure" || s.Tag != "1.2.3" {
+		t.Errorf("unexpected image %s:%s", s.Image, s.Tag)
+	}
+	if s.Health != "healthy" || s.RestartCount != 2 || s.StartedAt.IsZero() {
+		t.Errorf("unexpected health %s, restarts %d, start %s", s.Health, s.RestartCount, s.StartedAt)
+	}
+	if len(s.PortMappings) != 1 || s.PortMappings[0] != (PortMap{ContainerPort: "3000", HostPort: "13000"}) {
+		t.Errorf("unexpected port mappings %v", s.PortMappings)
+	}
+}
+
+func TestPodmanContainerStatusWithoutContainer(t *testing.T) {
+	f, p := newFakePodman(t)
+	f.handle("GET /containers/test-cli-1/json", notFound)
+
+	s, err := p.GetContainerStatus(context.Background(), newTestInstance(t, "test"), "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Running || s.CCCStatus != CCCDown || s.Tag != "latest" {
+		t.Errorf("unexpected state running=%v ccc=%v tag=%s", s.Running, s.CCCStatus, s.Tag)
+	}
+}
+
+func TestPodmanStart(t *testing.T) {
+	f, p := newFakePodman(t)
+	f.handle("GET /networks/test_default/exists", notFound)
+	f.handle("GET /containers/test-cli-1/exists", notFound)
+	f.handle("GET /containers/test-db-1/exists", reply(http.StatusNoContent, nil))
+
+	if err := p.StartCloudControl(context.Background(), newTestInstance(t, "test"), "test"); err != nil {
+		t.Fatal(err)
+	}
+	requests := f.calls()
+	createNetwork := indexOf(requests, "POST /networks/create")
+	createCli := indexOf(requests, "POST /containers/create")
+	if createNetwork < 0 || createCli < 0 || createNetwork > createCli {
+		t.Fatalf("network must be created before the containers: %v", requests)
+	}
+	if indexOf(requests, "POST /images/pull") < 0 {
+		t.Errorf("image of new container not pulled: %v", requests)
+	}
+	for _, start := range []string{"POST /containers/test-cli-1/start", "POST /containers/test-db-1/start"} {
+		if indexOf(requests, start) < 0 {
+			t.Errorf("missing %s: %v", start, requests)
+		}
+	}
+
+	var network podmanNetwork
+	if err := json.Unmarshal(f.bodies["POST /networks/create"], &network); err != nil {
+		t.Fatal(err)
+	}
+	if network.Name != "test_default" || network.Labels["com.docker.compose.project"] != "test" {
+		t.Errorf("unexpected network %+v", network)
+	}
+	var spec podmanContainerSpec
+	if err := json.Unmarshal(f.bodies["POST /containers/create"], &spec); err != nil {
+		t.Fatal(err)
+	}
+	if spec.Name != "test-cli-1" || len(spec.PortMappings) != 1 || spec.PortMappings[0].HostPort != 8080 {
+		t.Errorf("unexpected container spec %+v", spec)
+	}
+	if aliases := spec.Networks["test_default"].Aliases; len(aliases) == 0 || aliases[0] != "cli" {
+		t.Errorf("container not attached to the network by its service name: %+v", spec.Networks)
+	}
+	if len(spec.Volumes) != 1 || spec.Volumes[0].Name != "test_home" {
+		t.Errorf("unexpected volumes %+v", spec.Volumes)
+	}
+}
+
+func TestPodmanStop(t *testing.T) {
+	f, p := newFakePodman(t)
+	f.handle("GET /containers/json", reply(http.StatusOK, []podmanContainerListEntry{
+		{Id: "abc", Names: []string{"test-cli-1"}},
+		{Id: "def", Names: []string{"test-db-1"}},
+	}))
+	f.handle("POST /containers/def/stop", notFound)
+
+	if err := p.StopCloudControl(context.Background(), newTestInstance(t, "test"), "test", true); err != nil {
+		t.Fatal(err)
+	}
+	requests := f.calls()
+	for _, r := range []string{
+		"POST /containers/abc/stop",
+		"DELETE /containers/abc",
+		"DELETE /containers/def",
+		"DELETE /volumes/test_home",
+		"DELETE /networks/test_default",
+	} {
+		if indexOf(requests, r) < 0 {
+			t.Errorf("missing %s: %v", r, requests)
+		}
+	}
+}
+
+func TestPodmanLogs(t *testing.T) {
+	f, p := newFakePodman(t)
+	f.handle("GET /containers/json", reply(http.StatusOK, []podmanContainerListEntry{
+		{Id: "abc", Names: []string{"test-cli-1"}},
+	}))
+	f.handle("GET /containers/abc/logs", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("tail") != "10" {
+			t.Errorf("tail not passed on: %s", r.URL.RawQuery)
+		}
+		_, _ = stdcopy.NewStdWriter(w, stdcopy.Stdout).Write([]byte("out line\n"))
+		_, _ = stdcopy.NewStdWriter(w, stdcopy.Stderr).Write([]byte("err line\n"))
+	})
+
+	r, err := p.StreamLogs(context.Background(), newTestInstance(t, "test"), "test", LogOptions{Tail: "10"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	l, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(l) != "out line\nerr line\n" {
+		t.Errorf("unexpected log %q", l)
+	}
+}
+
+func TestPodmanExec(t *testing.T) {
+	f, p := newFakePodman(t)
+	f.handle("POST /containers/test-cli-1/exec", reply(http.StatusCreated, map[string]string{"Id": "exec1"}))
+	f.handle("POST /exec/exec1/start", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Upgrade") != "tcp" {
+			t.Errorf("exec start is no upgrade request")
+		}
+		conn, buf, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		defer conn.Close()
+		_, _ = buf.WriteString("HTTP/1.1 101 UPGRADED\r\nConnection: Upgrade\r\nUpgrade: tcp\r\n\r\n")
+		_ = buf.Flush()
+		// Echo the input until it ends
+		input, _ := io.ReadAll(buf)
+		_, _ = conn.Write(append([]byte("echo: "), input...))
+	})
+	f.handle("GET /exec/exec1/json", reply(http.StatusOK, map[string]interface{}{"Running": false, "ExitCode": 0}))
+
+	c, err := p.Exec(context.Background(), "", "test", ExecOptions{
+		Service:       "cli",
+		Command:       []string{"cat"},
+		ConsoleWidth:  80,
+		ConsoleHeight: 24,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var output bytes.Buffer
+	c.SetStdin(strings.NewReader("hello"))
+	c.SetStdout(&output)
+	if err := c.Run(); err != nil {
+		t.Fatal(err)
+	}
+	if output.String() != "echo: hello" {
+		t.Errorf("unexpected output %q", output.String())
+	}
+
+	var exec struct {
+		Cmd []string
+		Tty bool
+	}
+	if err := json.Unmarshal(f.bodies["POST /containers/test-cli-1/exec"], &exec); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(exec.Cmd, " ") != "cat" || !exec.Tty {
+		t.Errorf("unexpected exec %+v", exec)
+	}
+	if indexOf(f.calls(), "POST /exec/exec1/resize") < 0 {
+		t.Errorf("tty not resized: %v", f.calls())
+	}
+}
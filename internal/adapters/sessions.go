@@ -0,0 +1,114 @@
+package adapters
+
+// Detachable sessions run their command in a tmux session inside the cli container. The command keeps running when
+// the exec session attached to it ends, e.g. because CCmanager has been closed, and can be attached to again
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"math/rand"
+	"slices"
+	"strings"
+	"time"
+)
+
+// sessionPrefix is prepended to the names of the tmux sessions created by CCmanager
+const sessionPrefix = "ccmanager-"
+
+// The names of the detachable sessions running CloudControl and the shell
+const (
+	CloudControlSession = "cloudcontrol"
+	ShellSession        = "shell"
+)
+
+// detachableScript runs a command in a new tmux session. It is called with the session name, the quoted command and
+// "attach" as arguments. Without a command, it only attaches to the session. With "attach", it attaches to the session
+// if it is already running instead of failing
+const detachableScript = `if ! command -v tmux >/dev/null; then echo "Detachable sessions need tmux in the container" >&2; exit 127; fi
+if [ -z "$2" ]; then exec tmux attach-session -t "$1"; fi
+if [ "$3" = "attach" ]; then exec tmux new-session -A -s "$1" "$2"; fi
+exec tmux new-session -s "$1" "$2"`
+
+// sharedSessions holds the detachable sessions that are attached to if they are already running
+var sharedSessions = []string{CloudControlSession, ShellSession}
+
+// DetachableSession returns the name of the detachable session running a command. CloudControl and the shell have a
+// fixed session per instance, other commands get a new session with a unique name each
+func DetachableSession(command []string) string {
+	switch strings.Join(command, " ") {
+	case strings.Join(CloudControlCommand, " "):
+		return CloudControlSession
+	case strings.Join(ShellCommand, " "):
+		return ShellSession
+	}
+	return fmt.Sprintf("command-%s-%06x", time.Now().Format("20060102-150405"), rand.Intn(0x1000000))
+}
+
+// execCommand returns the command run by an exec session. Commands of detachable sessions are wrapped into tmux
+func execCommand(options ExecOptions) []string {
+	if options.Session == "" {
+		return options.Command
+	}
+	var quoted []string
+	for _, c := range options.Command {
+		quoted = append(quoted, "'"+strings.ReplaceAll(c, "'", `'\''`)+"'")
+	}
+	mode := "new"
+	if slices.Contains(sharedSessions, options.Session) {
+		mode = "attach"
+	}
+	return []string{
+		"/bin/sh", "-c", detachableScript, "ccmanager", sessionPrefix + options.Session, strings.Join(quoted, " "), mode,
+	}
+}
+
+// ListSessions returns the names of the detachable sessions running in the cli container of an instance
+func ListSessions(ctx context.Context, adapter BaseAdapter, basePath string, name string) ([]string, error) {
+	output, err := commandOutput(ctx, adapter, basePath, name, []string{
+		"/bin/sh", "-c", "tmux list-sessions -F '#{session_name}' 2>/dev/null || true",
+	})
+	if err != nil {
+		return nil, fmt.Errorf("can not list sessions of instance %s: %w", name, err)
+	}
+	var sessions []string
+	for _, line := range strings.Split(output, "\n") {
+		if s := strings.TrimSpace(line); strings.HasPrefix(s, sessionPrefix) {
+			sessions = append(sessions, strings.TrimPrefix(s, sessionPrefix))
+		}
+	}
+	return sessions, nil
+}
+
+// DetachSession detaches all clients from a detachable session of an instance. Their exec sessions end, the command
+// of the session keeps running
+func DetachSession(ctx context.Context, adapter BaseAdapter, basePath string, name string, session string) error {
+	if _, err := commandOutput(ctx, adapter, basePath, name, []string{
+		"tmux", "detach-client", "-s", sessionPrefix + session,
+	}); err != nil {
+		return fmt.Errorf("can not detach from session %s of instance %s: %w", session, name, err)
+	}
+	return nil
+}
+
+// commandOutput runs a command without input in the cli container of an instance and returns its output
+func commandOutput(ctx context.Context, adapter BaseAdapter, basePath string, name string, command []string) (string, error) {
+	c, err := adapter.Exec(ctx, basePath, name, ExecOptions{
+		Service:       "cli",
+		Command:       command,
+		ConsoleWidth:  200,
+		ConsoleHeight: 24,
+	})
+	if err != nil {
+		return "", err
+	}
+	// The input stays open until the command has finished, closing it could end the command early
+	stdin, input := io.Pipe()
+	defer input.Close()
+	var output bytes.Buffer
+	c.SetStdin(stdin)
+	c.SetStdout(&output)
+	err = c.Run()
+	return strings.ReplaceAll(output.String(), "\r", ""), err
+}
@@ -0,0 +1,45 @@
+package adapters
+
+import (
+	"testing"
+)
+
+func TestDetachableSession(t *testing.T) {
+	if s := DetachableSession(CloudControlCommand); s != CloudControlSession {
+		t.Errorf("session of CloudControl is %q, want %q", s, CloudControlSession)
+	}
+	if s := DetachableSession(ShellCommand); s != ShellSession {
+		t.Errorf("session of the shell is %q, want %q", s, ShellSession)
+	}
+	seen := map[string]bool{}
+	for i := 0; i < 100; i++ {
+		s := DetachableSession([]string{"sleep", "1"})
+		if seen[s] {
+			t.Fatalf("session name %s is used twice", s)
+		}
+		seen[s] = true
+	}
+}
+
+func TestExecCommand(t *testing.T) {
+	tests := []struct {
+		session string
+		mode    string
+	}{
+		{session: CloudControlSession, mode: "attach"},
+		{session: ShellSession, mode: "attach"},
+		{session: "command-20240102-132337-00beef", mode: "new"},
+	}
+	for _, test := range tests {
+		command := execCommand(ExecOptions{Command: []string{"echo", "it's"}, Session: test.session})
+		if len(command) != 7 {
+			t.Fatalf("got command %q", command)
+		}
+		if command[4] != sessionPrefix+test.session || command[5] != `'echo' 'it'\''s'` || command[6] != test.mode {
+			t.Errorf("got arguments %q for session %s", command[4:], test.session)
+		}
+	}
+	if command := execCommand(ExecOptions{Command: []string{"echo"}}); len(command) != 1 {
+		t.Errorf("command without session is wrapped: %q", command)
+	}
+}
@@ -0,0 +1,167 @@
+package adapters
+
+// Ports published by instances on machines whose Docker daemon is reached over SSH are forwarded through SSH
+// tunnels. Each connection to a forwarded port runs ssh -W to the machine of the daemon, so the tunnels use the same
+// SSH configuration, keys and agent as Docker itself
+
+import (
+	"context"
+	"fmt"
+	"github.com/docker/cli/cli/connhelper/commandconn"
+	sshhelper "github.com/docker/cli/cli/connhelper/ssh"
+	"io"
+	"net"
+	"net/url"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// probeTimeout limits the time to wait for the SSH connection checked before ports are forwarded
+const probeTimeout = 30 * time.Second
+
+// PortForward describes how a port published by an instance can be reached from the local machine
+type PortForward struct {
+	// Port holds the port published on the machine of the instance
+	Port string
+	// URL holds the URL the port can be reached at from the local machine
+	URL string
+}
+
+// directForwards returns the URLs of ports that are reachable from the local machine without a tunnel
+func directForwards(host string, ports []string) []PortForward {
+	var forwards []PortForward
+	for _, port := range ports {
+		forwards = append(forwards, PortForward{Port: port, URL: PublishedURL(host, port)})
+	}
+	return forwards
+}
+
+// sshTunnel forwards local ports to ports published on the machine of a Docker daemon reached over SSH
+type sshTunnel struct {
+	// spec holds the SSH destination of the daemon
+	spec *sshhelper.Spec
+}
+
+// newSSHTunnel returns a tunnel to the machine of a Docker daemon or nil if the daemon isn't reached over SSH
+func newSSHTunnel(daemonURL string) (*sshTunnel, error) {
+	if u, err := url.Parse(daemonURL); err != nil || u.Scheme != "ssh" {
+		return nil, nil
+	}
+	spec, err := sshhelper.ParseURL(daemonURL)
+	if err != nil {
+		return nil, fmt.Errorf("can not parse SSH host %s: %w", daemonURL, err)
+	}
+	return &sshTunnel{spec: spec}, nil
+}
+
+// forward listens on a free local port for each of the ports and forwards the connections to the port on the remote
+// machine. It fails if the remote machine can't be reached over SSH. The listeners and their connections are closed
+// when the context is cancelled
+func (t *sshTunnel) forward(ctx context.Context, ports []string) ([]PortForward, error) {
+	if err := t.probe(ctx); err != nil {
+		return nil, err
+	}
+	var forwards []PortForward
+	var listeners []net.Listener
+	for _, port := range ports {
+		l, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			for _, l := range listeners {
+				_ = l.Close()
+			}
+			return nil, fmt.Errorf("can not forward port %s: %w", port, err)
+		}
+		listeners = append(listeners, l)
+		_, localPort, _ := net.SplitHostPort(l.Addr().String())
+		forwards = append(forwards, PortForward{Port: port, URL: PublishedURL("127.0.0.1", localPort)})
+		go t.serve(ctx, l, port)
+	}
+	go func() {
+		<-ctx.Done()
+		for _, l := range listeners {
+			_ = l.Close()
+		}
+	}()
+	return forwards, nil
+}
+
+// probe checks that the remote machine can be reached over SSH without asking for a password
+func (t *sshTunnel) probe(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, probeTimeout)
+	defer cancel()
+	args := append([]string{"-o", "BatchMode=yes"}, t.spec.Args("true")...)
+	if out, err := exec.CommandContext(ctx, "ssh", args...).CombinedOutput(); err != nil {
+		if msg := strings.TrimSpace(string(out)); msg != "" {
+			return fmt.Errorf("can not connect to %s over SSH: %w: %s", t.spec.Host, err, msg)
+		}
+		return fmt.Errorf("can not connect to %s over SSH: %w", t.spec.Host, err)
+	}
+	return nil
+}
+
+// serve forwards the connections accepted by a listener to a port on the remote machine until the listener is closed
+func (t *sshTunnel) serve(ctx context.Context, l net.Listener, port string) {
+	for {
+		local, err := l.Accept()
+		if err != nil {
+			return
+		}
+		go t.pipe(ctx, local, port)
+	}
+}
+
+// dial opens a connection to a port on the remote machine. The connection stays open until it is closed by the caller
+func (t *sshTunnel) dial(ctx context.Context, port string) (net.Conn, error) {
+	// BatchMode keeps ssh from asking for passwords on the terminal used by CCmanager
+	args := append([]string{"-o", "BatchMode=yes", "-W", net.JoinHostPort("127.0.0.1", port)}, t.spec.Args()...)
+	return commandconn.New(ctx, "ssh", args...)
+}
+
+// dialer returns a dialFunc connecting to a port on the remote machine regardless of the address it is called with.
+// It returns nil for a nil tunnel, so connections are opened directly then
+func (t *sshTunnel) dialer(port string) dialFunc {
+	if t == nil {
+		return nil
+	}
+	return func(ctx context.Context, _ string, _ string) (net.Conn, error) {
+		return t.dial(ctx, port)
+	}
+}
+
+// pipe copies data between a local connection and a port on the remote machine until both sides are done or the
+// context is cancelled. The local connection is closed right away if the remote port can't be reached
+func (t *sshTunnel) pipe(ctx context.Context, local net.Conn, port string) {
+	defer local.Close()
+	remote, err := t.dial(ctx, port)
+	if err != nil {
+		return
+	}
+	defer remote.Close()
+
+	done := make(chan struct{}, 2)
+	go func() {
+		_, _ = io.Copy(remote, local)
+		closeWrite(remote)
+		done <- struct{}{}
+	}()
+	go func() {
+		_, _ = io.Copy(local, remote)
+		closeWrite(local)
+		done <- struct{}{}
+	}()
+	for i := 0; i < 2; i++ {
+		select {
+		case <-done:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// closeWrite signals the end of the data written to a connection if it supports half-closed connections
+func closeWrite(c net.Conn) {
+	if w, ok := c.(interface{ CloseWrite() error }); ok {
+		_ = w.CloseWrite()
+	}
+}
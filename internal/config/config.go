@@ -0,0 +1,296 @@
+package config
+
+// The CCmanager configuration file
+
+import (
+	"ccmanager/internal/adapters"
+	"ccmanager/internal/templates"
+	"errors"
+	"fmt"
+	"gopkg.in/yaml.v3"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"time"
+)
+
+// InstanceConfig holds settings that override the global settings for a single instance
+type InstanceConfig struct {
+	// LogSince overrides Config.LogSince
+	LogSince string `yaml:"logSince,omitempty"`
+	// LogTail overrides Config.LogTail
+	LogTail string `yaml:"logTail,omitempty"`
+	// UpdateCheck overrides Config.UpdateCheck
+	UpdateCheck *bool `yaml:"updateCheck,omitempty"`
+	// Groups holds the names of the groups the instance belongs to
+	Groups []string `yaml:"groups,omitempty"`
+	// Host overrides Config.BasePathHosts
+	Host string `yaml:"host,omitempty"`
+}
+
+// Config holds the CCmanager configuration
+type Config struct {
+	// BasePath is a list of paths where to find CloudControl docker compose folders
+	BasePath []string `yaml:"basePath"`
+	// Separator is the separator used in docker compose container names
+	Separator string `yaml:"separator"`
+	// Engine is the container engine to use (docker or podman)
+	Engine string `yaml:"engine"`
+	// PodmanSocket is the path to the Podman API socket
+	PodmanSocket string `yaml:"podmanSocket,omitempty"`
+	// BasePathHosts holds the Docker host (e.g. ssh://user@buildvm) or the name of the Docker context the instances
+	// of a base path run on by base path
+	BasePathHosts map[string]string `yaml:"basePathHosts,omitempty"`
+	// Parallelism is the maximum number of instances processed at the same time by bulk actions
+	Parallelism int `yaml:"parallelism"`
+	// StatusParallelism is the maximum number of instances whose status is loaded at the same time
+	StatusParallelism int `yaml:"statusParallelism"`
+	// RefreshInterval is the interval in which the instances are refreshed
+	RefreshInterval time.Duration `yaml:"refreshInterval"`
+	// StatusTimeout is the timeout for fetching the status of an instance
+	StatusTimeout time.Duration `yaml:"statusTimeout"`
+	// ActionTimeout is the timeout for starting and stopping an instance
+	ActionTimeout time.Duration `yaml:"actionTimeout"`
+	// LogTimeout is the timeout for opening the log of an instance in the log screen and for fetching it headless
+	LogTimeout time.Duration `yaml:"logTimeout"`
+	// LogSince limits the log screen to log lines since a timestamp or relative duration
+	LogSince string `yaml:"logSince,omitempty"`
+	// LogTail limits the log screen to a number of lines from the end of the log of each container
+	LogTail string `yaml:"logTail,omitempty"`
+	// TemplateDir is the directory with templates overriding the embedded new instance templates
+	TemplateDir string `yaml:"templateDir"`
+	// HistoryFile is the file holding the history of commands run in instances
+	HistoryFile string `yaml:"historyFile"`
+	// RecordSessions tells whether exec sessions in instances are recorded
+	RecordSessions bool `yaml:"recordSessions"`
+	// RecordInput tells whether the input of recorded sessions is recorded as well. It is off by default because the
+	// input contains everything typed, including passwords
+	RecordInput bool `yaml:"recordInput"`
+	// RecordingDir is the directory holding the session recordings
+	RecordingDir string `yaml:"recordingDir"`
+	// EmbedSessions tells whether exec sessions are shown as terminal tabs next to the instance list instead of
+	// taking over the whole terminal
+	EmbedSessions bool `yaml:"embedSessions"`
+	// DetachableSessions tells whether CloudControl, shells and commands in the cli service run in tmux sessions that
+	// keep running when CCmanager is closed
+	DetachableSessions bool `yaml:"detachableSessions"`
+	// ShowResources tells whether the CPU and memory usage of running instances is shown in the instance list
+	ShowResources bool `yaml:"showResources"`
+	// UpdateCheck tells whether the registry is checked for newer CloudControl images
+	UpdateCheck bool `yaml:"updateCheck"`
+	// Theme is the name of a built-in theme, the name of a theme file in the themes directory or the path to a
+	// theme file
+	Theme string `yaml:"theme"`
+	// KeyBindings maps the names of actions to the keys triggering them
+	KeyBindings map[string][]string `yaml:"keyBindings,omitempty"`
+	// Favourites holds the names of instances shown at the top of the instance list
+	Favourites []string `yaml:"favourites,omitempty"`
+	// Groups holds the names of the instances of a group by group name
+	Groups map[string][]string `yaml:"groups,omitempty"`
+	// Instances holds settings for single instances by instance name
+	Instances map[string]InstanceConfig `yaml:"instances,omitempty"`
+}
+
+// Default returns the configuration used if no configuration file exists
+func Default() Config {
+	return Config{
+		Separator:         "-",
+		Engine:            "docker",
+		Parallelism:       4,
+		StatusParallelism: 8,
+		RefreshInterval:   5 * time.Second,
+		StatusTimeout:     10 * time.Second,
+		ActionTimeout:     10 * time.Minute,
+		LogTimeout:        30 * time.Second,
+		TemplateDir:       templates.DefaultTemplateDir(),
+		HistoryFile:       DefaultHistoryPath(),
+		RecordingDir:      DefaultRecordingDir(),
+		EmbedSessions:     true,
+		UpdateCheck:       true,
+		Theme:             "auto",
+	}
+}
+
+// DefaultPath returns the path of the configuration file in the user configuration directory
+// (e.g. ~/.config/ccmanager/config.yaml)
+func DefaultPath() string {
+	if d, err := os.UserConfigDir(); err == nil {
+		return filepath.Join(d, "ccmanager", "config.yaml")
+	}
+	return ""
+}
+
+// DefaultHistoryPath returns the path of the command history in the user configuration directory
+// (e.g. ~/.config/ccmanager/history)
+func DefaultHistoryPath() string {
+	if d, err := os.UserConfigDir(); err == nil {
+		return filepath.Join(d, "ccmanager", "history")
+	}
+	return ""
+}
+
+// DefaultRecordingDir returns the directory of the session recordings in the user configuration directory
+// (e.g. ~/.config/ccmanager/recordings)
+func DefaultRecordingDir() string {
+	if d, err := os.UserConfigDir(); err == nil {
+		return filepath.Join(d, "ccmanager", "recordings")
+	}
+	return ""
+}
+
+// Load reads the configuration file at path over the default configuration. A missing file is only an error if
+// required is set
+func Load(path string, required bool) (Config, error) {
+	c := Default()
+	if path == "" {
+		return c, nil
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) && !required {
+			return c, nil
+		}
+		return c, fmt.Errorf("can not read configuration file: %w", err)
+	}
+	var document yaml.Node
+	if err := yaml.Unmarshal(content, &document); err != nil {
+		return c, fmt.Errorf("can not parse configuration file %s: %w", path, err)
+	}
+	if err := checkDurations(&document); err != nil {
+		return c, fmt.Errorf("invalid configuration file %s: %w", path, err)
+	}
+	if err := document.Decode(&c); err != nil {
+		return c, fmt.Errorf("can not parse configuration file %s: %w", path, err)
+	}
+	if err := c.Validate(); err != nil {
+		return c, fmt.Errorf("invalid configuration file %s: %w", path, err)
+	}
+	return c, nil
+}
+
+// namedDuration is a duration of the configuration and its key in the configuration file
+type namedDuration struct {
+	key   string
+	value time.Duration
+}
+
+// durations returns the durations of the configuration
+func (c Config) durations() []namedDuration {
+	return []namedDuration{
+		{"refreshInterval", c.RefreshInterval},
+		{"statusTimeout", c.StatusTimeout},
+		{"actionTimeout", c.ActionTimeout},
+		{"logTimeout", c.LogTimeout},
+	}
+}
+
+// checkDurations rejects durations written as plain numbers (e.g. refreshInterval: 5), which can't be decoded, with an
+// error naming the key
+func checkDurations(document *yaml.Node) error {
+	if len(document.Content) == 0 || document.Content[0].Kind != yaml.MappingNode {
+		return nil
+	}
+	root := document.Content[0]
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		key, value := root.Content[i].Value, root.Content[i+1]
+		for _, d := range Default().durations() {
+			if d.key != key || value.ShortTag() != "!!int" {
+				continue
+			}
+			return fmt.Errorf("%s must be a duration with a unit (e.g. %ss), not %s", key, value.Value, value.Value)
+		}
+	}
+	return nil
+}
+
+// Validate checks that all durations are positive
+func (c Config) Validate() error {
+	for _, d := range c.durations() {
+		if d.value <= 0 {
+			return fmt.Errorf("%s must be a positive duration, not %s", d.key, d.value)
+		}
+	}
+	return nil
+}
+
+// Timeouts returns the timeouts of the adapter calls
+func (c Config) Timeouts() adapters.Timeouts {
+	return adapters.Timeouts{
+		Status: c.StatusTimeout,
+		Action: c.ActionTimeout,
+		Logs:   c.LogTimeout,
+	}
+}
+
+// LogOptions returns the options used to stream the log of an instance in the log screen
+func (c Config) LogOptions(name string) adapters.LogOptions {
+	options := adapters.LogOptions{
+		Since: c.LogSince,
+		Tail:  c.LogTail,
+	}
+	if i, ok := c.Instances[name]; ok {
+		if i.LogSince != "" {
+			options.Since = i.LogSince
+		}
+		if i.LogTail != "" {
+			options.Tail = i.LogTail
+		}
+	}
+	return options
+}
+
+// CheckUpdates tells whether the registry is checked for newer images of an instance
+func (c Config) CheckUpdates(name string) bool {
+	if i, ok := c.Instances[name]; ok && i.UpdateCheck != nil {
+		return *i.UpdateCheck
+	}
+	return c.UpdateCheck
+}
+
+// DockerHosts returns the Docker engines the instances run on
+func (c Config) DockerHosts() adapters.DockerHosts {
+	hosts := adapters.DockerHosts{
+		BasePaths: c.BasePathHosts,
+		Instances: map[string]string{},
+		Paths:     c.BasePath,
+	}
+	for name, i := range c.Instances {
+		if i.Host != "" {
+			hosts.Instances[name] = i.Host
+		}
+	}
+	return hosts
+}
+
+// GroupNames returns the sorted names of all groups defined in Config.Groups or in the settings of the instances
+func (c Config) GroupNames() []string {
+	var names []string
+	for name := range c.Groups {
+		names = append(names, name)
+	}
+	for _, i := range c.Instances {
+		for _, name := range i.Groups {
+			if !slices.Contains(names, name) {
+				names = append(names, name)
+			}
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// InGroup tells whether an instance belongs to a group
+func (c Config) InGroup(name string, group string) bool {
+	return slices.Contains(c.Groups[group], name) || slices.Contains(c.Instances[name].Groups, group)
+}
+
+// IsFavourite tells whether an instance is a favourite
+func (c Config) IsFavourite(name string) bool {
+	for _, f := range c.Favourites {
+		if f == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,78 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// writeConfig writes a configuration file and returns its path
+func writeConfig(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoad(t *testing.T) {
+	path := writeConfig(t, `
+basePath: [/cc]
+refreshInterval: 10s
+statusTimeout: 2s
+instances:
+  dev:
+    updateCheck: false
+`)
+	c, err := Load(path, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(c.BasePath) != 1 || c.BasePath[0] != "/cc" {
+		t.Errorf("basePath = %v", c.BasePath)
+	}
+	if c.RefreshInterval != 10*time.Second || c.StatusTimeout != 2*time.Second {
+		t.Errorf("refreshInterval = %s, statusTimeout = %s", c.RefreshInterval, c.StatusTimeout)
+	}
+	// Unset values keep their defaults
+	if c.ActionTimeout != Default().ActionTimeout || c.Engine != "docker" {
+		t.Errorf("actionTimeout = %s, engine = %s, want the defaults", c.ActionTimeout, c.Engine)
+	}
+	if c.CheckUpdates("dev") || !c.CheckUpdates("other") {
+		t.Error("the update check of the instance doesn't override the global one")
+	}
+}
+
+func TestLoadMissingFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if _, err := Load(path, false); err != nil {
+		t.Errorf("optional missing file: %v", err)
+	}
+	if _, err := Load(path, true); err == nil {
+		t.Error("required missing file wasn't reported")
+	}
+}
+
+func TestLoadInvalidDurations(t *testing.T) {
+	tests := []struct {
+		content string
+		key     string
+	}{
+		{"refreshInterval: 5", "refreshInterval"},
+		{"statusTimeout: 0s", "statusTimeout"},
+		{"actionTimeout: 0", "actionTimeout"},
+		{"statusTimeout: 2000000000", "statusTimeout"},
+		{"logTimeout: -1m", "logTimeout"},
+	}
+	for _, test := range tests {
+		t.Run(test.content, func(t *testing.T) {
+			_, err := Load(writeConfig(t, test.content), true)
+			if err == nil || !strings.Contains(err.Error(), test.key) {
+				t.Errorf("got error %v, want an error naming %s", err, test.key)
+			}
+		})
+	}
+}
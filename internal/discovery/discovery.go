@@ -0,0 +1,87 @@
+package discovery
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ErrInstanceNotFound is returned by FindInstance if no instance was found
+var ErrInstanceNotFound = errors.New("instance not found")
+
+// ErrInstanceAmbiguous is returned by FindInstance if an instance name exists in more than one base path
+var ErrInstanceAmbiguous = errors.New("instance name is ambiguous")
+
+// Instance identifies a CloudControl instance by the base path it was found in and its name
+type Instance struct {
+	// BasePath is the base path the instance folder is located in
+	BasePath string
+	// Name is the name of the instance folder
+	Name string
+}
+
+// GetSubFolders walks through the given base path and returns a list of directory entries which are also directories.
+func GetSubFolders(basePath string) ([]string, error) {
+	entries, err := os.ReadDir(basePath)
+	if err != nil {
+		return nil, err
+	}
+
+	var items []string
+
+	for _, e := range entries {
+		if e.Type().IsDir() {
+			items = append(items, e.Name())
+		}
+	}
+	return items, nil
+}
+
+// FindInstances returns all instances found in the given base paths
+func FindInstances(basePaths []string) ([]Instance, error) {
+	var instances []Instance
+	for _, p := range basePaths {
+		if folders, err := GetSubFolders(p); err != nil {
+			return nil, fmt.Errorf("can not read base path %s: %w", p, err)
+		} else {
+			for _, f := range folders {
+				instances = append(instances, Instance{BasePath: p, Name: f})
+			}
+		}
+	}
+	return instances, nil
+}
+
+// FindInstance looks up an instance by its name or by its path (base path and name) in the given base paths.
+// If more than one instance matches the name, ErrInstanceAmbiguous is returned
+func FindInstance(basePaths []string, name string) (Instance, error) {
+	instances, err := FindInstances(basePaths)
+	if err != nil {
+		return Instance{}, err
+	}
+	var found []Instance
+	for _, i := range instances {
+		if i.Name == name || filepath.Join(i.BasePath, i.Name) == filepath.Clean(name) {
+			found = append(found, i)
+		}
+	}
+	switch len(found) {
+	case 0:
+		return Instance{}, fmt.Errorf("%w: %s", ErrInstanceNotFound, name)
+	case 1:
+		return found[0], nil
+	default:
+		var basePaths []string
+		for _, i := range found {
+			basePaths = append(basePaths, i.BasePath)
+		}
+		return Instance{}, fmt.Errorf(
+			"%w: %s exists in the base paths %s, please use the full path",
+			ErrInstanceAmbiguous,
+			name,
+			strings.Join(basePaths, ", "),
+		)
+	}
+}
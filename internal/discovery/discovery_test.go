@@ -0,0 +1,54 @@
+package discovery
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// makeBasePath creates a base path holding instance folders with the given names
+func makeBasePath(t *testing.T, names ...string) string {
+	t.Helper()
+	basePath := t.TempDir()
+	for _, name := range names {
+		if err := os.Mkdir(filepath.Join(basePath, name), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return basePath
+}
+
+func TestFindInstance(t *testing.T) {
+	first := makeBasePath(t, "dev", "shared")
+	second := makeBasePath(t, "prod", "shared")
+	basePaths := []string{first, second}
+
+	tests := []struct {
+		name    string
+		want    Instance
+		wantErr error
+	}{
+		{"dev", Instance{BasePath: first, Name: "dev"}, nil},
+		{"prod", Instance{BasePath: second, Name: "prod"}, nil},
+		{"shared", Instance{}, ErrInstanceAmbiguous},
+		{filepath.Join(second, "shared"), Instance{BasePath: second, Name: "shared"}, nil},
+		{filepath.Join(first, "shared") + string(filepath.Separator), Instance{BasePath: first, Name: "shared"}, nil},
+		{"missing", Instance{}, ErrInstanceNotFound},
+		{filepath.Join(first, "prod"), Instance{}, ErrInstanceNotFound},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := FindInstance(basePaths, test.name)
+			if !errors.Is(err, test.wantErr) || got != test.want {
+				t.Errorf("FindInstance(%q) = %+v, %v, want %+v, %v", test.name, got, err, test.want, test.wantErr)
+			}
+		})
+	}
+}
+
+func TestFindInstancesMissingBasePath(t *testing.T) {
+	if _, err := FindInstances([]string{filepath.Join(t.TempDir(), "missing")}); err == nil {
+		t.Error("a missing base path wasn't reported")
+	}
+}
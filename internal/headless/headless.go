@@ -0,0 +1,314 @@
+package headless
+
+// Headless commands for scripts and CI jobs that can't run the TUI
+
+import (
+	"ccmanager/internal/adapters"
+	"ccmanager/internal/discovery"
+	"ccmanager/internal/recordings"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/moby/term"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// Exit codes returned by the headless commands
+const (
+	// ExitOK is returned if the command succeeded
+	ExitOK = 0
+	// ExitError is returned if the command failed
+	ExitError = 1
+	// ExitNotFound is returned if the requested instance could not be found
+	ExitNotFound = 2
+	// ExitNotRunning is returned if the requested instance is not running
+	ExitNotRunning = 3
+)
+
+// Runner runs CCmanager commands without the TUI
+type Runner struct {
+	// Adapter is the adapter used to connect to CloudControl instances
+	Adapter adapters.BaseAdapter
+	// BasePath is a list of CloudControl instance base paths
+	BasePath []string
+	// In is the terminal to read input from when running CloudControl
+	In io.Reader
+	// Out is the writer to write command output to
+	Out io.Writer
+	// Err is the writer to write error messages to
+	Err io.Writer
+	// Output is the format used to print instance states (one of OutputTable, OutputJSON or OutputYAML)
+	Output string
+	// Timeouts holds the timeouts of the adapter calls
+	Timeouts adapters.Timeouts
+	// RecordingDir is the directory exec sessions are recorded into. Sessions aren't recorded if it is empty
+	RecordingDir string
+	// RecordInput tells whether the input of recorded sessions is recorded as well
+	RecordInput bool
+	// DetachableSessions tells whether commands in the cli service run in detachable sessions
+	DetachableSessions bool
+}
+
+// NewRunner creates a Runner using the standard input and output of the process
+func NewRunner(adapter adapters.BaseAdapter, basePath []string, output string, timeouts adapters.Timeouts) Runner {
+	return Runner{
+		Adapter:  adapter,
+		BasePath: basePath,
+		In:       os.Stdin,
+		Out:      os.Stdout,
+		Err:      os.Stderr,
+		Output:   output,
+		Timeouts: timeouts,
+	}
+}
+
+// List prints all instances with their state. Instances whose status can't be fetched are listed anyway, their
+// errors are printed to Err and ExitError is returned
+func (r Runner) List() int {
+	instances, err := discovery.FindInstances(r.BasePath)
+	if err != nil {
+		return r.fail(ExitError, err)
+	}
+	code := ExitOK
+	statuses := make([]adapters.CloudControlStatus, len(instances))
+	for index, i := range instances {
+		s, err := r.status(i)
+		if err != nil {
+			s.Error = err
+			code = r.fail(ExitError, fmt.Errorf("can not get status of %s: %w", filepath.Join(i.BasePath, i.Name), err))
+		}
+		statuses[index] = s
+	}
+	if r.Output == OutputJSON || r.Output == OutputYAML {
+		result := []InstanceStatus{}
+		for index, i := range instances {
+			result = append(result, NewInstanceStatus(i, statuses[index]))
+		}
+		if err := encode(r.Out, r.Output, result); err != nil {
+			return r.fail(ExitError, err)
+		}
+		return code
+	}
+	w := tabwriter.NewWriter(r.Out, 0, 4, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "NAME\tSTATE\tIMAGE\tTAG\tPATH")
+	for index, i := range instances {
+		s := statuses[index]
+		_, _ = fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", i.Name, s.CCCStatus, s.Image, s.Tag, filepath.Join(i.BasePath, i.Name))
+	}
+	if err := w.Flush(); err != nil {
+		return r.fail(ExitError, err)
+	}
+	return code
+}
+
+// Status prints the state of an instance. It returns ExitNotRunning if the instance is not running
+func (r Runner) Status(name string) int {
+	instance, s, code := r.lookup(name)
+	if code != ExitOK {
+		return code
+	}
+	if r.Output == OutputJSON || r.Output == OutputYAML {
+		if err := encode(r.Out, r.Output, NewInstanceStatus(instance, s)); err != nil {
+			return r.fail(ExitError, err)
+		}
+	} else {
+		r.printStatus(instance, s)
+	}
+	if !s.Running {
+		return ExitNotRunning
+	}
+	return ExitOK
+}
+
+// printStatus prints the state of an instance in a human-readable form
+func (r Runner) printStatus(instance discovery.Instance, s adapters.CloudControlStatus) {
+	var portMappings []string
+	for _, mapping := range s.PortMappings {
+		portMappings = append(portMappings, fmt.Sprintf("%s:%s", mapping.HostPort, mapping.ContainerPort))
+	}
+	_, _ = fmt.Fprintf(
+		r.Out,
+		"Name: %s\nPath: %s\nState: %s\nImage: %s:%s\nCCC port: %s\nPort mappings: %s\n",
+		instance.Name,
+		filepath.Join(instance.BasePath, instance.Name),
+		s.CCCStatus,
+		s.Image,
+		s.Tag,
+		s.CCCPort,
+		strings.Join(portMappings, ", "),
+	)
+	if s.Host != "" {
+		_, _ = fmt.Fprintf(r.Out, "Host: %s\n", s.Host)
+	}
+	if s.Error != nil {
+		_, _ = fmt.Fprintf(r.Out, "Error: %s\n", s.Error)
+	}
+}
+
+// Start starts an instance
+func (r Runner) Start(name string) int {
+	instance, code := r.find(name)
+	if code != ExitOK {
+		return code
+	}
+	ctx, cancel := r.context(r.Timeouts.Action)
+	defer cancel()
+	if err := r.Adapter.StartCloudControl(ctx, instance.BasePath, instance.Name); err != nil {
+		return r.fail(ExitError, fmt.Errorf("can not start CloudControl: %w", err))
+	}
+	return ExitOK
+}
+
+// Stop stops an instance
+func (r Runner) Stop(name string) int {
+	instance, code := r.find(name)
+	if code != ExitOK {
+		return code
+	}
+	ctx, cancel := r.context(r.Timeouts.Action)
+	defer cancel()
+	if err := r.Adapter.StopCloudControl(ctx, instance.BasePath, instance.Name, true); err != nil {
+		return r.fail(ExitError, fmt.Errorf("can not stop CloudControl: %w", err))
+	}
+	return ExitOK
+}
+
+// Restart stops and starts an instance
+func (r Runner) Restart(name string) int {
+	if code := r.Stop(name); code != ExitOK {
+		return code
+	}
+	return r.Start(name)
+}
+
+// Logs prints the log of an instance. If LogOptions.Follow is set, new log lines are printed until the process
+// is interrupted
+func (r Runner) Logs(name string, options adapters.LogOptions) int {
+	instance, code := r.find(name)
+	if code != ExitOK {
+		return code
+	}
+	var ctx context.Context
+	var cancel context.CancelFunc
+	if options.Follow {
+		ctx, cancel = signal.NotifyContext(context.Background(), os.Interrupt)
+	} else {
+		ctx, cancel = r.context(r.Timeouts.Logs)
+	}
+	defer cancel()
+	l, err := r.Adapter.StreamLogs(ctx, instance.BasePath, instance.Name, options)
+	if err != nil {
+		return r.fail(ExitError, fmt.Errorf("can not get logs: %w", err))
+	}
+	defer l.Close()
+	if _, err := io.Copy(r.Out, l); err != nil && !(options.Follow && ctx.Err() != nil) {
+		return r.fail(ExitError, fmt.Errorf("can not get logs: %w", err))
+	}
+	return ExitOK
+}
+
+// Shell runs CloudControl in an instance using the terminal of the process. It returns the exit code of CloudControl
+// or ExitNotRunning if the instance is not running
+func (r Runner) Shell(name string) int {
+	return r.Exec(name, "cli", adapters.CloudControlCommand)
+}
+
+// Exec runs a command in a service of an instance using the terminal of the process. It returns the exit code of
+// the command or ExitNotRunning if the instance is not running
+func (r Runner) Exec(name string, service string, command []string) int {
+	instance, s, code := r.lookup(name)
+	if code != ExitOK {
+		return code
+	}
+	if !s.Running {
+		return r.fail(ExitNotRunning, fmt.Errorf("instance %s is not running", instance.Name))
+	}
+	var width, height uint = 80, 24
+	if fd, isTerminal := term.GetFdInfo(r.Out); isTerminal {
+		if w, err := term.GetWinsize(fd); err == nil {
+			width = uint(w.Width)
+			height = uint(w.Height)
+		}
+	}
+	options := adapters.ExecOptions{
+		Service:       service,
+		Command:       command,
+		ConsoleWidth:  width,
+		ConsoleHeight: height,
+	}
+	if r.RecordingDir != "" {
+		options.RecordingFile = recordings.Path(r.RecordingDir, instance.Name, service, time.Now())
+		options.RecordInput = r.RecordInput
+	}
+	if r.DetachableSessions && service == "cli" {
+		options.Session = adapters.DetachableSession(command)
+	}
+	c, err := r.Adapter.Exec(context.Background(), instance.BasePath, instance.Name, options)
+	if err != nil {
+		return r.fail(ExitError, err)
+	}
+	c.SetStdin(r.In)
+	c.SetStdout(r.Out)
+	if err := c.Run(); err != nil {
+		var exitErr adapters.ExecExitError
+		if errors.As(err, &exitErr) {
+			return exitErr.ExitCode
+		}
+		return r.fail(ExitError, err)
+	}
+	return ExitOK
+}
+
+// find finds an instance by name
+func (r Runner) find(name string) (discovery.Instance, int) {
+	instance, err := discovery.FindInstance(r.BasePath, name)
+	if err != nil {
+		if errors.Is(err, discovery.ErrInstanceNotFound) {
+			return instance, r.fail(ExitNotFound, err)
+		}
+		return instance, r.fail(ExitError, err)
+	}
+	return instance, ExitOK
+}
+
+// lookup finds an instance by name and fetches its status
+func (r Runner) lookup(name string) (discovery.Instance, adapters.CloudControlStatus, int) {
+	instance, code := r.find(name)
+	if code != ExitOK {
+		return instance, adapters.CloudControlStatus{}, code
+	}
+	s, err := r.status(instance)
+	if err != nil {
+		return instance, s, r.fail(ExitError, err)
+	}
+	return instance, s, ExitOK
+}
+
+// status fetches the status of an instance
+func (r Runner) status(instance discovery.Instance) (adapters.CloudControlStatus, error) {
+	ctx, cancel := r.context(r.Timeouts.Status)
+	defer cancel()
+	return r.Adapter.GetContainerStatus(ctx, instance.BasePath, instance.Name)
+}
+
+// context returns a context that is cancelled after the given timeout or when the process is interrupted
+func (r Runner) context(timeout time.Duration) (context.Context, context.CancelFunc) {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	return ctx, func() {
+		cancel()
+		stop()
+	}
+}
+
+// fail prints an error and returns the given exit code
+func (r Runner) fail(code int, err error) int {
+	_, _ = fmt.Fprintf(r.Err, "Error: %s\n", err)
+	return code
+}
@@ -0,0 +1,65 @@
+package headless
+
+import (
+	"bytes"
+	"ccmanager/internal/adapters"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// statusAdapter returns a running status for all instances except the broken one
+type statusAdapter struct {
+	adapters.BaseAdapter
+}
+
+func (statusAdapter) GetContainerStatus(_ context.Context, _ string, name string) (adapters.CloudControlStatus, error) {
+	if name == "broken" {
+		return adapters.CloudControlStatus{}, errors.New("engine unreachable")
+	}
+	return adapters.CloudControlStatus{Running: true, CCCStatus: adapters.CCCReady, Image: "cc", Tag: "1.0.0"}, nil
+}
+
+// newTestRunner returns a runner listing the instances ok and broken in the given output format
+func newTestRunner(t *testing.T, output string) (Runner, *bytes.Buffer, *bytes.Buffer) {
+	basePath := t.TempDir()
+	for _, name := range []string{"ok", "broken"} {
+		if err := os.Mkdir(filepath.Join(basePath, name), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var out, errOut bytes.Buffer
+	r := Runner{
+		Adapter:  statusAdapter{},
+		BasePath: []string{basePath},
+		Out:      &out,
+		Err:      &errOut,
+		Output:   output,
+		Timeouts: adapters.Timeouts{Status: time.Second},
+	}
+	return r, &out, &errOut
+}
+
+func TestListReportsFailedStatuses(t *testing.T) {
+	for _, output := range []string{OutputTable, OutputJSON} {
+		t.Run(output, func(t *testing.T) {
+			r, out, errOut := newTestRunner(t, output)
+			if code := r.List(); code != ExitError {
+				t.Errorf("exit code %d, want %d", code, ExitError)
+			}
+			if !strings.Contains(errOut.String(), "broken") || !strings.Contains(errOut.String(), "engine unreachable") {
+				t.Errorf("error output %q doesn't name the broken instance", errOut.String())
+			}
+			if !strings.Contains(out.String(), "ok") || !strings.Contains(out.String(), "broken") {
+				t.Errorf("output %q doesn't list all instances", out.String())
+			}
+			if output == OutputJSON && !strings.Contains(out.String(), `"error": "engine unreachable"`) {
+				t.Errorf("output %q doesn't contain the error", out.String())
+			}
+		})
+	}
+}
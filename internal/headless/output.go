@@ -0,0 +1,102 @@
+package headless
+
+import (
+	"ccmanager/internal/adapters"
+	"ccmanager/internal/config"
+	"ccmanager/internal/discovery"
+	"encoding/json"
+	"fmt"
+	"gopkg.in/yaml.v3"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// Output formats supported by the headless commands
+const (
+	// OutputTable prints a human-readable table
+	OutputTable = "table"
+	// OutputJSON prints JSON
+	OutputJSON = "json"
+	// OutputYAML prints YAML
+	OutputYAML = "yaml"
+)
+
+// InstanceStatus is the serializable status of an instance
+type InstanceStatus struct {
+	// Name is the instance name
+	Name string `json:"name" yaml:"name"`
+	// Path is the instance path
+	Path string `json:"path" yaml:"path"`
+	// Host holds the name of the machine the instance runs on. It is empty for instances on the local machine
+	Host string `json:"host,omitempty" yaml:"host,omitempty"`
+	// Running says whether the instance is running
+	Running bool `json:"running" yaml:"running"`
+	// Image holds the image name the instance is using
+	Image string `json:"image" yaml:"image"`
+	// Tag holds the image tag the instance is using
+	Tag string `json:"tag" yaml:"tag"`
+	// CCCPort holds the port where the CCC can be reached
+	CCCPort string `json:"cccPort" yaml:"cccPort"`
+	// CCCStatus holds the status the CCC returns
+	CCCStatus adapters.CCCStatus `json:"cccStatus" yaml:"cccStatus"`
+	// PortMappings holds additional portmappings (aside from the CCCport)
+	PortMappings []adapters.PortMap `json:"portMappings" yaml:"portMappings"`
+	// Error holds the text of an error that occurred when gathering information about the instance
+	Error string `json:"error,omitempty" yaml:"error,omitempty"`
+}
+
+// NewInstanceStatus converts the status of an instance into an InstanceStatus
+func NewInstanceStatus(instance discovery.Instance, s adapters.CloudControlStatus) InstanceStatus {
+	i := InstanceStatus{
+		Name:         instance.Name,
+		Path:         filepath.Join(instance.BasePath, instance.Name),
+		Host:         s.Host,
+		Running:      s.Running,
+		Image:        s.Image,
+		Tag:          s.Tag,
+		CCCPort:      s.CCCPort,
+		CCCStatus:    s.CCCStatus,
+		PortMappings: s.PortMappings,
+	}
+	if i.PortMappings == nil {
+		i.PortMappings = []adapters.PortMap{}
+	}
+	if s.Error != nil {
+		i.Error = s.Error.Error()
+	}
+	return i
+}
+
+// ValidOutput returns whether the given output format is supported
+func ValidOutput(output string) bool {
+	return output == OutputTable || output == OutputJSON || output == OutputYAML
+}
+
+// encode writes v in the given machine-readable output format
+func encode(w io.Writer, output string, v interface{}) error {
+	switch output {
+	case OutputJSON:
+		e := json.NewEncoder(w)
+		e.SetIndent("", "  ")
+		return e.Encode(v)
+	case OutputYAML:
+		e := yaml.NewEncoder(w)
+		e.SetIndent(2)
+		if err := e.Encode(v); err != nil {
+			return err
+		}
+		return e.Close()
+	default:
+		return fmt.Errorf("unsupported output format %s", output)
+	}
+}
+
+// PrintConfig prints the effective configuration as YAML
+func PrintConfig(w io.Writer, c config.Config) int {
+	if err := encode(w, OutputYAML, c); err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+		return ExitError
+	}
+	return ExitOK
+}
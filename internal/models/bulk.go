@@ -0,0 +1,249 @@
+package models
+
+import (
+	"ccmanager/internal"
+	"ccmanager/internal/adapters"
+	"context"
+	"fmt"
+	tea "github.com/charmbracelet/bubbletea"
+	"strings"
+)
+
+// bulkAction is an action that can be run on all marked instances
+type bulkAction string
+
+// The actions that can be run on marked instances
+const (
+	bulkStart   bulkAction = "start"
+	bulkStop    bulkAction = "stop"
+	bulkRestart bulkAction = "restart"
+	bulkUpgrade bulkAction = "upgrade"
+)
+
+// run runs the action on an instance
+func (a bulkAction) run(ctx context.Context, adapter adapters.BaseAdapter, item InstanceItem) error {
+	switch a {
+	case bulkStart:
+		return adapter.StartCloudControl(ctx, item.Path, item.Name)
+	case bulkStop:
+		return adapter.StopCloudControl(ctx, item.Path, item.Name, true)
+	case bulkRestart:
+		if err := adapter.StopCloudControl(ctx, item.Path, item.Name, true); err != nil {
+			return err
+		}
+		return adapter.StartCloudControl(ctx, item.Path, item.Name)
+	case bulkUpgrade:
+		if item.Update == "" {
+			return nil
+		}
+		if err := upgradeTag(item); err != nil {
+			return err
+		}
+		return bulkRestart.run(ctx, adapter, item)
+	}
+	return fmt.Errorf("unknown action %s", a)
+}
+
+// progress describes an instance the action is running on
+func (a bulkAction) progress() string {
+	switch a {
+	case bulkStart:
+		return "Starting"
+	case bulkStop:
+		return "Stopping"
+	case bulkRestart:
+		return "Restarting"
+	default:
+		return "Upgrading"
+	}
+}
+
+// bulkJob is an action to run on an instance
+type bulkJob struct {
+	action bulkAction
+	item   InstanceItem
+	// phase orders the jobs of a run. Jobs only start after all jobs of earlier phases have finished
+	phase int
+}
+
+// newBulkJobs creates jobs running the same action on all given instances
+func newBulkJobs(action bulkAction, items []InstanceItem) []bulkJob {
+	var jobs []bulkJob
+	for _, item := range items {
+		jobs = append(jobs, bulkJob{action: action, item: item})
+	}
+	return jobs
+}
+
+// bulkRun holds the state of actions running on multiple instances. At most parallelism instances are processed
+// at the same time, the remaining ones wait in queue
+type bulkRun struct {
+	// description describes the run in the summary (e.g. "stop")
+	description string
+	// ctx is the context of the run. It is cancelled using cancel
+	ctx context.Context
+	// cancel cancels all jobs of the run
+	cancel context.CancelFunc
+	// queue holds the jobs waiting to be processed
+	queue []bulkJob
+	// running is the number of instances currently processed
+	running int
+	// phase is the phase of the jobs currently processed
+	phase int
+	// total is the number of instances of the run
+	total int
+	// failures holds the error messages of failed instances
+	failures []string
+	// skipped holds the names of the instances that were still queued when the run was cancelled
+	skipped []string
+}
+
+// cancelBulk cancels the running jobs of the bulk action and skips the queued ones
+func cancelBulk(m MainModel) (MainModel, tea.Cmd) {
+	run := m.bulk
+	run.cancel()
+	var cmds []tea.Cmd
+	for _, job := range run.queue {
+		run.skipped = append(run.skipped, job.item.Name)
+		m.Progress[job.item.ID()] = "Skipped"
+		cmds = append(cmds, updateItem(m, job.item.ID(), func(i *InstanceItem) {
+			i.Progress = "Skipped"
+		}))
+	}
+	run.queue = nil
+	if run.running == 0 {
+		var cmd tea.Cmd
+		m, cmd = finishBulk(m)
+		return m, tea.Batch(append(cmds, cmd)...)
+	}
+	return m, tea.Batch(append(cmds, m.List.NewStatusMessage(internal.ErrorMessageStyle("Action cancelled")))...)
+}
+
+// finishBulk ends the bulk action after all instances have been processed and shows a summary
+func finishBulk(m MainModel) (MainModel, tea.Cmd) {
+	run := m.bulk
+	run.cancel()
+	m.bulk = nil
+	var cmds []tea.Cmd
+	for id := range m.Progress {
+		delete(m.Progress, id)
+		cmds = append(cmds, updateItem(m, id, func(i *InstanceItem) {
+			i.Progress = ""
+		}))
+	}
+	skipped := ""
+	if len(run.skipped) > 0 {
+		skipped = fmt.Sprintf(", skipped %d cancelled instances: %s", len(run.skipped), strings.Join(run.skipped, ", "))
+	}
+	if len(run.failures) > 0 {
+		cmds = append(cmds, m.List.NewStatusMessage(internal.ErrorMessageStyle(fmt.Sprintf(
+			"Can not %s %d of %d instances: %s%s",
+			run.description,
+			len(run.failures),
+			run.total,
+			strings.Join(run.failures, "; "),
+			skipped,
+		))))
+	} else if len(run.skipped) > 0 {
+		cmds = append(cmds, m.List.NewStatusMessage(internal.ErrorMessageStyle(fmt.Sprintf(
+			"Finished to %s %d of %d instances%s",
+			run.description,
+			run.total-len(run.skipped),
+			run.total,
+			skipped,
+		))))
+	} else {
+		cmds = append(cmds, m.List.NewStatusMessage(fmt.Sprintf("Finished to %s %d instances", run.description, run.total)))
+	}
+	return m, tea.Batch(cmds...)
+}
+
+// markedItems returns all marked instances
+func markedItems(m MainModel) []InstanceItem {
+	var items []InstanceItem
+	for _, i := range m.List.Items() {
+		if item := i.(InstanceItem); item.Marked {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// updateItem changes the instance with the given ID in the list
+func updateItem(m MainModel, id string, update func(item *InstanceItem)) tea.Cmd {
+	for index, i := range m.List.Items() {
+		item := i.(InstanceItem)
+		if item.ID() == id {
+			update(&item)
+			return m.List.SetItem(index, item)
+		}
+	}
+	return nil
+}
+
+// toggleMark marks or unmarks the selected instance
+func toggleMark(m MainModel) (MainModel, tea.Cmd) {
+	item, ok := m.List.SelectedItem().(InstanceItem)
+	if !ok {
+		return m, nil
+	}
+	m.Marked[item.ID()] = !item.Marked
+	return m, updateItem(m, item.ID(), func(i *InstanceItem) {
+		i.Marked = !i.Marked
+	})
+}
+
+// toggleMarkAll marks all instances matching the current filter. If all of them are already marked, they are
+// unmarked instead
+func toggleMarkAll(m MainModel) (MainModel, tea.Cmd) {
+	mark := false
+	for _, i := range m.List.VisibleItems() {
+		if !i.(InstanceItem).Marked {
+			mark = true
+		}
+	}
+	var cmds []tea.Cmd
+	for _, i := range m.List.VisibleItems() {
+		m.Marked[i.(InstanceItem).ID()] = mark
+		cmds = append(cmds, updateItem(m, i.(InstanceItem).ID(), func(item *InstanceItem) {
+			item.Marked = mark
+		}))
+	}
+	return m, tea.Batch(cmds...)
+}
+
+// startBulkJobs starts processing queued instances of the running bulk action until parallelism instances are
+// processed at the same time. The jobs of the next phase wait until all jobs of the current phase have finished
+func startBulkJobs(m MainModel) (MainModel, tea.Cmd) {
+	run := m.bulk
+	var cmds []tea.Cmd
+	for len(run.queue) > 0 && run.running < max(m.Config.Parallelism, 1) &&
+		(run.running == 0 || run.queue[0].phase == run.phase) {
+		var cmd tea.Cmd
+		m, cmd = nextBulkJob(m)
+		cmds = append(cmds, cmd)
+	}
+	return m, tea.Batch(cmds...)
+}
+
+// nextBulkJob starts processing the next instance in the queue of the running bulk action
+func nextBulkJob(m MainModel) (MainModel, tea.Cmd) {
+	run := m.bulk
+	job := run.queue[0]
+	run.queue = run.queue[1:]
+	run.running++
+	run.phase = job.phase
+	m.Progress[job.item.ID()] = job.action.progress()
+	updateCmd := updateItem(m, job.item.ID(), func(i *InstanceItem) {
+		i.Progress = job.action.progress()
+	})
+	return m, tea.Batch(updateCmd, func() tea.Msg {
+		ctx, cancel := context.WithTimeout(run.ctx, m.Timeouts.Action)
+		defer cancel()
+		return BulkJobFinishedMsg{
+			run:  run,
+			Item: job.item,
+			Err:  job.action.run(ctx, m.Adapter, job.item),
+		}
+	})
+}
@@ -0,0 +1,81 @@
+package models
+
+import (
+	"ccmanager/internal/config"
+	"context"
+	"github.com/charmbracelet/bubbles/list"
+	"testing"
+)
+
+// newBulkTestModel returns a model running jobs on instances named like their actions with the given parallelism
+func newBulkTestModel(parallelism int, jobs []bulkJob) MainModel {
+	var items []list.Item
+	for _, job := range jobs {
+		items = append(items, job.item)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	m := MainModel{
+		List:     list.New(items, list.NewDefaultDelegate(), 0, 0),
+		Config:   config.Config{Parallelism: parallelism},
+		Progress: map[string]string{},
+	}
+	m.bulk = &bulkRun{description: "test", ctx: ctx, cancel: cancel, queue: jobs, total: len(jobs)}
+	return m
+}
+
+// workspaceJobs returns two stop jobs followed by two start jobs of the next phase
+func workspaceJobs() []bulkJob {
+	jobs := newBulkJobs(bulkStop, []InstanceItem{{Name: "stop1", Path: "/p"}, {Name: "stop2", Path: "/p"}})
+	for _, job := range newBulkJobs(bulkStart, []InstanceItem{{Name: "start1", Path: "/p"}, {Name: "start2", Path: "/p"}}) {
+		job.phase = 1
+		jobs = append(jobs, job)
+	}
+	return jobs
+}
+
+func TestBulkPhases(t *testing.T) {
+	m := newBulkTestModel(4, workspaceJobs())
+	m, _ = startBulkJobs(m)
+	run := m.bulk
+	if run.running != 2 || len(run.queue) != 2 {
+		t.Fatalf("started %d jobs with %d queued, want the 2 stops only", run.running, len(run.queue))
+	}
+
+	m, _ = BulkJobFinishedHandler(m, BulkJobFinishedMsg{run: run, Item: InstanceItem{Name: "stop1", Path: "/p"}})
+	if run.running != 1 || len(run.queue) != 2 {
+		t.Fatalf("%d jobs running with %d queued after the first stop, want a start to wait", run.running, len(run.queue))
+	}
+	m, _ = BulkJobFinishedHandler(m, BulkJobFinishedMsg{run: run, Item: InstanceItem{Name: "stop2", Path: "/p"}})
+	if run.running != 2 || len(run.queue) != 0 || run.phase != 1 {
+		t.Fatalf("%d jobs of phase %d running with %d queued after all stops, want both starts", run.running,
+			run.phase, len(run.queue))
+	}
+	if p := m.Progress[(InstanceItem{Name: "start1", Path: "/p"}).ID()]; p != "Starting" {
+		t.Errorf("progress of start1 is %q, want Starting", p)
+	}
+}
+
+func TestBulkCancel(t *testing.T) {
+	m := newBulkTestModel(1, workspaceJobs())
+	m, _ = startBulkJobs(m)
+	run := m.bulk
+
+	m, _ = cancelBulk(m)
+	if run.ctx.Err() == nil {
+		t.Error("the running jobs weren't cancelled")
+	}
+	if len(run.queue) != 0 || len(run.skipped) != 3 {
+		t.Fatalf("%d jobs queued and %v skipped, want all queued jobs skipped", len(run.queue), run.skipped)
+	}
+	if m.bulk == nil {
+		t.Fatal("the run finished while a job is running")
+	}
+
+	m, _ = BulkJobFinishedHandler(m, BulkJobFinishedMsg{run: run, Item: InstanceItem{Name: "stop1", Path: "/p"}, Err: context.Canceled})
+	if m.bulk != nil {
+		t.Error("the run didn't finish after the last running job")
+	}
+	if len(run.failures) != 1 {
+		t.Errorf("failures = %v, want only the cancelled running job", run.failures)
+	}
+}
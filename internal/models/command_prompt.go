@@ -0,0 +1,177 @@
+package models
+
+import (
+	"bufio"
+	"ccmanager/internal/adapters"
+	"fmt"
+	tea "github.com/charmbracelet/bubbletea"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// commandHistoryLimit is the maximum number of commands kept in the command history
+const commandHistoryLimit = 100
+
+// Labels of the fields of the command prompt
+const (
+	promptCommand = "Command"
+	promptService = "Service"
+)
+
+// CommandPrompt holds the state of the form used to run a command in an instance. While the command field is
+// focused, up and down browse the command history instead of switching fields
+type CommandPrompt struct {
+	Form
+	// Item is the instance the command runs in
+	Item InstanceItem
+	// history holds the previously run commands, the newest one last
+	history []string
+	// position is the index of the history entry shown in the command field. It is len(history) for a new command
+	position int
+}
+
+// newCommandPrompt creates a prompt for a command running in one of the given services. The cli service is selected
+// by default
+func newCommandPrompt(item InstanceItem, services []string, history []string) CommandPrompt {
+	service := formField{label: promptService, choices: services}
+	for i, s := range services {
+		if s == "cli" {
+			service.choice = i
+		}
+	}
+	return CommandPrompt{
+		Form:     newForm(newTextField(promptCommand, ""), service),
+		Item:     item,
+		history:  history,
+		position: len(history),
+	}
+}
+
+// Update browses the command history or updates the form
+func (p CommandPrompt) Update(msg tea.KeyMsg) (CommandPrompt, tea.Cmd) {
+	if p.fields[p.focus].label == promptCommand {
+		switch msg.Type {
+		case tea.KeyUp:
+			return p.browse(p.position - 1), nil
+		case tea.KeyDown:
+			return p.browse(p.position + 1), nil
+		}
+	}
+	var cmd tea.Cmd
+	p.Form, cmd = p.Form.Update(msg)
+	return p, cmd
+}
+
+// browse shows the history entry with the given index in the command field. The index after the newest entry shows
+// an empty command
+func (p CommandPrompt) browse(position int) CommandPrompt {
+	if position < 0 || position > len(p.history) {
+		return p
+	}
+	p.position = position
+	value := ""
+	if position < len(p.history) {
+		value = p.history[position]
+	}
+	p.fields[p.focus].input.SetValue(value)
+	p.fields[p.focus].input.CursorEnd()
+	return p
+}
+
+// Command returns the service and the command entered in the prompt or an error if no command was entered
+func (p CommandPrompt) Command() (string, string, error) {
+	var service, command string
+	for _, f := range p.fields {
+		switch f.label {
+		case promptCommand:
+			command = f.value()
+		case promptService:
+			service = f.value()
+		}
+	}
+	if command == "" {
+		return service, command, fmt.Errorf("please enter a command")
+	}
+	return service, command, nil
+}
+
+// pausedExec runs a command in an instance and waits for enter afterwards, so its output can be read before the
+// instance list is shown again
+type pausedExec struct {
+	*adapters.ContainerExec
+	stdin  io.Reader
+	stdout io.Writer
+}
+
+var _ tea.ExecCommand = &pausedExec{}
+
+func (p *pausedExec) Run() error {
+	err := p.ContainerExec.Run()
+	if err != nil {
+		_, _ = fmt.Fprintf(p.stdout, "\r\n%s", err.Error())
+	}
+	_, _ = fmt.Fprint(p.stdout, "\r\nPress enter to return to the instance list")
+	_, _ = bufio.NewReader(p.stdin).ReadString('\n')
+	return err
+}
+
+func (p *pausedExec) SetStdin(reader io.Reader) {
+	p.stdin = reader
+	p.ContainerExec.SetStdin(reader)
+}
+
+func (p *pausedExec) SetStdout(writer io.Writer) {
+	p.stdout = writer
+	p.ContainerExec.SetStdout(writer)
+}
+
+// loadHistory reads the command history from a file holding one command per line. A missing or unreadable file
+// results in an empty history
+func loadHistory(path string) []string {
+	if path == "" {
+		return nil
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	var history []string
+	for _, line := range strings.Split(string(content), "\n") {
+		if line != "" {
+			history = addToHistory(history, line)
+		}
+	}
+	return history
+}
+
+// addToHistory appends a command to the history. An earlier entry of the same command is removed and only the
+// newest commandHistoryLimit entries are kept
+func addToHistory(history []string, command string) []string {
+	var newHistory []string
+	for _, h := range history {
+		if h != command {
+			newHistory = append(newHistory, h)
+		}
+	}
+	newHistory = append(newHistory, command)
+	if len(newHistory) > commandHistoryLimit {
+		newHistory = newHistory[len(newHistory)-commandHistoryLimit:]
+	}
+	return newHistory
+}
+
+// saveHistory writes the command history into a file. Nothing is written if path is empty
+func saveHistory(path string, history []string) error {
+	if path == "" {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("can not create history directory: %w", err)
+	}
+	if err := os.WriteFile(path, []byte(strings.Join(history, "\n")+"\n"), 0600); err != nil {
+		return fmt.Errorf("can not write command history: %w", err)
+	}
+	return nil
+}
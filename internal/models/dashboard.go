@@ -0,0 +1,235 @@
+package models
+
+import (
+	"ccmanager/internal"
+	"ccmanager/internal/adapters"
+	"context"
+	"fmt"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/lipgloss/table"
+	"github.com/docker/go-units"
+	"sort"
+	"strings"
+)
+
+// dashboardHistory is the number of samples shown in the sparklines of the dashboard
+const dashboardHistory = 20
+
+// sparkBlocks are the characters used to draw sparklines from the lowest to the highest value
+var sparkBlocks = []rune("▁▂▃▄▅▆▇█")
+
+// dashboardColumn is a column of the dashboard the rows can be sorted by
+type dashboardColumn int
+
+// The sortable columns of the dashboard
+const (
+	dashboardName dashboardColumn = iota
+	dashboardCPU
+	dashboardMemory
+	dashboardNetwork
+	dashboardBlock
+)
+
+// dashboardHeaders holds the headers of the dashboard table. The sparklines follow the CPU and memory columns
+var dashboardHeaders = map[dashboardColumn]string{
+	dashboardName:    "Instance",
+	dashboardCPU:     "CPU %",
+	dashboardMemory:  "Memory",
+	dashboardNetwork: "Net I/O",
+	dashboardBlock:   "Block I/O",
+}
+
+// dashboardRow holds the resource usage of an instance in the dashboard
+type dashboardRow struct {
+	name   string
+	usage  adapters.ResourceUsage
+	cpu    []float64
+	memory []float64
+	err    error
+}
+
+// dashboard holds the state of the resource dashboard
+type dashboard struct {
+	// cancel stops all usage streams of the dashboard
+	cancel context.CancelFunc
+	// rows holds the rows by instance ID
+	rows map[string]*dashboardRow
+	// sortBy is the column the rows are sorted by
+	sortBy dashboardColumn
+	// reverse reverses the sort order
+	reverse bool
+}
+
+// usageStream receives the resource usage of an instance shown in the dashboard
+type usageStream struct {
+	dashboard *dashboard
+	id        string
+	usages    <-chan adapters.ResourceUsage
+	errs      <-chan error
+}
+
+// Next returns a tea.Cmd that waits for the next resource usage of the stream
+func (u *usageStream) Next() tea.Cmd {
+	return func() tea.Msg {
+		select {
+		case usage, ok := <-u.usages:
+			if ok {
+				return ResourceUsageMsg{stream: u, Usage: usage}
+			}
+			return UsageStreamEndedMsg{stream: u, Err: <-u.errs}
+		case err := <-u.errs:
+			return UsageStreamEndedMsg{stream: u, Err: err}
+		}
+	}
+}
+
+// newDashboard starts streaming the resource usage of all running instances in the instance list
+func newDashboard(m MainModel) (*dashboard, tea.Cmd) {
+	ctx, cancel := context.WithCancel(context.Background())
+	d := &dashboard{
+		cancel: cancel,
+		rows:   map[string]*dashboardRow{},
+		sortBy: dashboardCPU,
+	}
+	var cmds []tea.Cmd
+	for _, i := range m.List.Items() {
+		item := i.(InstanceItem)
+		if !item.State.Running {
+			continue
+		}
+		d.rows[item.ID()] = &dashboardRow{name: item.Name}
+		usages, errs := m.Adapter.StreamResourceUsage(ctx, item.Path, item.Name)
+		stream := &usageStream{
+			dashboard: d,
+			id:        item.ID(),
+			usages:    usages,
+			errs:      errs,
+		}
+		cmds = append(cmds, stream.Next())
+	}
+	return d, tea.Batch(cmds...)
+}
+
+// add adds a resource usage sample to the row of an instance
+func (d *dashboard) add(id string, usage adapters.ResourceUsage) {
+	row := d.rows[id]
+	row.usage = usage
+	row.cpu = appendSample(row.cpu, usage.CPUPercent)
+	row.memory = appendSample(row.memory, float64(usage.MemoryUsage))
+}
+
+// sortedRows returns the rows sorted by the selected column
+func (d *dashboard) sortedRows() []*dashboardRow {
+	var rows []*dashboardRow
+	for _, row := range d.rows {
+		rows = append(rows, row)
+	}
+	// Rows with the same values keep their order by name
+	sort.Slice(rows, func(i, j int) bool {
+		return rows[i].name < rows[j].name
+	})
+	sort.SliceStable(rows, func(i, j int) bool {
+		a, b := rows[i], rows[j]
+		if d.reverse {
+			a, b = b, a
+		}
+		switch d.sortBy {
+		case dashboardCPU:
+			return a.usage.CPUPercent > b.usage.CPUPercent
+		case dashboardMemory:
+			return a.usage.MemoryUsage > b.usage.MemoryUsage
+		case dashboardNetwork:
+			return a.usage.NetworkRx+a.usage.NetworkTx > b.usage.NetworkRx+b.usage.NetworkTx
+		case dashboardBlock:
+			return a.usage.BlockRead+a.usage.BlockWrite > b.usage.BlockRead+b.usage.BlockWrite
+		default:
+			return a.name < b.name
+		}
+	})
+	return rows
+}
+
+// View renders the dashboard table
+func (d *dashboard) View(width int) string {
+	if len(d.rows) == 0 {
+		return "No running instances"
+	}
+	headers := []string{
+		dashboardHeaders[dashboardName],
+		dashboardHeaders[dashboardCPU],
+		"",
+		dashboardHeaders[dashboardMemory],
+		"",
+		dashboardHeaders[dashboardNetwork],
+		dashboardHeaders[dashboardBlock],
+	}
+	sortHeader := map[dashboardColumn]int{
+		dashboardName:    0,
+		dashboardCPU:     1,
+		dashboardMemory:  3,
+		dashboardNetwork: 5,
+		dashboardBlock:   6,
+	}[d.sortBy]
+	arrow := "▼"
+	if d.reverse {
+		arrow = "▲"
+	}
+	headers[sortHeader] = fmt.Sprintf("%s %s", headers[sortHeader], arrow)
+
+	t := table.New().
+		Border(lipgloss.NormalBorder()).
+		Headers(headers...).
+		StyleFunc(func(row, col int) lipgloss.Style {
+			if row == 0 {
+				return internal.ItemTitleStyle.Copy().Padding(0, 1)
+			}
+			return lipgloss.NewStyle().Padding(0, 1)
+		})
+	for _, row := range d.sortedRows() {
+		if row.err != nil {
+			t.Row(row.name, internal.ErrorMessageStyle(row.err.Error()), "", "", "", "", "")
+			continue
+		}
+		memory := units.BytesSize(float64(row.usage.MemoryUsage))
+		if row.usage.MemoryLimit > 0 {
+			memory = fmt.Sprintf("%s / %s", memory, units.BytesSize(float64(row.usage.MemoryLimit)))
+		}
+		t.Row(
+			row.name,
+			fmt.Sprintf("%.1f", row.usage.CPUPercent),
+			sparkline(row.cpu),
+			memory,
+			sparkline(row.memory),
+			fmt.Sprintf("%s / %s", units.BytesSize(float64(row.usage.NetworkRx)), units.BytesSize(float64(row.usage.NetworkTx))),
+			fmt.Sprintf("%s / %s", units.BytesSize(float64(row.usage.BlockRead)), units.BytesSize(float64(row.usage.BlockWrite))),
+		)
+	}
+	return lipgloss.NewStyle().MaxWidth(width).Render(t.Render())
+}
+
+// appendSample appends a value to the samples of a sparkline and drops the oldest ones
+func appendSample(samples []float64, value float64) []float64 {
+	samples = append(samples, value)
+	if len(samples) > dashboardHistory {
+		samples = samples[len(samples)-dashboardHistory:]
+	}
+	return samples
+}
+
+// sparkline draws the samples scaled to the highest sample
+func sparkline(samples []float64) string {
+	highest := 0.0
+	for _, s := range samples {
+		highest = max(highest, s)
+	}
+	var b strings.Builder
+	for _, s := range samples {
+		i := 0
+		if highest > 0 {
+			i = int(s / highest * float64(len(sparkBlocks)-1))
+		}
+		b.WriteRune(sparkBlocks[min(max(i, 0), len(sparkBlocks)-1)])
+	}
+	return b.String() + strings.Repeat(" ", dashboardHistory-len(samples))
+}
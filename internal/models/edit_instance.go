@@ -0,0 +1,80 @@
+package models
+
+import (
+	"ccmanager/internal/adapters"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Labels of the fields of the instance editor
+const (
+	editorTag         = "Image tag"
+	editorEnvironment = "Environment"
+	editorPort        = "Port"
+)
+
+// InstanceEditor holds the state of the form used to edit the configuration of an instance. Every environment
+// variable and port mapping has its own field. Clearing a field removes the entry, the empty last field of each
+// kind adds a new entry
+type InstanceEditor struct {
+	Form
+	// Item is the instance being edited
+	Item InstanceItem
+	// config holds the configuration loaded from the compose file
+	config adapters.InstanceConfig
+}
+
+// newInstanceEditor creates an editor for the given instance configuration
+func newInstanceEditor(item InstanceItem, config adapters.InstanceConfig) InstanceEditor {
+	fields := []formField{newTextField(editorTag, config.Tag)}
+	var keys []string
+	for key := range config.Environment {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		fields = append(fields, newTextField(editorEnvironment, fmt.Sprintf("%s=%s", key, config.Environment[key])))
+	}
+	fields = append(fields, newTextField(editorEnvironment, ""))
+	for _, port := range config.Ports {
+		fields = append(fields, newTextField(editorPort, port))
+	}
+	fields = append(fields, newTextField(editorPort, ""))
+	return InstanceEditor{
+		Form:   newForm(fields...),
+		Item:   item,
+		config: config,
+	}
+}
+
+// Config returns the configuration entered in the editor or an error if it is invalid
+func (e InstanceEditor) Config() (adapters.InstanceConfig, error) {
+	config := adapters.InstanceConfig{
+		Environment: map[string]string{},
+	}
+	for _, f := range e.fields {
+		value := f.value()
+		switch f.label {
+		case editorTag:
+			if value == "" {
+				return config, fmt.Errorf("please set an image tag")
+			}
+			config.Tag = value
+		case editorEnvironment:
+			if value == "" {
+				continue
+			}
+			key, v, _ := strings.Cut(value, "=")
+			if key == "" {
+				return config, fmt.Errorf("invalid environment variable %s", value)
+			}
+			config.Environment[key] = v
+		case editorPort:
+			if value != "" {
+				config.Ports = append(config.Ports, value)
+			}
+		}
+	}
+	return config, nil
+}
@@ -0,0 +1,47 @@
+package models
+
+import (
+	"ccmanager/internal/adapters"
+	"context"
+	tea "github.com/charmbracelet/bubbletea"
+	"time"
+)
+
+// eventRetryInterval is the interval in which a failed event stream is subscribed again
+const eventRetryInterval = 30 * time.Second
+
+// eventStream receives the container events of all instances
+type eventStream struct {
+	events <-chan adapters.InstanceEvent
+	errs   <-chan error
+}
+
+func newEventStream(adapter adapters.BaseAdapter) *eventStream {
+	events, errs := adapter.Events(context.Background())
+	return &eventStream{
+		events: events,
+		errs:   errs,
+	}
+}
+
+// Next returns a tea.Cmd that waits for the next event of the stream. Errors of single engines don't end the stream
+func (e *eventStream) Next() tea.Cmd {
+	return func() tea.Msg {
+		select {
+		case event, ok := <-e.events:
+			if ok {
+				return InstanceEventMsg{stream: e, Event: event}
+			}
+			return EventStreamEndedMsg{stream: e, Err: <-e.errs}
+		case err, ok := <-e.errs:
+			if ok && err != nil {
+				return EventStreamFailedMsg{stream: e, Err: err}
+			}
+			// The error channel is closed with the event channel
+			if event, ok := <-e.events; ok {
+				return InstanceEventMsg{stream: e, Event: event}
+			}
+			return EventStreamEndedMsg{stream: e}
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package models
+
+import (
+	"ccmanager/internal/adapters"
+	tea "github.com/charmbracelet/bubbletea"
+	"testing"
+)
+
+// closedEventStream returns a stream that has ended, so waiting for its next event doesn't block
+func closedEventStream() *eventStream {
+	events, errs := make(chan adapters.InstanceEvent), make(chan error)
+	close(events)
+	close(errs)
+	return &eventStream{events: events, errs: errs}
+}
+
+// loadedInstances runs the commands returned by an event handler and returns the instances they load
+func loadedInstances(cmd tea.Cmd) []LoadInstanceMsg {
+	var loaded []LoadInstanceMsg
+	msg := cmd()
+	batch, ok := msg.(tea.BatchMsg)
+	if !ok {
+		batch = tea.BatchMsg{func() tea.Msg { return msg }}
+	}
+	for _, c := range batch {
+		if c == nil {
+			continue
+		}
+		if l, ok := c().(LoadInstanceMsg); ok {
+			loaded = append(loaded, l)
+		}
+	}
+	return loaded
+}
+
+func TestInstanceEventHandler(t *testing.T) {
+	tests := []struct {
+		name  string
+		event adapters.InstanceEvent
+		want  []LoadInstanceMsg
+	}{
+		{
+			name:  "configured base path",
+			event: adapters.InstanceEvent{BasePath: "/cc", Name: "dev", Action: "start"},
+			// The instance is loaded using the base path as configured, so it matches the item in the list
+			want: []LoadInstanceMsg{{BasePath: "/cc/", Name: "dev"}},
+		},
+		{
+			name:  "unclean base path",
+			event: adapters.InstanceEvent{BasePath: "/other", Name: "prod", Action: "die"},
+			want:  []LoadInstanceMsg{{BasePath: "/x/../other", Name: "prod"}},
+		},
+		{
+			name:  "other base path",
+			event: adapters.InstanceEvent{BasePath: "/unrelated", Name: "dev", Action: "start"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			m := MainModel{BasePath: []string{"/cc/", "/x/../other"}, events: closedEventStream()}
+			_, cmd := InstanceEventHandler(m, InstanceEventMsg{stream: m.events, Event: test.event})
+			got := loadedInstances(cmd)
+			if len(got) != len(test.want) || len(got) == 1 && got[0] != test.want[0] {
+				t.Errorf("loaded %+v, want %+v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestInstanceEventHandlerStaleStream(t *testing.T) {
+	m := MainModel{BasePath: []string{"/cc"}, events: closedEventStream()}
+	event := adapters.InstanceEvent{BasePath: "/cc", Name: "dev", Action: "start"}
+	if _, cmd := InstanceEventHandler(m, InstanceEventMsg{stream: closedEventStream(), Event: event}); cmd != nil {
+		t.Error("an event of a replaced stream was handled")
+	}
+}
@@ -0,0 +1,117 @@
+package models
+
+import (
+	"ccmanager/internal"
+	"fmt"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"strings"
+)
+
+// formField is an input field of a Form. Fields with choices are changed using left and right
+type formField struct {
+	// label is shown in front of the field
+	label string
+	// choices holds the possible values of a choice field
+	choices []string
+	// choice is the index of the selected value of a choice field
+	choice int
+	// input is the text input of a text field
+	input textinput.Model
+}
+
+// value returns the value of the field
+func (f formField) value() string {
+	if f.choices != nil {
+		return f.choices[f.choice]
+	}
+	return strings.TrimSpace(f.input.Value())
+}
+
+// newTextField creates a text field with an optional default value
+func newTextField(label string, value string) formField {
+	input := textinput.New()
+	input.Prompt = ""
+	input.SetValue(value)
+	return formField{label: label, input: input}
+}
+
+// Form is a simple list of text and choice fields
+type Form struct {
+	// fields holds the fields of the form
+	fields []formField
+	// focus is the index of the currently focused field
+	focus int
+	// Error holds a validation error shown below the form
+	Error string
+}
+
+// newForm creates a form with the given fields and focuses the first one
+func newForm(fields ...formField) Form {
+	f := Form{fields: fields}
+	f.fields[0].input.Focus()
+	return f
+}
+
+// Update moves the focus between the fields and updates the focused field
+func (f Form) Update(msg tea.KeyMsg) (Form, tea.Cmd) {
+	switch msg.Type {
+	case tea.KeyTab, tea.KeyDown:
+		return f.focusField((f.focus + 1) % len(f.fields))
+	case tea.KeyShiftTab, tea.KeyUp:
+		return f.focusField((f.focus - 1 + len(f.fields)) % len(f.fields))
+	}
+	field := f.fields[f.focus]
+	if field.choices != nil {
+		switch msg.Type {
+		case tea.KeyLeft:
+			field.choice = (field.choice - 1 + len(field.choices)) % len(field.choices)
+		case tea.KeyRight, tea.KeySpace:
+			field.choice = (field.choice + 1) % len(field.choices)
+		}
+		f.fields[f.focus] = field
+		return f, nil
+	}
+	var cmd tea.Cmd
+	field.input, cmd = field.input.Update(msg)
+	f.fields[f.focus] = field
+	return f, cmd
+}
+
+// focusField moves the focus to the field with the given index
+func (f Form) focusField(index int) (Form, tea.Cmd) {
+	f.fields[f.focus].input.Blur()
+	f.focus = index
+	if f.fields[f.focus].choices == nil {
+		return f, f.fields[f.focus].input.Focus()
+	}
+	return f, nil
+}
+
+// View renders the form
+func (f Form) View() string {
+	labelWidth := 0
+	for _, field := range f.fields {
+		if len(field.label) > labelWidth {
+			labelWidth = len(field.label)
+		}
+	}
+	var lines []string
+	for i, field := range f.fields {
+		value := field.input.View()
+		if field.choices != nil {
+			value = fmt.Sprintf("< %s >", field.value())
+		}
+		line := fmt.Sprintf("%-*s  %s", labelWidth, field.label, value)
+		if i == f.focus {
+			line = internal.SelectedItemTitleStyle.Render(line)
+		} else {
+			line = fmt.Sprintf("  %s", line)
+		}
+		lines = append(lines, line)
+	}
+	if f.Error != "" {
+		lines = append(lines, "", internal.ErrorMessageStyle(f.Error))
+	}
+	return strings.Join(lines, "\n")
+}
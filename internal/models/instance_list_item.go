@@ -5,8 +5,10 @@ import (
 	"ccmanager/internal/adapters"
 	"fmt"
 	"github.com/charmbracelet/bubbles/list"
+	"github.com/docker/go-units"
 	"path"
 	"strings"
+	"time"
 )
 
 // InstanceItem is an implementation of list.Item that describes an CloudControl instance
@@ -18,6 +20,22 @@ type InstanceItem struct {
 	Path string
 	// State holds the instance status information in an adapters.CloudControlStatus struct
 	State adapters.CloudControlStatus
+	// Update holds a newer image tag if one is available
+	Update string
+	// Favourite tells whether the instance is a favourite shown at the top of the list
+	Favourite bool
+	// Marked tells whether the instance is marked for bulk actions
+	Marked bool
+	// Progress describes the state of a running bulk action on the instance
+	Progress string
+	// Sessions holds the names of the detachable sessions running in the instance
+	Sessions []string
+	// Loading tells whether the item is a placeholder for an instance whose status hasn't been loaded yet
+	Loading bool
+	// LoadFailed tells whether the status of the instance couldn't be loaded, e.g. because its engine can't be reached
+	LoadFailed bool
+	// keys is the key map used in hints of the state description
+	keys *ApplicationKeyMap
 }
 
 var _ list.Item = InstanceItem{}
@@ -27,7 +45,7 @@ func (i InstanceItem) ID() string {
 	return i.Path + i.Name
 }
 
-// Title shows the flavour and the name of the instance
+// Title shows the flavour and the name of the instance and whether an update is available
 func (i InstanceItem) Title() string {
 	flavour := ""
 	iParts := strings.Split(i.State.Image, "/")
@@ -45,45 +63,91 @@ func (i InstanceItem) Title() string {
 	default:
 		if i.State.Error != nil {
 			flavour = internal.Labels["Err"]
-		} else {
+		} else if !i.Loading {
 			panic(fmt.Sprintf("%s unknown", i.State.Image))
 		}
 	}
-	return fmt.Sprintf("%s %s", i.Name, flavour)
+	title := fmt.Sprintf("%s %s", i.Name, flavour)
+	if i.State.Host != "" {
+		title = fmt.Sprintf("%s %s", title, internal.HostLabelStyle.Render(i.State.Host))
+	}
+	if i.Favourite {
+		title = fmt.Sprintf("★ %s", title)
+	}
+	if i.Marked {
+		title = fmt.Sprintf("✔ %s", title)
+	}
+	if i.Update != "" {
+		title = fmt.Sprintf("%s %s", title, internal.Labels["Update"])
+	}
+	if len(i.Sessions) > 0 {
+		title = fmt.Sprintf("%s %s", title, internal.Labels["Session"])
+	}
+	return title
 }
 
 // Description holds the image, path and state of an instance
 func (i InstanceItem) Description() string {
+	if i.Loading {
+		return fmt.Sprintf(" \n Path: %s \n State: Loading...", path.Join(i.Path, i.Name))
+	}
+	image := fmt.Sprintf("%s:%s", i.State.Image, i.State.Tag)
+	if i.State.Usage != nil {
+		image = fmt.Sprintf("%s | %s", image, formatUsage(*i.State.Usage))
+	}
 	return fmt.Sprintf(
-		" %s:%s \n Path: %s \n State: %s",
-		i.State.Image,
-		i.State.Tag,
+		" %s \n Path: %s \n State: %s",
+		image,
 		path.Join(i.Path, i.Name),
 		i.StateDescription(),
 	)
 }
 
+// Uptime returns the time since the container of a running instance was started
+func (i InstanceItem) Uptime() string {
+	if !i.State.Running || i.State.StartedAt.IsZero() {
+		return "n/a"
+	}
+	return units.HumanDuration(time.Since(i.State.StartedAt))
+}
+
+// formatUsage describes the CPU and memory usage of a container
+func formatUsage(u adapters.ResourceUsage) string {
+	memory := units.BytesSize(float64(u.MemoryUsage))
+	if u.MemoryLimit > 0 {
+		memory = fmt.Sprintf("%s / %s", memory, units.BytesSize(float64(u.MemoryLimit)))
+	}
+	return fmt.Sprintf("CPU %.1f%% | Mem %s", u.CPUPercent, memory)
+}
+
 // StateDescription describes the state of the instance
 func (i InstanceItem) StateDescription() string {
+	keys := i.keys
+	if keys == nil {
+		keys = NewApplicationKeyMap()
+	}
 	var s string
 	if i.State.Error != nil && i.State.CCCStatus != adapters.CCCExited {
 		s = fmt.Sprintf("System error: %s", i.State.Error)
 	} else {
 		switch i.State.CCCStatus {
 		case adapters.CCCDown:
-			s = "Down (use s to start)"
+			s = fmt.Sprintf("Down (use %s to start)", keys.Start.Help().Key)
 		case adapters.CCCInit:
-			s = "Running/Initializing (use c to open CCC)"
+			s = fmt.Sprintf("Running/Initializing (use %s to open CCC)", keys.OpenCCC.Help().Key)
 		case adapters.CCCErr:
 			s = "Running/Error"
 		case adapters.CCCReady:
 			s = "Running"
 		case adapters.CCCExited:
-			s = fmt.Sprintf("Container error: %s (use L to display the logs)", i.State.Error.Error())
+			s = fmt.Sprintf("Container error: %s (use %s to display the logs)", i.State.Error.Error(), keys.ShowLog.Help().Key)
 		default:
 			s = "Invalid"
 		}
 	}
+	if i.Progress != "" {
+		s = fmt.Sprintf("%s (%s)", s, i.Progress)
+	}
 	return s
 }
 
@@ -0,0 +1,157 @@
+package models
+
+import (
+	"ccmanager/internal"
+	"context"
+	"fmt"
+	tea "github.com/charmbracelet/bubbletea"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// startLogStream (re-)starts streaming the log of the instance in MainModel.InfoItem using MainModel.LogOptions. The
+// stream is opened in the background and has to deliver within the log timeout
+func startLogStream(m MainModel) (MainModel, tea.Cmd) {
+	m = closeLogStream(m)
+	m.LogLines = nil
+	m.logMatches = nil
+	m.LogViewer.SetContent("")
+
+	options := m.LogOptions
+	options.Follow = true
+	item := m.InfoItem
+	var openCmd tea.Cmd
+	m.logStream, openCmd = openLogStream(m.Timeouts.Logs, func(ctx context.Context) (io.ReadCloser, error) {
+		return m.Adapter.StreamLogs(ctx, item.Path, item.Name, options)
+	})
+	return m, openCmd
+}
+
+// refreshLogContent updates the search matches and renders the log lines with highlighted matches into the log
+// viewer
+func refreshLogContent(m MainModel) MainModel {
+	query := strings.ToLower(m.LogSearch.Value())
+	m.logMatches = nil
+	if query != "" {
+		for i, line := range m.LogLines {
+			if strings.Contains(strings.ToLower(line), query) {
+				m.logMatches = append(m.logMatches, i)
+			}
+		}
+	}
+	if m.logMatch >= len(m.logMatches) {
+		m.logMatch = len(m.logMatches) - 1
+	}
+	if m.logMatch < 0 {
+		m.logMatch = 0
+	}
+
+	if len(m.logMatches) == 0 {
+		m.LogViewer.SetContent(strings.Join(m.LogLines, "\n"))
+		return m
+	}
+
+	lines := make([]string, len(m.LogLines))
+	copy(lines, m.LogLines)
+	for i, lineIndex := range m.logMatches {
+		style := internal.LogMatchStyle
+		if i == m.logMatch {
+			style = internal.LogCurrentMatchStyle
+		}
+		lines[lineIndex] = highlight(lines[lineIndex], query, style.Render)
+	}
+	m.LogViewer.SetContent(strings.Join(lines, "\n"))
+	return m
+}
+
+// highlight renders all case-insensitive occurrences of query in line using render
+func highlight(line string, query string, render func(...string) string) string {
+	var b strings.Builder
+	lower := strings.ToLower(line)
+	if len(lower) != len(line) {
+		// Lowercasing changed the byte offsets, so the whole line is highlighted instead
+		return render(line)
+	}
+	for {
+		i := strings.Index(lower, query)
+		if i < 0 {
+			b.WriteString(line)
+			return b.String()
+		}
+		b.WriteString(line[:i])
+		b.WriteString(render(line[i : i+len(query)]))
+		line = line[i+len(query):]
+		lower = lower[i+len(query):]
+	}
+}
+
+// jumpToLogMatch selects the match with the given index in the list of matches and scrolls to it. The index
+// wraps around at both ends of the list. Following the log is paused
+func jumpToLogMatch(m MainModel, index int) MainModel {
+	if len(m.logMatches) == 0 {
+		return m
+	}
+	m.logMatch = (index + len(m.logMatches)) % len(m.logMatches)
+	m.FollowLog = false
+	m = refreshLogContent(m)
+	m.LogViewer.SetYOffset(m.logMatches[m.logMatch] - m.LogViewer.Height/2)
+	return m
+}
+
+// firstVisibleLogMatch returns the index of the first match at or below the top of the log viewer
+func firstVisibleLogMatch(m MainModel) int {
+	for i, lineIndex := range m.logMatches {
+		if lineIndex >= m.LogViewer.YOffset {
+			return i
+		}
+	}
+	return 0
+}
+
+// cycleLogService switches the log screen to the log of the next service of the instance. After the last service,
+// the log of all services is shown again
+func cycleLogService(m MainModel) (MainModel, tea.Cmd) {
+	if len(m.LogServices) == 0 {
+		return m, nil
+	}
+	next := 0
+	if len(m.LogOptions.Services) == 1 {
+		for i, service := range m.LogServices {
+			if service == m.LogOptions.Services[0] {
+				next = i + 1
+			}
+		}
+	}
+	if next < len(m.LogServices) {
+		m.LogOptions.Services = []string{m.LogServices[next]}
+	} else {
+		m.LogOptions.Services = nil
+	}
+	return startLogStream(m)
+}
+
+// logServiceDescription describes the services shown in the log screen
+func logServiceDescription(m MainModel) string {
+	if len(m.LogOptions.Services) == 0 {
+		return "all services"
+	}
+	return fmt.Sprintf("service %s", strings.Join(m.LogOptions.Services, ", "))
+}
+
+// saveLog writes the current log buffer into a file in the instance directory
+func saveLog(m MainModel) MainModel {
+	fileName := filepath.Join(
+		m.InfoItem.Path,
+		m.InfoItem.Name,
+		fmt.Sprintf("ccmanager-%s.log", time.Now().Format("20060102-150405")),
+	)
+	if err := os.WriteFile(fileName, []byte(strings.Join(m.LogLines, "\n")+"\n"), 0600); err != nil {
+		m.LogStatus = internal.ErrorMessageStyle(fmt.Sprintf("Can not save log: %s", err.Error()))
+	} else {
+		m.LogStatus = fmt.Sprintf("Saved log to %s", fileName)
+	}
+	return m
+}
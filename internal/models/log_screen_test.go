@@ -0,0 +1,116 @@
+package models
+
+import (
+	"github.com/charmbracelet/bubbles/textinput"
+	"github.com/charmbracelet/bubbles/viewport"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// brackets renders highlighted text in brackets
+func brackets(s ...string) string {
+	return "[" + strings.Join(s, "") + "]"
+}
+
+func TestHighlight(t *testing.T) {
+	tests := []struct {
+		name  string
+		line  string
+		query string
+		want  string
+	}{
+		{"single match", "starting cli", "cli", "starting [cli]"},
+		{"case insensitive", "Error: ERROR", "error", "[Error]: [ERROR]"},
+		{"adjacent matches", "aaaa", "aa", "[aa][aa]"},
+		{"no match", "ready", "error", "ready"},
+		{"multibyte", "größe: Größe", "größe", "[größe]: [Größe]"},
+		// Lowercasing İ changes its length, so the offsets can't be used
+		{"changed length", "İstanbul error", "error", "[İstanbul error]"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := highlight(test.line, test.query, brackets); got != test.want {
+				t.Errorf("highlight(%q, %q) = %q, want %q", test.line, test.query, got, test.want)
+			}
+		})
+	}
+}
+
+// newLogTestModel returns a model showing log lines searched for query in a viewer of the given height
+func newLogTestModel(lines []string, query string, height int) MainModel {
+	m := MainModel{
+		LogLines:  lines,
+		LogViewer: viewport.New(80, height),
+		LogSearch: textinput.New(),
+	}
+	m.LogSearch.SetValue(query)
+	return refreshLogContent(m)
+}
+
+func TestRefreshLogContent(t *testing.T) {
+	tests := []struct {
+		name    string
+		query   string
+		match   int
+		want    []int
+		wantSel int
+	}{
+		{"no query", "", 0, nil, 0},
+		{"matches", "ERROR", 0, []int{1, 3}, 0},
+		{"selection kept", "error", 1, []int{1, 3}, 1},
+		{"selection clamped", "error", 5, []int{1, 3}, 1},
+		{"no matches", "missing", 1, nil, 0},
+	}
+	lines := []string{"start", "error one", "ok", "Error two"}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			m := newLogTestModel(lines, test.query, 10)
+			m.logMatch = test.match
+			m = refreshLogContent(m)
+			if !slices.Equal(m.logMatches, test.want) || m.logMatch != test.wantSel {
+				t.Errorf("matches %v with %d selected, want %v with %d selected", m.logMatches, m.logMatch, test.want,
+					test.wantSel)
+			}
+		})
+	}
+}
+
+func TestJumpToLogMatch(t *testing.T) {
+	var lines []string
+	for i := 0; i < 100; i++ {
+		line := "info"
+		if i%30 == 0 {
+			line = "error"
+		}
+		lines = append(lines, line)
+	}
+	tests := []struct {
+		name   string
+		index  int
+		want   int
+		offset int
+	}{
+		{"first", 0, 0, 0},
+		{"middle", 2, 2, 55},
+		{"wraps after the last", 4, 0, 0},
+		{"wraps before the first", -1, 3, 85},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			m := newLogTestModel(lines, "error", 10)
+			m.FollowLog = true
+			m = jumpToLogMatch(m, test.index)
+			if m.logMatch != test.want {
+				t.Errorf("selected match %d, want %d", m.logMatch, test.want)
+			}
+			// The match is scrolled to the middle of the viewer as far as the log allows
+			if m.LogViewer.YOffset != test.offset {
+				t.Errorf("scrolled to line %d, want %d", m.LogViewer.YOffset, test.offset)
+			}
+			if m.FollowLog {
+				t.Error("following the log wasn't paused")
+			}
+		})
+	}
+}
@@ -0,0 +1,103 @@
+package models
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	tea "github.com/charmbracelet/bubbletea"
+	"io"
+	"strings"
+	"time"
+)
+
+// maxLogLinesPerRead limits the number of lines delivered in one LogLinesMsg
+const maxLogLinesPerRead = 500
+
+// maxLogLines limits the number of lines kept in the log viewer
+const maxLogLines = 10000
+
+// logStream reads the log of an instance line by line
+type logStream struct {
+	// cancel stops opening and reading the stream
+	cancel context.CancelFunc
+	// source is the opened log. It is nil until the LogStreamOpenedMsg has been handled
+	source io.ReadCloser
+	reader *bufio.Reader
+}
+
+// openLogStream returns a stream and a tea.Cmd opening it using streamLogs in the background. Opening fails if it
+// takes longer than timeout
+func openLogStream(timeout time.Duration, streamLogs func(ctx context.Context) (io.ReadCloser, error)) (*logStream, tea.Cmd) {
+	ctx, cancel := context.WithCancel(context.Background())
+	l := &logStream{cancel: cancel}
+	return l, func() tea.Msg {
+		type result struct {
+			source io.ReadCloser
+			err    error
+		}
+		opened := make(chan result, 1)
+		go func() {
+			source, err := streamLogs(ctx)
+			opened <- result{source: source, err: err}
+		}()
+		select {
+		case r := <-opened:
+			if r.err != nil {
+				return LogStreamEndedMsg{stream: l, Err: fmt.Errorf("can not get logs: %w", r.err)}
+			}
+			return LogStreamOpenedMsg{stream: l, source: r.source}
+		case <-time.After(timeout):
+			cancel()
+			go func() {
+				if r := <-opened; r.err == nil {
+					_ = r.source.Close()
+				}
+			}()
+			return LogStreamEndedMsg{stream: l, Err: fmt.Errorf("can not get logs within %s", timeout)}
+		}
+	}
+}
+
+// LogStreamOpenedMsg is sent when a log stream has been opened
+type LogStreamOpenedMsg struct {
+	stream *logStream
+	source io.ReadCloser
+}
+
+// opened starts reading the opened log
+func (l *logStream) opened(source io.ReadCloser) {
+	l.source = source
+	l.reader = bufio.NewReader(source)
+}
+
+// Next returns a tea.Cmd that waits for the next lines of the stream. All lines that are already available are
+// delivered in one LogLinesMsg
+func (l *logStream) Next() tea.Cmd {
+	return func() tea.Msg {
+		var lines []string
+		for {
+			line, err := l.reader.ReadString('\n')
+			if line != "" {
+				lines = append(lines, strings.TrimRight(line, "\r\n"))
+			}
+			if err != nil {
+				if len(lines) > 0 {
+					return LogLinesMsg{stream: l, Lines: lines}
+				}
+				return LogStreamEndedMsg{stream: l, Err: err}
+			}
+			if l.reader.Buffered() == 0 || len(lines) >= maxLogLinesPerRead {
+				return LogLinesMsg{stream: l, Lines: lines}
+			}
+		}
+	}
+}
+
+// Close stops the stream
+func (l *logStream) Close() error {
+	l.cancel()
+	if l.source == nil {
+		return nil
+	}
+	return l.source.Close()
+}
@@ -0,0 +1,64 @@
+package models
+
+import (
+	"context"
+	"io"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// closeRecorder is a log source remembering whether it has been closed
+type closeRecorder struct {
+	io.Reader
+	closed atomic.Bool
+}
+
+func (c *closeRecorder) Close() error {
+	c.closed.Store(true)
+	return nil
+}
+
+func TestOpenLogStream(t *testing.T) {
+	source := &closeRecorder{Reader: strings.NewReader("first\nsecond\n")}
+	stream, open := openLogStream(time.Second, func(context.Context) (io.ReadCloser, error) {
+		return source, nil
+	})
+	m := MainModel{logStream: stream}
+	m, next := LogStreamOpenedHandler(m, open().(LogStreamOpenedMsg))
+	msg, ok := next().(LogLinesMsg)
+	if !ok || strings.Join(msg.Lines, ",") != "first,second" {
+		t.Errorf("got %#v, want the lines of the log", msg)
+	}
+
+	// A stream opened after the log screen switched to another stream is closed
+	_, open = openLogStream(time.Second, func(context.Context) (io.ReadCloser, error) {
+		return source, nil
+	})
+	LogStreamOpenedHandler(m, open().(LogStreamOpenedMsg))
+	if !source.closed.Load() {
+		t.Error("the stale stream wasn't closed")
+	}
+}
+
+func TestOpenLogStreamTimeout(t *testing.T) {
+	release := make(chan struct{})
+	source := &closeRecorder{Reader: strings.NewReader("")}
+	_, open := openLogStream(10*time.Millisecond, func(ctx context.Context) (io.ReadCloser, error) {
+		<-release
+		return source, nil
+	})
+	msg, ok := open().(LogStreamEndedMsg)
+	if !ok || msg.Err == nil {
+		t.Fatalf("got %#v, want a timeout", msg)
+	}
+	close(release)
+	deadline := time.Now().Add(5 * time.Second)
+	for !source.closed.Load() && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if !source.closed.Load() {
+		t.Error("the log opened after the timeout wasn't closed")
+	}
+}
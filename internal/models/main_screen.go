@@ -3,12 +3,20 @@ package models
 import (
 	"ccmanager/internal"
 	"ccmanager/internal/adapters"
+	"ccmanager/internal/config"
+	"ccmanager/internal/versions"
+	"context"
+	"fmt"
 	"github.com/charmbracelet/bubbles/key"
 	"github.com/charmbracelet/bubbles/list"
 	"github.com/charmbracelet/bubbles/spinner"
+	"github.com/charmbracelet/bubbles/textinput"
 	"github.com/charmbracelet/bubbles/viewport"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
+	funk "github.com/thoas/go-funk"
+	"sort"
+	"strings"
 	"time"
 )
 
@@ -38,6 +46,56 @@ type ApplicationKeyMap struct {
 	Info key.Binding
 	// Start starts an instance
 	Start key.Binding
+	// Cancel cancels a running start or stop action
+	Cancel key.Binding
+	// FollowLog toggles following new lines in the log screen
+	FollowLog key.Binding
+	// SearchLog starts searching in the log screen
+	SearchLog key.Binding
+	// NextMatch jumps to the next search match in the log screen
+	NextMatch key.Binding
+	// PreviousMatch jumps to the previous search match in the log screen
+	PreviousMatch key.Binding
+	// LogService switches the log screen to the next service of the instance
+	LogService key.Binding
+	// SaveLog saves the log buffer into the instance directory
+	SaveLog key.Binding
+	// NewInstance opens the wizard to create a new instance
+	NewInstance key.Binding
+	// Edit opens the editor for the configuration of an instance
+	Edit key.Binding
+	// Upgrade upgrades an instance to the newest image tag
+	Upgrade key.Binding
+	// Mark marks an instance for bulk actions
+	Mark key.Binding
+	// MarkAll marks all instances matching the filter for bulk actions
+	MarkAll key.Binding
+	// NextGroup filters the instance list by the next group
+	NextGroup key.Binding
+	// SwitchWorkspace stops all instances outside of the current group and starts the instances of the group
+	SwitchWorkspace key.Binding
+	// Dashboard shows the resource dashboard of all running instances
+	Dashboard key.Binding
+	// SortDashboard sorts the dashboard by the next column
+	SortDashboard key.Binding
+	// ReverseSort reverses the sort order of the dashboard
+	ReverseSort key.Binding
+	// OpenShell opens a shell in an instance
+	OpenShell key.Binding
+	// RunCommand opens the prompt for a command to run in an instance
+	RunCommand key.Binding
+	// Recordings shows the session recordings of an instance
+	Recordings key.Binding
+	// FocusSession moves the focus between the instance list and the terminal tabs
+	FocusSession key.Binding
+	// NextSession shows the next terminal tab
+	NextSession key.Binding
+	// PreviousSession shows the previous terminal tab
+	PreviousSession key.Binding
+	// DetachSession detaches from the detachable session of the focused terminal tab
+	DetachSession key.Binding
+	// Attach attaches to a detachable session of an instance
+	Attach key.Binding
 }
 
 func NewApplicationKeyMap() *ApplicationKeyMap {
@@ -90,16 +148,241 @@ func NewApplicationKeyMap() *ApplicationKeyMap {
 			key.WithKeys("s"),
 			key.WithHelp("s", "start"),
 		),
+		Cancel: key.NewBinding(
+			key.WithKeys("x"),
+			key.WithHelp("x", "cancel action"),
+		),
+		FollowLog: key.NewBinding(
+			key.WithKeys("f"),
+			key.WithHelp("f", "follow log"),
+		),
+		SearchLog: key.NewBinding(
+			key.WithKeys("/"),
+			key.WithHelp("/", "search"),
+		),
+		NextMatch: key.NewBinding(
+			key.WithKeys("n"),
+			key.WithHelp("n", "next match"),
+		),
+		PreviousMatch: key.NewBinding(
+			key.WithKeys("N"),
+			key.WithHelp("N", "previous match"),
+		),
+		LogService: key.NewBinding(
+			key.WithKeys("tab"),
+			key.WithHelp("tab", "switch service"),
+		),
+		SaveLog: key.NewBinding(
+			key.WithKeys("w"),
+			key.WithHelp("w", "save log"),
+		),
+		NewInstance: key.NewBinding(
+			key.WithKeys("a"),
+			key.WithHelp("a", "new instance"),
+		),
+		Edit: key.NewBinding(
+			key.WithKeys("e"),
+			key.WithHelp("e", "edit"),
+		),
+		Upgrade: key.NewBinding(
+			key.WithKeys("U"),
+			key.WithHelp("U", "upgrade"),
+		),
+		Mark: key.NewBinding(
+			key.WithKeys(" "),
+			key.WithHelp("space", "mark"),
+		),
+		MarkAll: key.NewBinding(
+			key.WithKeys("*"),
+			key.WithHelp("*", "mark all"),
+		),
+		NextGroup: key.NewBinding(
+			key.WithKeys("tab"),
+			key.WithHelp("tab", "next group"),
+		),
+		SwitchWorkspace: key.NewBinding(
+			key.WithKeys("W"),
+			key.WithHelp("W", "switch workspace"),
+		),
+		Dashboard: key.NewBinding(
+			key.WithKeys("D"),
+			key.WithHelp("D", "dashboard"),
+		),
+		SortDashboard: key.NewBinding(
+			key.WithKeys("tab"),
+			key.WithHelp("tab", "sort"),
+		),
+		ReverseSort: key.NewBinding(
+			key.WithKeys("r"),
+			key.WithHelp("r", "reverse sort"),
+		),
+		OpenShell: key.NewBinding(
+			key.WithKeys("o"),
+			key.WithHelp("o", "open shell"),
+		),
+		RunCommand: key.NewBinding(
+			key.WithKeys(":"),
+			key.WithHelp(":", "run command..."),
+		),
+		Recordings: key.NewBinding(
+			key.WithKeys("p"),
+			key.WithHelp("p", "recordings"),
+		),
+		FocusSession: key.NewBinding(
+			key.WithKeys("ctrl+o"),
+			key.WithHelp("ctrl+o", "focus session"),
+		),
+		NextSession: key.NewBinding(
+			key.WithKeys("ctrl+pgdown"),
+			key.WithHelp("ctrl+pgdown", "next session"),
+		),
+		PreviousSession: key.NewBinding(
+			key.WithKeys("ctrl+pgup"),
+			key.WithHelp("ctrl+pgup", "previous session"),
+		),
+		DetachSession: key.NewBinding(
+			key.WithKeys("ctrl+]"),
+			key.WithHelp("ctrl+]", "detach session"),
+		),
+		Attach: key.NewBinding(
+			key.WithKeys("A"),
+			key.WithHelp("A", "attach session"),
+		),
+	}
+}
+
+// bindings returns the key bindings by the names used in the keyBindings section of the configuration file
+func (k *ApplicationKeyMap) bindings() map[string]*key.Binding {
+	return map[string]*key.Binding{
+		"toggleTitleBar":   &k.ToggleTitleBar,
+		"toggleStatusBar":  &k.ToggleStatusBar,
+		"togglePagination": &k.TogglePagination,
+		"toggleHelpMenu":   &k.ToggleHelpMenu,
+		"refresh":          &k.Refresh,
+		"run":              &k.Run,
+		"restart":          &k.Restart,
+		"openCCC":          &k.OpenCCC,
+		"stop":             &k.Stop,
+		"showLog":          &k.ShowLog,
+		"info":             &k.Info,
+		"start":            &k.Start,
+		"cancel":           &k.Cancel,
+		"followLog":        &k.FollowLog,
+		"searchLog":        &k.SearchLog,
+		"nextMatch":        &k.NextMatch,
+		"previousMatch":    &k.PreviousMatch,
+		"logService":       &k.LogService,
+		"saveLog":          &k.SaveLog,
+		"newInstance":      &k.NewInstance,
+		"edit":             &k.Edit,
+		"upgrade":          &k.Upgrade,
+		"mark":             &k.Mark,
+		"markAll":          &k.MarkAll,
+		"nextGroup":        &k.NextGroup,
+		"switchWorkspace":  &k.SwitchWorkspace,
+		"dashboard":        &k.Dashboard,
+		"sortDashboard":    &k.SortDashboard,
+		"reverseSort":      &k.ReverseSort,
+		"openShell":        &k.OpenShell,
+		"runCommand":       &k.RunCommand,
+		"recordings":       &k.Recordings,
+		"focusSession":     &k.FocusSession,
+		"nextSession":      &k.NextSession,
+		"previousSession":  &k.PreviousSession,
+		"detachSession":    &k.DetachSession,
+		"attach":           &k.Attach,
 	}
 }
 
-// A RefreshableItem holds the information about an instance that is refreshed constantly while initializing/
-// stopping or exiting
-type RefreshableItem struct {
-	index int
-	item  InstanceItem
+// screenBindings holds the names of the key bindings only used in the log screen and the dashboard by screen. All
+// other bindings are used in the instance list
+var screenBindings = map[string][]string{
+	"log":       {"followLog", "searchLog", "nextMatch", "previousMatch", "logService", "saveLog"},
+	"dashboard": {"sortDashboard", "reverseSort"},
+}
+
+// screenNames holds the names of the screens used in error messages
+var screenNames = map[string]string{
+	"list":      "instance list",
+	"log":       "log screen",
+	"dashboard": "dashboard",
+}
+
+// newListKeyMap returns the key map of the instance list. "d" is removed from the next page keys because it stops
+// instances
+func newListKeyMap() list.KeyMap {
+	k := list.DefaultKeyMap()
+	k.NextPage.SetKeys("right", "l", "pgdown", "f")
+	k.ForceQuit.SetHelp("ctrl+c", "force quit")
+	return k
+}
+
+// reservedKeys returns the keys of the instance list and the keys closing the log screen and the dashboard by screen.
+// They can't be used by other key bindings of the same screen
+func (k *ApplicationKeyMap) reservedKeys() map[string][]key.Binding {
+	l := newListKeyMap()
+	return map[string][]key.Binding{
+		"list": {
+			l.CursorUp, l.CursorDown, l.PrevPage, l.NextPage, l.GoToStart, l.GoToEnd, l.Filter, l.ClearFilter,
+			l.ShowFullHelp, l.Quit, l.ForceQuit,
+		},
+		"log":       {l.Quit},
+		"dashboard": {l.Quit, k.Dashboard},
+	}
 }
 
+// Apply remaps the key bindings using a map of binding names to keys. An empty list of keys disables a binding.
+// An error is returned for unknown binding names and if a key is used by multiple bindings of the same screen or by
+// the instance list itself (e.g. to move the cursor)
+func (k *ApplicationKeyMap) Apply(keyBindings map[string][]string) error {
+	bindings := k.bindings()
+	for name, keys := range keyBindings {
+		b, ok := bindings[name]
+		if !ok {
+			return fmt.Errorf("unknown key binding %s", name)
+		}
+		b.SetKeys(keys...)
+		b.SetHelp(strings.Join(keys, "/"), b.Help().Desc)
+		b.SetEnabled(len(keys) > 0)
+	}
+
+	var names []string
+	for name := range bindings {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	reservedKeys := k.reservedKeys()
+	usedKeys := map[string]map[string]string{}
+	for _, name := range names {
+		screen := "list"
+		for s, screenNames := range screenBindings {
+			if funk.ContainsString(screenNames, name) {
+				screen = s
+			}
+		}
+		if usedKeys[screen] == nil {
+			usedKeys[screen] = map[string]string{}
+		}
+		used := usedKeys[screen]
+		for _, keyName := range bindings[name].Keys() {
+			if other, ok := used[keyName]; ok {
+				return fmt.Errorf("key %s is used by the key bindings %s and %s", keyName, other, name)
+			}
+			for _, reserved := range reservedKeys[screen] {
+				if funk.ContainsString(reserved.Keys(), keyName) {
+					return fmt.Errorf("key %s of the key binding %s is used for %s in the %s", keyName, name,
+						reserved.Help().Desc, screenNames[screen])
+				}
+			}
+			used[keyName] = name
+		}
+	}
+	return nil
+}
+
+// listTitle is the title of the instance list
+const listTitle = "CloudControl instance manager"
+
 var _ tea.Model = MainModel{}
 
 // MainModel is the tea.Model of the main instance list
@@ -114,12 +397,18 @@ type MainModel struct {
 	keys *ApplicationKeyMap
 	// BasePath is a list of CloudControl instance base paths
 	BasePath []string
+	// Config is the effective configuration
+	Config config.Config
 	// Adapter is the adapter used to connect to CloudControl instances
 	Adapter adapters.BaseAdapter
+	// Checker checks for newer image tags of the instances. Updates aren't checked if it is nil
+	Checker *versions.Checker
 	// ShowInfo tells whether the info screen is shown
 	ShowInfo bool
 	// InfoItem holds the instance currently used in ShowInfo or ShowLog
 	InfoItem InstanceItem
+	// InfoError holds an error that occurred when loading the resource usage shown in the info screen
+	InfoError string
 	// Width is the width of the screen
 	Width int
 	// Height is the height of the screen
@@ -132,22 +421,95 @@ type MainModel struct {
 	Confirm list.Model
 	// ConfirmPrompt holds the prompt for the confirmation action
 	ConfirmPrompt string
-	// ItemsToRefresh is a list of items that need constant refreshing because they are currently
-	// starting, initializing, invalid or stopping
-	ItemsToRefresh []RefreshableItem
+	// events is the stream of container events. Instances are polled if it is nil
+	events *eventStream
 	// ShowLog tells whether the log screen is shown
 	ShowLog bool
+	// ShowDashboard tells whether the resource dashboard is shown
+	ShowDashboard bool
+	// dashboard holds the state of the resource dashboard
+	dashboard *dashboard
 	// LogViewer is the viewport.Model used for log screens
 	LogViewer viewport.Model
+	// LogLines holds the lines of the log currently shown
+	LogLines []string
+	// FollowLog tells whether the log screen scrolls to new lines automatically
+	FollowLog bool
+	// logStream is the stream of the log currently shown
+	logStream *logStream
+	// LogOptions holds the options used to stream the log in the log screen
+	LogOptions adapters.LogOptions
+	// LogServices holds the services of the instance whose log is shown
+	LogServices []string
+	// LogSearch is the input used to search in the log screen
+	LogSearch textinput.Model
+	// LogSearching tells whether the search input of the log screen is active
+	LogSearching bool
+	// logMatches holds the indices of the log lines matching the search
+	logMatches []int
+	// logMatch is the index of the currently selected match in logMatches
+	logMatch int
+	// LogStatus holds a message shown in the status line of the log screen
+	LogStatus string
+	// ShowWizard tells whether the new instance wizard is shown
+	ShowWizard bool
+	// Wizard is the form used to create new instances
+	Wizard NewInstanceWizard
+	// ShowEditor tells whether the instance editor is shown
+	ShowEditor bool
+	// Editor is the form used to edit the configuration of an instance
+	Editor InstanceEditor
+	// ShowPrompt tells whether the command prompt is shown
+	ShowPrompt bool
+	// Prompt is the form used to enter a command to run in an instance
+	Prompt CommandPrompt
+	// History holds the commands run using the command prompt, the newest one last
+	History []string
+	// ShowRecordings tells whether the recording list is shown
+	ShowRecordings bool
+	// Recordings is the list of the session recordings of InfoItem
+	Recordings list.Model
+	// sessions holds the exec sessions shown as terminal tabs next to the instance list
+	sessions []*terminalSession
+	// activeSession is the index of the terminal tab currently shown
+	activeSession int
+	// SessionFocused tells whether key presses are sent to the shown terminal tab instead of the instance list
+	SessionFocused bool
+	// Marked holds the IDs of the instances marked for bulk actions
+	Marked map[string]bool
+	// Progress holds the progress of the running bulk action by instance ID
+	Progress map[string]string
+	// forwards holds the forwarded ports of instances on remote machines by instance ID
+	forwards map[string]*portForward
+	// pendingForwards holds the IDs of instances whose ports are being forwarded. The value tells whether the CCC is
+	// opened afterwards
+	pendingForwards map[string]bool
+	// Group is the group the instance list is filtered by. All instances are shown if it is empty
+	Group string
+	// bulk is the bulk action currently running
+	bulk *bulkRun
+	// loader loads the statuses of the instances in the background
+	loader *statusLoader
+	// TemplateDir is the directory holding templates that override the embedded instance templates
+	TemplateDir string
+	// Timeouts holds the timeouts of the adapter calls
+	Timeouts adapters.Timeouts
+	// cancelAction cancels the currently running start or stop action
+	cancelAction context.CancelFunc
 }
 
 // NewMainModel creates a new model for the main instance list view
-func NewMainModel(adapter adapters.BaseAdapter, basePath []string, items []list.Item) tea.Model {
+func NewMainModel(adapter adapters.BaseAdapter, items []list.Item, c config.Config, checker *versions.Checker) (tea.Model, error) {
 	listKeys := NewApplicationKeyMap()
+	if err := listKeys.Apply(c.KeyBindings); err != nil {
+		return nil, err
+	}
 
 	// Set up the default item controller
 	itemDelegate := list.NewDefaultDelegate()
 	itemDelegate.SetHeight(4)
+	itemDelegate.Styles.NormalTitle = internal.ItemTitleStyle
+	itemDelegate.Styles.NormalDesc = internal.ItemDescriptionStyle
 	itemDelegate.Styles.SelectedTitle = internal.SelectedItemTitleStyle
 	itemDelegate.Styles.SelectedDesc = internal.SelectedItemDescriptionStyle
 	itemDelegate.FullHelpFunc = func() [][]key.Binding {
@@ -163,7 +525,8 @@ func NewMainModel(adapter adapters.BaseAdapter, basePath []string, items []list.
 	// Set up the instance list model
 
 	instanceList := list.New(items, itemDelegate, 0, 0)
-	instanceList.Title = "CloudControl instance manager"
+	instanceList.KeyMap = newListKeyMap()
+	instanceList.Title = listTitle
 	instanceList.Styles.Title = internal.TitleStyle
 	instanceList.StatusMessageLifetime = 10 * time.Second
 	instanceList.AdditionalFullHelpKeys = func() []key.Binding {
@@ -173,6 +536,22 @@ func NewMainModel(adapter adapters.BaseAdapter, basePath []string, items []list.
 			listKeys.TogglePagination,
 			listKeys.ToggleHelpMenu,
 			listKeys.Refresh,
+			listKeys.Cancel,
+			listKeys.NewInstance,
+			listKeys.Edit,
+			listKeys.Upgrade,
+			listKeys.Mark,
+			listKeys.MarkAll,
+			listKeys.NextGroup,
+			listKeys.SwitchWorkspace,
+			listKeys.Dashboard,
+			listKeys.OpenShell,
+			listKeys.RunCommand,
+			listKeys.Recordings,
+			listKeys.FocusSession,
+			listKeys.NextSession,
+			listKeys.PreviousSession,
+			listKeys.Attach,
 		}
 	}
 	instanceList.AdditionalShortHelpKeys = func() []key.Binding {
@@ -195,7 +574,7 @@ func NewMainModel(adapter adapters.BaseAdapter, basePath []string, items []list.
 	confirmDelegate := list.NewDefaultDelegate()
 	confirmDelegate.ShowDescription = false
 	confirmDelegate.Styles.NormalTitle = lipgloss.NewStyle().Padding(0)
-	confirmDelegate.Styles.SelectedTitle = lipgloss.NewStyle().Padding(0).Foreground(lipgloss.Color("#ffffff"))
+	confirmDelegate.Styles.SelectedTitle = internal.SelectedChoiceStyle
 	confirmList := list.New([]list.Item{}, confirmDelegate, 0, 2)
 	confirmList.SetShowFilter(false)
 	confirmList.SetShowHelp(false)
@@ -205,24 +584,62 @@ func NewMainModel(adapter adapters.BaseAdapter, basePath []string, items []list.
 	confirmList.DisableQuitKeybindings()
 	confirmList.SetFilteringEnabled(false)
 
+	// Set up the recording list model
+
+	recordingDelegate := list.NewDefaultDelegate()
+	recordingDelegate.Styles.NormalTitle = internal.ItemTitleStyle
+	recordingDelegate.Styles.NormalDesc = internal.ItemDescriptionStyle
+	recordingDelegate.Styles.SelectedTitle = internal.SelectedItemTitleStyle
+	recordingDelegate.Styles.SelectedDesc = internal.SelectedItemDescriptionStyle
+	recordingList := list.New([]list.Item{}, recordingDelegate, 0, 0)
+	recordingList.Styles.Title = internal.TitleStyle
+	recordingList.SetStatusBarItemName("recording", "recordings")
+	recordingList.DisableQuitKeybindings()
+	recordingList.AdditionalShortHelpKeys = func() []key.Binding {
+		return []key.Binding{
+			key.NewBinding(key.WithKeys("enter"), key.WithHelp("enter", "replay")),
+		}
+	}
+
 	textArea := viewport.New(10, 10)
 
+	logSearch := textinput.New()
+	logSearch.Prompt = "Search: "
+
 	return MainModel{
-		loadedItems: false,
-		Adapter:     adapter,
-		List:        instanceList,
-		spinner:     s,
-		keys:        listKeys,
-		BasePath:    basePath,
-		Confirm:     confirmList,
-		LogViewer:   textArea,
-	}
+		loadedItems:     false,
+		Adapter:         adapter,
+		List:            instanceList,
+		spinner:         s,
+		keys:            listKeys,
+		BasePath:        c.BasePath,
+		Config:          c,
+		Confirm:         confirmList,
+		Recordings:      recordingList,
+		LogViewer:       textArea,
+		Timeouts:        c.Timeouts(),
+		LogSearch:       logSearch,
+		TemplateDir:     c.TemplateDir,
+		Checker:         checker,
+		Marked:          map[string]bool{},
+		Progress:        map[string]string{},
+		forwards:        map[string]*portForward{},
+		pendingForwards: map[string]bool{},
+		History:         loadHistory(c.HistoryFile),
+		loader:          newStatusLoader(c.StatusParallelism, c.ShowResources, c.DetachableSessions),
+	}, nil
+}
+
+// Close releases the resources held by the model after the program has ended, e.g. the tunnels of forwarded ports
+func (m MainModel) Close() {
+	closeAllForwards(m)
 }
 
 func (m MainModel) Init() tea.Cmd {
 	return tea.Batch(
 		m.spinner.Tick,
 		LoadInstances,
-		RefreshTick(),
+		SubscribeEvents,
+		RefreshTick(m.Config.RefreshInterval),
 	)
 }
@@ -0,0 +1,33 @@
+package models
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestApplicationKeyMapApply(t *testing.T) {
+	tests := []struct {
+		bindings map[string][]string
+		err      string
+	}{
+		{bindings: nil},
+		{bindings: map[string][]string{"stop": {"X"}, "info": {"i"}, "showLog": {"v"}}},
+		{bindings: map[string][]string{"followLog": {"r"}}},
+		{bindings: map[string][]string{"unknown": {"x"}}, err: "unknown key binding"},
+		{bindings: map[string][]string{"info": {"s"}}, err: "key s is used by the key bindings"},
+		{bindings: map[string][]string{"upgrade": {"u"}}, err: "used for prev page in the instance list"},
+		{bindings: map[string][]string{"showLog": {"l"}}, err: "used for next page in the instance list"},
+		{bindings: map[string][]string{"edit": {"?"}}, err: "in the instance list"},
+		{bindings: map[string][]string{"saveLog": {"q"}}, err: "used for quit in the log screen"},
+		{bindings: map[string][]string{"reverseSort": {"D"}}, err: "in the dashboard"},
+	}
+	for _, test := range tests {
+		err := NewApplicationKeyMap().Apply(test.bindings)
+		switch {
+		case test.err == "" && err != nil:
+			t.Errorf("Apply(%v) failed: %v", test.bindings, err)
+		case test.err != "" && (err == nil || !strings.Contains(err.Error(), test.err)):
+			t.Errorf("Apply(%v) = %v, want an error containing %q", test.bindings, err, test.err)
+		}
+	}
+}
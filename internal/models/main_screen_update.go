@@ -2,6 +2,7 @@ package models
 
 import (
 	"ccmanager/internal"
+	"context"
 	"github.com/charmbracelet/bubbles/key"
 	"github.com/charmbracelet/bubbles/list"
 	tea "github.com/charmbracelet/bubbletea"
@@ -10,6 +11,36 @@ import (
 // Update holds the main controlling code for the applcation.
 func (m MainModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
+	// Keep the instance list up to date regardless of the screen shown.
+	switch msg := msg.(type) {
+	case LoadInstanceMsg:
+		return LoadInstanceHandler(m, msg.BasePath, msg.Name)
+	case InstanceLoadedMsg:
+		return InstanceLoadedHandler(m, msg)
+	case UsageLoadedMsg:
+		return UsageLoadedHandler(m, msg)
+	case RefreshTickMsg:
+		return RefreshTickHandler(m)
+	case SubscribeEventsMsg:
+		return SubscribeEventsHandler(m)
+	case InstanceEventMsg:
+		return InstanceEventHandler(m, msg)
+	case EventStreamFailedMsg:
+		return EventStreamFailedHandler(m, msg)
+	case EventStreamEndedMsg:
+		return EventStreamEndedHandler(m, msg)
+	case SessionUpdatedMsg:
+		return SessionUpdatedHandler(m, msg)
+	case SessionEndedMsg:
+		return SessionEndedHandler(m, msg)
+	case SessionDetachedMsg:
+		return SessionDetachedHandler(m, msg)
+	case PortsForwardedMsg:
+		return PortsForwardedHandler(m, msg)
+	case LogStreamOpenedMsg:
+		return LogStreamOpenedHandler(m, msg)
+	}
+
 	// If the info screen is shown, only react to a keypress and hide it.
 	if m.ShowInfo {
 		switch msg.(type) {
@@ -19,20 +50,103 @@ func (m MainModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		}
 	}
 
-	// If the log view is shown, only react to the quit keys and update the logviewer model.
+	// If the log view is shown, only react to the log screen keys and update the logviewer model.
 	if m.ShowLog {
 		switch msg := msg.(type) {
 		case tea.KeyMsg:
-			if key.Matches(msg, m.List.KeyMap.Quit) {
-				m.ShowLog = false
-				return m, nil
+			if m.LogSearching {
+				return m.logSearchHandler(msg)
+			}
+			if handled, newModel, cmd := m.logKeyHandler(msg); handled {
+				return newModel, cmd
 			}
+		case LogLinesMsg:
+			return LogLinesHandler(m, msg)
+		case LogStreamEndedMsg:
+			return LogStreamEndedHandler(m, msg)
 		}
 		newLogViewerModel, cmd := m.LogViewer.Update(msg)
 		m.LogViewer = newLogViewerModel
 		return m, cmd
 	}
 
+	// If the dashboard is shown, only react to the dashboard keys and the resource usage streams.
+	if m.ShowDashboard {
+		switch msg := msg.(type) {
+		case tea.KeyMsg:
+			return m.dashboardKeyHandler(msg)
+		case ResourceUsageMsg:
+			return ResourceUsageHandler(m, msg)
+		case UsageStreamEndedMsg:
+			return UsageStreamEndedHandler(m, msg)
+		}
+	}
+
+	// If the recording list is shown, only react to the recording list keys and update the recording list.
+	if m.ShowRecordings {
+		switch msg := msg.(type) {
+		case tea.KeyMsg:
+			return m.recordingsKeyHandler(msg)
+		case tea.WindowSizeMsg, ExecFinishedMsg:
+			break
+		default:
+			newRecordingsModel, cmd := m.Recordings.Update(msg)
+			m.Recordings = newRecordingsModel
+			return m, cmd
+		}
+	}
+
+	// If the new instance wizard is shown, only react to the submit and cancel keys and update the wizard.
+	if m.ShowWizard {
+		switch msg := msg.(type) {
+		case tea.KeyMsg:
+			switch msg.Type {
+			case tea.KeyEnter:
+				return CreateInstanceHandler(m)
+			case tea.KeyEsc:
+				m.ShowWizard = false
+				return m, EnableList
+			}
+			newWizardModel, cmd := m.Wizard.Update(msg)
+			m.Wizard = newWizardModel
+			return m, cmd
+		}
+	}
+
+	// If the instance editor is shown, only react to the save and cancel keys and update the editor.
+	if m.ShowEditor {
+		switch msg := msg.(type) {
+		case tea.KeyMsg:
+			switch msg.Type {
+			case tea.KeyEnter:
+				return SaveConfigHandler(m)
+			case tea.KeyEsc:
+				m.ShowEditor = false
+				return m, EnableList
+			}
+			newEditorModel, cmd := m.Editor.Update(msg)
+			m.Editor.Form = newEditorModel
+			return m, cmd
+		}
+	}
+
+	// If the command prompt is shown, only react to the run and cancel keys and update the prompt.
+	if m.ShowPrompt {
+		switch msg := msg.(type) {
+		case tea.KeyMsg:
+			switch msg.Type {
+			case tea.KeyEnter:
+				return ExecCommandHandler(m)
+			case tea.KeyEsc:
+				m.ShowPrompt = false
+				return m, EnableList
+			}
+			newPromptModel, cmd := m.Prompt.Update(msg)
+			m.Prompt = newPromptModel
+			return m, cmd
+		}
+	}
+
 	// If the confirm view is shown, only react to the selection and update the confirmation model.
 	if m.RunningConfirm {
 		switch msg.(type) {
@@ -54,11 +168,12 @@ func (m MainModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	switch msg := msg.(type) {
 	case tea.WindowSizeMsg:
 		h, v := internal.AppStyle.GetFrameSize()
-		m.List.SetSize(msg.Width-h, msg.Height-v)
+		m.Recordings.SetSize(msg.Width-h, msg.Height-v)
 		m.LogViewer.Width = msg.Width
 		m.LogViewer.Height = msg.Height - 2
 		m.Width = msg.Width
 		m.Height = msg.Height
+		m = layoutSessions(m)
 		m.List.Styles.Title.Width(m.Width - h - 4)
 
 	case tea.KeyMsg:
@@ -66,60 +181,82 @@ func (m MainModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 	case LoadInstancesMsg:
 		return LoadInstancesHandler(m)
-	case LoadInstanceMsg:
-		return LoadInstanceHandler(m, msg.BasePath, msg.Name)
-	case InstancesLoadedMsg:
-		m.loadedItems = true
-		return m, nil
 	case ReloadItemsMsg:
 		for len(m.List.Items()) > 0 {
 			m.List.RemoveItem(0)
 		}
-		m.ItemsToRefresh = nil
 		return m, LoadInstances
 
 	case ShowInfoMsg:
-		m.ShowInfo = true
-		m.InfoItem = m.List.SelectedItem().(InstanceItem)
+		return ShowInfoHandler(m)
+	case ResourcesLoadedMsg:
+		return ResourcesLoadedHandler(m, msg)
 	case OpenCCCMsg:
 		return OpenCCCHandler(m)
 	case RunCloudControlMsg:
 		return m, RunCloudControlHandler(m)
+	case ExecFinishedMsg:
+		return ExecFinishedHandler(m, msg)
+	case OpenShellMsg:
+		return m, OpenShellHandler(m)
+	case OpenSessionMsg:
+		return OpenSessionHandler(m, msg)
+	case AttachMsg:
+		return AttachHandler(m)
+	case SessionsListedMsg:
+		return SessionsListedHandler(m, msg)
+	case RunCommandMsg:
+		return RunCommandHandler(m)
 	case StartMsg:
-		return m, tea.Sequence(DisableList, tea.ClearScreen, StartHandler(m), tea.ClearScreen, EnableList)
+		var ctx context.Context
+		ctx, m.cancelAction = context.WithTimeout(context.Background(), m.Timeouts.Action)
+		return m, tea.Sequence(DisableList, tea.ClearScreen, StartHandler(ctx, m), tea.ClearScreen, EnableList, ActionFinished)
 	case StopMsg:
-		return m, tea.Sequence(DisableList, tea.ClearScreen, StopHandler(m), tea.ClearScreen, EnableList)
+		var ctx context.Context
+		ctx, m.cancelAction = context.WithTimeout(context.Background(), m.Timeouts.Action)
+		return m, tea.Sequence(DisableList, tea.ClearScreen, StopHandler(ctx, m), tea.ClearScreen, EnableList, ActionFinished)
 	case RestartMsg:
-		return m, tea.Sequence(DisableList, tea.ClearScreen, StopHandler(m), StartHandler(m), tea.ClearScreen, EnableList)
+		item := m.List.SelectedItem().(InstanceItem)
+		if msg.Item != nil {
+			item = *msg.Item
+		}
+		var ctx context.Context
+		ctx, m.cancelAction = context.WithTimeout(context.Background(), m.Timeouts.Action)
+		return m, tea.Sequence(DisableList, tea.ClearScreen, stopItem(ctx, m, item), startItem(ctx, m, item), tea.ClearScreen, EnableList, ActionFinished)
+	case ActionFinishedMsg:
+		if m.cancelAction != nil {
+			m.cancelAction()
+			m.cancelAction = nil
+		}
+		return m, nil
 	case ShowLogMsg:
 		return ShowLogHandler(m)
-
-	case RefreshTickMsg:
-		if m.loadedItems {
-			var refreshCmds []tea.Cmd
-			m.ItemsToRefresh = nil
-			for index, item := range m.List.Items() {
-				m.ItemsToRefresh = append(m.ItemsToRefresh, RefreshableItem{
-					index,
-					item.(InstanceItem),
-				})
-			}
-			for _, itemToRefresh := range m.ItemsToRefresh {
-				refreshCmds = append(refreshCmds, func(i RefreshableItem) tea.Cmd {
-					return func() tea.Msg {
-						return LoadInstanceMsg{
-							BasePath: i.item.Path,
-							Name:     i.item.Name,
-						}
-					}
-				}(itemToRefresh))
-			}
-
-			return m, tea.Sequence(
-				tea.Batch(refreshCmds...),
-				RefreshTick(),
-			)
-		}
+	case ShowDashboardMsg:
+		return ShowDashboardHandler(m)
+	case ShowRecordingsMsg:
+		return ShowRecordingsHandler(m)
+	case NewInstanceMsg:
+		return NewInstanceHandler(m)
+	case EditMsg:
+		return EditHandler(m)
+	case UpdateCheckedMsg:
+		return UpdateCheckedHandler(m, msg)
+	case UpgradeMsg:
+		return UpgradeHandler(m)
+	case UpgradeConfirmedMsg:
+		return UpgradeConfirmedHandler(m, msg)
+	case NextGroupMsg:
+		return NextGroupHandler(m)
+	case SwitchWorkspaceMsg:
+		return SwitchWorkspaceHandler(m)
+	case WorkspacePlannedMsg:
+		return WorkspacePlannedHandler(m, msg)
+	case BulkMsg:
+		return BulkHandler(m, msg)
+	case BulkConfirmedMsg:
+		return BulkConfirmedHandler(m, msg)
+	case BulkJobFinishedMsg:
+		return BulkJobFinishedHandler(m, msg)
 
 	case EnableListMsg:
 		m.ListDisabled = false
@@ -162,10 +299,42 @@ func (m MainModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 // The keyHandler reacts to key presses while the main screen is shown.
 func (m MainModel) keyHandler(msg tea.KeyMsg) (MainModel, tea.Cmd) {
+	if m.SessionFocused {
+		return m.sessionKeyHandler(msg)
+	}
 	switch {
 	case m.List.FilterState() == list.Filtering:
 		break
 
+	case key.Matches(msg, m.keys.FocusSession):
+		m.SessionFocused = len(m.sessions) > 0
+		return m, nil
+	case key.Matches(msg, m.keys.NextSession):
+		return selectSession(m, m.activeSession+1), nil
+	case key.Matches(msg, m.keys.PreviousSession):
+		return selectSession(m, m.activeSession-1), nil
+
+	case key.Matches(msg, m.keys.Cancel):
+		if m.cancelAction != nil {
+			m.cancelAction()
+			return m, m.List.NewStatusMessage(internal.ErrorMessageStyle("Action cancelled"))
+		}
+		if m.bulk != nil {
+			return cancelBulk(m)
+		}
+		return m, nil
+
+	case key.Matches(msg, m.keys.Mark):
+		return toggleMark(m)
+	case key.Matches(msg, m.keys.MarkAll):
+		return toggleMarkAll(m)
+	case key.Matches(msg, m.keys.NextGroup):
+		return m, NextGroup
+	case key.Matches(msg, m.keys.Dashboard):
+		return m, ShowDashboard
+	case key.Matches(msg, m.keys.SwitchWorkspace):
+		return m, SwitchWorkspace
+
 	case key.Matches(msg, m.keys.ToggleTitleBar):
 		v := !m.List.ShowTitle()
 		m.List.SetShowTitle(v)
@@ -191,16 +360,42 @@ func (m MainModel) keyHandler(msg tea.KeyMsg) (MainModel, tea.Cmd) {
 		return m, RunCloudControl
 	case key.Matches(msg, m.keys.Info):
 		return m, ShowInfo
+	case key.Matches(msg, m.keys.OpenShell):
+		return m, OpenShell
+	case key.Matches(msg, m.keys.RunCommand):
+		return m, RunCommand
+	case key.Matches(msg, m.keys.Recordings):
+		return m, ShowRecordings
+	case key.Matches(msg, m.keys.Attach):
+		return m, Attach
 	case key.Matches(msg, m.keys.OpenCCC):
 		return m, OpenCCC
 	case key.Matches(msg, m.keys.Stop):
+		if len(markedItems(m)) > 0 {
+			return m, Bulk(bulkStop)
+		}
 		return m, Stop
 	case key.Matches(msg, m.keys.Start):
+		if len(markedItems(m)) > 0 {
+			return m, Bulk(bulkStart)
+		}
 		return m, Start
 	case key.Matches(msg, m.keys.Restart):
+		if len(markedItems(m)) > 0 {
+			return m, Bulk(bulkRestart)
+		}
 		return m, Restart
 	case key.Matches(msg, m.keys.ShowLog):
 		return m, ShowLog
+	case key.Matches(msg, m.keys.NewInstance):
+		return m, NewInstance
+	case key.Matches(msg, m.keys.Edit):
+		return m, Edit
+	case key.Matches(msg, m.keys.Upgrade):
+		if len(markedItems(m)) > 0 {
+			return m, Bulk(bulkUpgrade)
+		}
+		return m, Upgrade
 	}
 	if m.ShowLog {
 		newLogViewerModel, cmd := m.LogViewer.Update(msg)
@@ -212,3 +407,115 @@ func (m MainModel) keyHandler(msg tea.KeyMsg) (MainModel, tea.Cmd) {
 		return m, cmd
 	}
 }
+
+// The sessionKeyHandler sends key presses to the shown terminal tab while it is focused. Only the keys moving the
+// focus, switching tabs and detaching are handled by CCmanager. Any key closes the tab of an ended session.
+func (m MainModel) sessionKeyHandler(msg tea.KeyMsg) (MainModel, tea.Cmd) {
+	switch {
+	case key.Matches(msg, m.keys.DetachSession) && !m.sessions[m.activeSession].ended:
+		return detachSession(m, m.sessions[m.activeSession])
+	case key.Matches(msg, m.keys.FocusSession):
+		m.SessionFocused = false
+		return m, nil
+	case key.Matches(msg, m.keys.NextSession):
+		return selectSession(m, m.activeSession+1), nil
+	case key.Matches(msg, m.keys.PreviousSession):
+		return selectSession(m, m.activeSession-1), nil
+	}
+	s := m.sessions[m.activeSession]
+	if s.ended {
+		return closeSession(m, m.activeSession), nil
+	}
+	s.send(msg)
+	return m, nil
+}
+
+// The logKeyHandler reacts to key presses while the log screen is shown. It returns false if the key press
+// was not handled.
+func (m MainModel) logKeyHandler(msg tea.KeyMsg) (bool, MainModel, tea.Cmd) {
+	m.LogStatus = ""
+	switch {
+	case key.Matches(msg, m.List.KeyMap.Quit):
+		m.ShowLog = false
+		return true, closeLogStream(m), nil
+
+	case key.Matches(msg, m.keys.FollowLog):
+		m.FollowLog = !m.FollowLog
+		if m.FollowLog {
+			m.LogViewer.GotoBottom()
+		}
+		return true, m, nil
+
+	case key.Matches(msg, m.keys.SearchLog):
+		m.LogSearching = true
+		m.LogSearch.SetValue("")
+		m = refreshLogContent(m)
+		return true, m, m.LogSearch.Focus()
+
+	case key.Matches(msg, m.keys.NextMatch):
+		return true, jumpToLogMatch(m, m.logMatch+1), nil
+
+	case key.Matches(msg, m.keys.PreviousMatch):
+		return true, jumpToLogMatch(m, m.logMatch-1), nil
+
+	case key.Matches(msg, m.keys.LogService):
+		newModel, cmd := cycleLogService(m)
+		return true, newModel, cmd
+
+	case key.Matches(msg, m.keys.SaveLog):
+		return true, saveLog(m), nil
+	}
+	return false, m, nil
+}
+
+// The logSearchHandler reacts to key presses while the search input of the log screen is active. Matches are
+// highlighted while typing, enter keeps the search and escape clears it.
+func (m MainModel) logSearchHandler(msg tea.KeyMsg) (MainModel, tea.Cmd) {
+	switch msg.Type {
+	case tea.KeyEnter:
+		m.LogSearching = false
+		m.LogSearch.Blur()
+		return m, nil
+	case tea.KeyEsc:
+		m.LogSearching = false
+		m.LogSearch.Blur()
+		m.LogSearch.SetValue("")
+		return refreshLogContent(m), nil
+	}
+	newSearchModel, cmd := m.LogSearch.Update(msg)
+	m.LogSearch = newSearchModel
+	m = refreshLogContent(m)
+	m = jumpToLogMatch(m, firstVisibleLogMatch(m))
+	return m, cmd
+}
+
+// The dashboardKeyHandler reacts to key presses while the dashboard is shown.
+func (m MainModel) dashboardKeyHandler(msg tea.KeyMsg) (MainModel, tea.Cmd) {
+	switch {
+	case key.Matches(msg, m.List.KeyMap.Quit), key.Matches(msg, m.keys.Dashboard):
+		return closeDashboard(m), tea.ClearScreen
+	case key.Matches(msg, m.keys.SortDashboard):
+		m.dashboard.sortBy = (m.dashboard.sortBy + 1) % dashboardColumn(len(dashboardHeaders))
+	case key.Matches(msg, m.keys.ReverseSort):
+		m.dashboard.reverse = !m.dashboard.reverse
+	}
+	return m, nil
+}
+
+// The recordingsKeyHandler reacts to key presses while the recording list is shown.
+func (m MainModel) recordingsKeyHandler(msg tea.KeyMsg) (MainModel, tea.Cmd) {
+	switch {
+	case m.Recordings.FilterState() == list.Filtering:
+		break
+	case m.Recordings.FilterState() == list.FilterApplied && msg.Type == tea.KeyEsc:
+		break
+	case key.Matches(msg, m.List.KeyMap.Quit), key.Matches(msg, m.keys.Recordings):
+		m.ShowRecordings = false
+		return m, nil
+	case key.Matches(msg, m.keys.Run):
+		return ReplayRecordingHandler(m)
+	}
+	newRecordingsModel, cmd := m.Recordings.Update(msg)
+	m.Recordings = newRecordingsModel
+	return m, cmd
+}
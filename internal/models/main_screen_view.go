@@ -4,6 +4,7 @@ import (
 	"ccmanager/internal"
 	"fmt"
 	"github.com/charmbracelet/lipgloss"
+	"github.com/docker/go-units"
 	"strings"
 )
 
@@ -15,16 +16,20 @@ func (m MainModel) View() string {
 			for _, mapping := range m.InfoItem.State.PortMappings {
 				portMappingsString = append(
 					portMappingsString,
-					fmt.Sprintf("  %s => http://127.0.0.1:%s", mapping.ContainerPort, mapping.HostPort),
+					fmt.Sprintf("  %s => %s", mapping.ContainerPort, portURL(m, m.InfoItem, mapping.HostPort)),
 				)
 			}
 			content := internal.InfoBoxStyle.Render(fmt.Sprintf(
-				"Path: %s\nState: %s\nImage: %s\nCCC port: http://0.0.0.0:%s\nPort mappings:\n%s",
+				"Path: %s\nState: %s\nHealth: %s\nUptime: %s\nRestarts: %d\nImage: %s\nCCC port: %s\nPort mappings:\n%s\n%s",
 				m.InfoItem.Path,
 				m.InfoItem.StateDescription(),
+				healthDescription(m.InfoItem.State.Health),
+				m.InfoItem.Uptime(),
+				m.InfoItem.State.RestartCount,
 				m.InfoItem.State.Image,
-				m.InfoItem.State.CCCPort,
+				portURL(m, m.InfoItem, m.InfoItem.State.CCCPort),
 				strings.Join(portMappingsString, "\n"),
+				resourcesView(m),
 			))
 			infoBox := lipgloss.JoinVertical(.5,
 				internal.TitleStyle.
@@ -43,17 +48,73 @@ func (m MainModel) View() string {
 			)
 		} else if m.ShowLog {
 			content := m.LogViewer.View()
+			var statusLine string
+			if m.LogSearching {
+				statusLine = m.LogSearch.View()
+			} else {
+				follow := fmt.Sprintf("Paused (%s to follow)", m.keys.FollowLog.Help().Key)
+				if m.FollowLog {
+					follow = fmt.Sprintf("Following (%s to pause)", m.keys.FollowLog.Help().Key)
+				}
+				status := []string{
+					follow,
+					fmt.Sprintf("Showing %s (%s to switch)", logServiceDescription(m), m.keys.LogService.Help().Key),
+				}
+				if m.LogSearch.Value() != "" {
+					if len(m.logMatches) > 0 {
+						status = append(status, fmt.Sprintf("Match %d/%d", m.logMatch+1, len(m.logMatches)))
+					} else {
+						status = append(status, "No matches")
+					}
+				}
+				if m.LogStatus != "" {
+					status = append(status, m.LogStatus)
+				}
+				status = append(status, "Press q or escape to return")
+				statusLine = strings.Join(status, " | ")
+			}
 			return lipgloss.JoinVertical(
 				0,
 				internal.TitleStyle.
 					Width(m.Width).
 					Render(fmt.Sprintf("Log of instance %s", m.InfoItem.Name)),
 				content,
-				internal.StatusLineStyle.Width(m.Width).Render("Press q or escape to return"),
+				internal.StatusLineStyle.Width(m.Width).Render(statusLine),
+			)
+		} else if m.ShowDashboard {
+			statusLine := fmt.Sprintf(
+				"Sorted by %s (%s to switch, %s to reverse) | Press q or escape to return",
+				dashboardHeaders[m.dashboard.sortBy],
+				m.keys.SortDashboard.Help().Key,
+				m.keys.ReverseSort.Help().Key,
+			)
+			return lipgloss.JoinVertical(
+				0,
+				internal.TitleStyle.
+					Width(m.Width).
+					Render("Resource dashboard"),
+				lipgloss.NewStyle().
+					Height(m.Height-2).
+					Render(m.dashboard.View(m.Width)),
+				internal.StatusLineStyle.Width(m.Width).Render(statusLine),
+			)
+		} else if m.ShowRecordings {
+			return internal.AppStyle.Render(m.Recordings.View())
+		} else if m.ShowWizard {
+			return formView(m, "New instance", m.Wizard.Form,
+				"tab/shift+tab: switch field, left/right: change choice, enter: create, escape: cancel",
+			)
+		} else if m.ShowEditor {
+			return formView(m, fmt.Sprintf("Edit %s", m.Editor.Item.Name), m.Editor.Form,
+				"tab/shift+tab: switch field, clear a field to remove it, enter: save, escape: cancel",
+			)
+		} else if m.ShowPrompt {
+			return formView(m, fmt.Sprintf("Run command in %s", m.Prompt.Item.Name), m.Prompt.Form,
+				"up/down: browse history, tab/shift+tab: switch field, left/right: change service, enter: run, escape: cancel",
 			)
 		} else if m.RunningConfirm {
 			m.Confirm.SetWidth(lipgloss.Width(m.ConfirmPrompt))
-			m.Confirm.SetHeight(4)
+			m.Confirm.SetHeight(max(4, 2*len(m.Confirm.Items())))
 			content := internal.InfoBoxStyle.Render(
 				lipgloss.JoinVertical(
 					0.5,
@@ -78,7 +139,18 @@ func (m MainModel) View() string {
 				internal.StatusLineStyle.Width(m.Width).Render("Please select an option"),
 			)
 		} else if !m.ListDisabled {
+			if len(m.sessions) > 0 {
+				return sessionsView(m)
+			}
 			return internal.AppStyle.Render(m.List.View())
+		} else if m.cancelAction != nil {
+			return lipgloss.JoinVertical(
+				0,
+				lipgloss.NewStyle().
+					Height(m.Height-1).
+					Render(internal.AppStyle.Render(internal.TitleStyle.Render(m.List.Title))),
+				internal.StatusLineStyle.Width(m.Width).Render(fmt.Sprintf("Working. Press %s to cancel", m.keys.Cancel.Help().Key)),
+			)
 		} else {
 			return fmt.Sprintf("%s\n\n", internal.AppStyle.Render(internal.TitleStyle.Render(m.List.Title)))
 		}
@@ -86,3 +158,108 @@ func (m MainModel) View() string {
 		return fmt.Sprintf("%s Loading instances", m.spinner.View())
 	}
 }
+
+// formView renders a form in a centered box with a title and a help line
+func formView(m MainModel, title string, form Form, help string) string {
+	content := internal.InfoBoxStyle.Render(form.View())
+	box := lipgloss.JoinVertical(.5,
+		internal.TitleStyle.
+			Width(lipgloss.Width(content)).
+			Render(title),
+		content,
+	)
+	return lipgloss.JoinVertical(
+		0,
+		lipgloss.NewStyle().
+			Width(m.Width).
+			Height(m.Height-1).
+			Align(lipgloss.Center, lipgloss.Center).
+			Render(box),
+		internal.StatusLineStyle.Width(m.Width).Render(help),
+	)
+}
+
+// sessionsView renders the instance list next to the terminal tabs
+func sessionsView(m MainModel) string {
+	width, _ := sessionSize(m)
+	var tabs []string
+	for i, s := range m.sessions {
+		title := s.title
+		if s.ended {
+			title = fmt.Sprintf("%s (ended)", title)
+		}
+		if i == m.activeSession {
+			tabs = append(tabs, internal.TitleStyle.Render(title))
+		} else {
+			tabs = append(tabs, lipgloss.NewStyle().Padding(0, 1).Render(title))
+		}
+	}
+	session := m.sessions[m.activeSession]
+	pane := lipgloss.JoinVertical(
+		0,
+		lipgloss.NewStyle().MaxWidth(width+2).Render(lipgloss.JoinHorizontal(lipgloss.Top, tabs...)),
+		internal.InfoBoxStyle.Copy().Padding(0).Render(session.View(m.SessionFocused)),
+	)
+
+	var status []string
+	if session.ended {
+		if session.err != nil {
+			status = append(status, fmt.Sprintf("Session ended: %s", session.err.Error()))
+		} else {
+			status = append(status, "Session ended")
+		}
+	}
+	if m.SessionFocused {
+		if session.ended {
+			status = append(status, "Press any key to close")
+		}
+		if session.session != "" && !session.ended {
+			status = append(status, fmt.Sprintf("%s: detach", m.keys.DetachSession.Help().Key))
+		}
+		status = append(status, fmt.Sprintf("%s: focus list", m.keys.FocusSession.Help().Key))
+	} else {
+		status = append(status, fmt.Sprintf("%s: focus session", m.keys.FocusSession.Help().Key))
+	}
+	status = append(status, fmt.Sprintf(
+		"%s/%s: switch session",
+		m.keys.PreviousSession.Help().Key,
+		m.keys.NextSession.Help().Key,
+	))
+	return lipgloss.JoinVertical(
+		0,
+		lipgloss.JoinHorizontal(
+			lipgloss.Top,
+			lipgloss.NewStyle().Width(sessionListWidth(m)).Render(internal.AppStyle.Render(m.List.View())),
+			pane,
+		),
+		internal.StatusLineStyle.Width(m.Width).MaxHeight(1).Render(strings.Join(status, " | ")),
+	)
+}
+
+// healthDescription describes the health check status of a container
+func healthDescription(health string) string {
+	if health == "" {
+		return "no health check"
+	}
+	return health
+}
+
+// resourcesView describes the resource usage and volume sizes of the instance in the info screen
+func resourcesView(m MainModel) string {
+	if m.InfoError != "" {
+		return internal.ErrorMessageStyle(fmt.Sprintf("Can not load resources: %s", m.InfoError))
+	}
+	usage := "n/a"
+	if m.InfoItem.State.Usage != nil {
+		usage = formatUsage(*m.InfoItem.State.Usage)
+	}
+	var volumes []string
+	for _, v := range m.InfoItem.State.Volumes {
+		size := "n/a"
+		if v.Size >= 0 {
+			size = units.BytesSize(float64(v.Size))
+		}
+		volumes = append(volumes, fmt.Sprintf("  %s: %s", v.Name, size))
+	}
+	return fmt.Sprintf("Resources: %s\nVolumes:\n%s", usage, strings.Join(volumes, "\n"))
+}
@@ -3,16 +3,31 @@ package models
 import (
 	"ccmanager/internal"
 	"ccmanager/internal/adapters"
+	"ccmanager/internal/discovery"
+	"ccmanager/internal/recordings"
+	"ccmanager/internal/templates"
+	"context"
+	"errors"
 	"fmt"
+	"github.com/charmbracelet/bubbles/list"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/pkg/browser"
-	funk "github.com/thoas/go-funk"
+	"io"
 	"log"
-	"os"
+	"path/filepath"
+	"slices"
 	"strconv"
+	"strings"
 	"time"
 )
 
+// An ActionFinishedMsg is sent when a start or stop action has finished
+type ActionFinishedMsg struct{}
+
+func ActionFinished() tea.Msg {
+	return ActionFinishedMsg{}
+}
+
 // A ConfirmMsg starts a yes/no confirmation screen with a prompt. Depending on the choice, a tea.Cmd is issued.
 type ConfirmMsg struct {
 	Prompt        string
@@ -46,48 +61,150 @@ func DisableList() tea.Msg {
 	return DisableListMsg{}
 }
 
-// An InstancesLoadedMsg is sent when all instances have been loaded
-type InstancesLoadedMsg struct{}
-
-func InstancesLoaded() tea.Msg {
-	return InstancesLoadedMsg{}
-}
-
 // A LoadInstanceMsg triggers the (re-)load of one specific instance
 type LoadInstanceMsg struct {
 	BasePath string
 	Name     string
 }
 
-// The LoadInstanceHandler loads information about an instance and - if it is loading an instance that is in
-// MainModel.ItemsToRefresh replaces the item in the instance list with the new information
+// The LoadInstanceHandler queues loading information about an instance. If the instance isn't in the instance list
+// yet, a placeholder item is shown until its status has been loaded
 func LoadInstanceHandler(m MainModel, basePath string, name string) (MainModel, tea.Cmd) {
+	if m.Group != "" && !m.Config.InGroup(name, m.Group) {
+		return m, nil
+	}
+
 	item := InstanceItem{
-		Name: name,
-		Path: basePath,
+		Name:      name,
+		Path:      basePath,
+		Favourite: m.Config.IsFavourite(name),
+		keys:      m.keys,
+		Marked:    m.Marked[basePath+name],
+		Progress:  m.Progress[basePath+name],
+		Loading:   true,
+	}
+	if findItem(m, item.ID()) < 0 {
+		// Favourites are inserted after the other favourites at the top of the list
+		index := len(m.List.Items())
+		if item.Favourite {
+			index = 0
+			for index < len(m.List.Items()) && m.List.Items()[index].(InstanceItem).Favourite {
+				index++
+			}
+		}
+		m.List.InsertItem(index, item)
 	}
 
-	if s, err := m.Adapter.GetContainerStatus(basePath, name); err == nil {
-		item.State = s
-	} else {
-		item.State = adapters.CloudControlStatus{Error: err}
+	m.loader.enqueue(LoadInstanceMsg{BasePath: basePath, Name: name})
+	return m, m.loader.next(m.Adapter, m.Timeouts)
+}
+
+// InstanceLoadedMsg is sent when the status of an instance has been loaded
+type InstanceLoadedMsg struct {
+	BasePath string
+	Name     string
+	State    adapters.CloudControlStatus
+	Sessions []string
+	// Failed tells whether the status couldn't be loaded
+	Failed bool
+}
+
+// The InstanceLoadedHandler replaces the item of a loaded instance in the instance list with the new information
+// and continues loading the queued instances
+func InstanceLoadedHandler(m MainModel, msg InstanceLoadedMsg) (MainModel, tea.Cmd) {
+	m.loader.done(msg.BasePath, msg.Name)
+	nextCmd := m.loader.next(m.Adapter, m.Timeouts)
+
+	index := findItem(m, msg.BasePath+msg.Name)
+	if index < 0 {
+		// The instance has been removed from the list while it was loaded
+		return m, nextCmd
 	}
 
-	r := funk.Find(m.ItemsToRefresh, func(e RefreshableItem) bool {
-		return e.item.Name == item.Name && e.item.Path == item.Path
-	})
+	item := m.List.Items()[index].(InstanceItem)
+	item.State = msg.State
+	item.Sessions = msg.Sessions
+	item.LoadFailed = msg.Failed
+	if !msg.Failed {
+		// A status that couldn't be loaded says nothing about the ports, e.g. after a timeout
+		closeStaleForward(m, item)
+	}
+	item.Loading = false
+	item.Marked = m.Marked[item.ID()]
+	item.Progress = m.Progress[item.ID()]
+	item.Update = ""
 
-	if r != nil {
-		ri := r.(RefreshableItem)
-		cmd := m.List.SetItem(ri.index, item)
-		if cmd != nil {
-			return m, cmd
+	var checkCmd tea.Cmd
+	if m.Checker != nil && m.Config.CheckUpdates(item.Name) && item.State.Image != "" {
+		update, needsCheck := m.Checker.Lookup(item.State.Image, item.State.Tag)
+		item.Update = update
+		if needsCheck {
+			checkCmd = CheckUpdate(m, item.State.Image)
 		}
+	}
+
+	return m, tea.Batch(m.List.SetItem(index, item), checkCmd, nextCmd)
+}
+
+// UsageLoadedMsg is sent when the resource usage of an instance has been refreshed
+type UsageLoadedMsg struct {
+	BasePath string
+	Name     string
+	Usage    *adapters.ResourceUsage
+}
+
+// The UsageLoadedHandler replaces the resource usage of a running instance in the instance list
+func UsageLoadedHandler(m MainModel, msg UsageLoadedMsg) (MainModel, tea.Cmd) {
+	m.loader.usageDone(msg.BasePath, msg.Name)
+	index := findItem(m, msg.BasePath+msg.Name)
+	if index < 0 {
+		return m, nil
+	}
+	item := m.List.Items()[index].(InstanceItem)
+	if !item.State.Running || item.Loading {
 		return m, nil
 	}
+	item.State.Usage = msg.Usage
+	return m, m.List.SetItem(index, item)
+}
 
-	m.List.InsertItem(len(m.List.Items()), item)
-	return m, nil
+// findItem returns the index of the instance with the given ID in the instance list or -1 if it isn't in the list
+func findItem(m MainModel, id string) int {
+	for index, i := range m.List.Items() {
+		if i.(InstanceItem).ID() == id {
+			return index
+		}
+	}
+	return -1
+}
+
+// UpdateCheckedMsg is sent when the tags of an image have been checked for updates
+type UpdateCheckedMsg struct {
+	Image string
+}
+
+// CheckUpdate lists the tags of an image using the versions.Checker
+func CheckUpdate(m MainModel, image string) tea.Cmd {
+	return func() tea.Msg {
+		ctx, cancel := context.WithTimeout(context.Background(), m.Timeouts.Status)
+		defer cancel()
+		_ = m.Checker.Check(ctx, image)
+		return UpdateCheckedMsg{Image: image}
+	}
+}
+
+// UpdateCheckedHandler updates the available updates of all instances using the checked image
+func UpdateCheckedHandler(m MainModel, msg UpdateCheckedMsg) (MainModel, tea.Cmd) {
+	var cmds []tea.Cmd
+	for index, i := range m.List.Items() {
+		item := i.(InstanceItem)
+		if item.State.Image != msg.Image {
+			continue
+		}
+		item.Update, _ = m.Checker.Lookup(item.State.Image, item.State.Tag)
+		cmds = append(cmds, m.List.SetItem(index, item))
+	}
+	return m, tea.Batch(cmds...)
 }
 
 // The LoadInstancesMsg triggers the loading of all instances.
@@ -97,24 +214,389 @@ func LoadInstances() tea.Msg {
 	return LoadInstancesMsg{}
 }
 
-// The LoadInstancesHandler runs through all configured base paths and generates a LoadInstanceMsg for every found
-// instance configuration.
+// The LoadInstancesHandler runs through all configured base paths and loads every found instance configuration.
+// The instance list is shown right away with placeholders that are filled in as the statuses arrive
 func LoadInstancesHandler(m MainModel) (MainModel, tea.Cmd) {
-	var seq []tea.Cmd
+	var cmds []tea.Cmd
 	for _, p := range m.BasePath {
 		for _, item := range getSubFolders(p) {
-			seq = append(seq, func(path string, name string) tea.Cmd {
-				return func() tea.Msg {
-					return LoadInstanceMsg{
-						BasePath: path,
-						Name:     name,
-					}
-				}
-			}(p, item))
+			var cmd tea.Cmd
+			m, cmd = LoadInstanceHandler(m, p, item)
+			cmds = append(cmds, cmd)
+		}
+	}
+	m.loadedItems = true
+	closeRemovedForwards(m)
+	return m, tea.Batch(cmds...)
+}
+
+// NewInstanceMsg opens the new instance wizard
+type NewInstanceMsg struct{}
+
+func NewInstance() tea.Msg {
+	return NewInstanceMsg{}
+}
+
+// NewInstanceHandler shows the new instance wizard
+func NewInstanceHandler(m MainModel) (MainModel, tea.Cmd) {
+	m.Wizard = newInstanceWizard(m.BasePath)
+	m.ShowWizard = true
+	return m, DisableList
+}
+
+// CreateInstanceHandler creates a new instance from the values of the new instance wizard and loads it into the
+// instance list
+func CreateInstanceHandler(m MainModel) (MainModel, tea.Cmd) {
+	basePath, values, err := m.Wizard.Values()
+	if err == nil {
+		err = templates.CreateInstance(m.TemplateDir, basePath, values)
+	}
+	if err != nil {
+		m.Wizard.Error = err.Error()
+		return m, nil
+	}
+	m.ShowWizard = false
+	return m, tea.Sequence(
+		EnableList,
+		func() tea.Msg {
+			return LoadInstanceMsg{
+				BasePath: basePath,
+				Name:     values.Name,
+			}
+		},
+		m.List.NewStatusMessage(fmt.Sprintf("Created instance %s", values.Name)),
+	)
+}
+
+// EditMsg opens the editor for the configuration of the currently selected instance
+type EditMsg struct{}
+
+func Edit() tea.Msg {
+	return EditMsg{}
+}
+
+// EditHandler loads the configuration of the currently selected instance and shows the instance editor
+func EditHandler(m MainModel) (MainModel, tea.Cmd) {
+	item := m.List.SelectedItem().(InstanceItem)
+	config, err := adapters.GetInstanceConfig(item.Path, item.Name)
+	if err != nil {
+		return m, m.List.NewStatusMessage(internal.ErrorMessageStyle(fmt.Sprintf("Can not load configuration: %s", err.Error())))
+	}
+	m.Editor = newInstanceEditor(item, config)
+	m.ShowEditor = true
+	return m, DisableList
+}
+
+// SaveConfigHandler writes the configuration of the instance editor into the compose file of the instance and
+// offers to recreate a running instance to apply it
+func SaveConfigHandler(m MainModel) (MainModel, tea.Cmd) {
+	config, err := m.Editor.Config()
+	if err == nil {
+		err = adapters.SaveInstanceConfig(m.Editor.Item.Path, m.Editor.Item.Name, m.Editor.config, config)
+	}
+	if err != nil {
+		m.Editor.Error = err.Error()
+		return m, nil
+	}
+	m.ShowEditor = false
+	if m.Editor.Item.State.Running {
+		return m, tea.Sequence(
+			EnableList,
+			ConfirmMsgCmd("Configuration saved. Do you want to recreate the instance to apply it?", Restart, nil, true),
+		)
+	}
+	return m, tea.Sequence(
+		EnableList,
+		m.List.NewStatusMessage(fmt.Sprintf("Saved configuration of %s", m.Editor.Item.Name)),
+	)
+}
+
+// NextGroupMsg filters the instance list by the next group
+type NextGroupMsg struct{}
+
+func NextGroup() tea.Msg {
+	return NextGroupMsg{}
+}
+
+// NextGroupHandler filters the instance list by the next group. After the last group, all instances are shown again
+func NextGroupHandler(m MainModel) (MainModel, tea.Cmd) {
+	groups := m.Config.GroupNames()
+	if len(groups) == 0 {
+		return m, m.List.NewStatusMessage("No groups configured")
+	}
+	next := 0
+	for i, group := range groups {
+		if group == m.Group {
+			next = i + 1
+		}
+	}
+	if next < len(groups) {
+		m.Group = groups[next]
+		m.List.Title = fmt.Sprintf("%s - %s", listTitle, m.Group)
+	} else {
+		m.Group = ""
+		m.List.Title = listTitle
+	}
+	return m, ReloadItems
+}
+
+// SwitchWorkspaceMsg switches to the workspace of the current group
+type SwitchWorkspaceMsg struct{}
+
+func SwitchWorkspace() tea.Msg {
+	return SwitchWorkspaceMsg{}
+}
+
+// SwitchWorkspaceHandler finds the instances that have to be stopped or started to switch to the workspace of the
+// current group
+func SwitchWorkspaceHandler(m MainModel) (MainModel, tea.Cmd) {
+	if m.Group == "" {
+		return m, m.List.NewStatusMessage(fmt.Sprintf("Use %s to select a group first", m.keys.NextGroup.Help().Key))
+	}
+	if m.bulk != nil {
+		return m, m.List.NewStatusMessage(internal.ErrorMessageStyle("Another action is running on multiple instances"))
+	}
+	group := m.Group
+	return m, func() tea.Msg {
+		instances, err := discovery.FindInstances(m.BasePath)
+		if err != nil {
+			return WorkspacePlannedMsg{Group: group, Err: err}
+		}
+		var stop, start []InstanceItem
+		var failures []string
+		for _, i := range instances {
+			ctx, cancel := context.WithTimeout(context.Background(), m.Timeouts.Status)
+			s, err := m.Adapter.GetContainerStatus(ctx, i.BasePath, i.Name)
+			cancel()
+			if err != nil {
+				failures = append(failures, fmt.Sprintf("%s: %s", i.Name, err.Error()))
+				continue
+			}
+			item := InstanceItem{Name: i.Name, Path: i.BasePath, State: s}
+			inGroup := m.Config.InGroup(i.Name, group)
+			if inGroup && !s.Running {
+				start = append(start, item)
+			} else if !inGroup && s.Running {
+				stop = append(stop, item)
+			}
+		}
+		// All instances are stopped before the first one is started to free their resources
+		jobs := newBulkJobs(bulkStop, stop)
+		for _, job := range newBulkJobs(bulkStart, start) {
+			job.phase = 1
+			jobs = append(jobs, job)
+		}
+		return WorkspacePlannedMsg{Group: group, Jobs: jobs, Failures: failures}
+	}
+}
+
+// WorkspacePlannedMsg holds the actions required to switch to the workspace of a group
+type WorkspacePlannedMsg struct {
+	Group string
+	Jobs  []bulkJob
+	// Failures holds the error messages of the instances whose status could not be read. They are left as they are
+	Failures []string
+	Err      error
+}
+
+// WorkspacePlannedHandler asks whether to run the actions required to switch to the workspace of a group
+func WorkspacePlannedHandler(m MainModel, msg WorkspacePlannedMsg) (MainModel, tea.Cmd) {
+	if msg.Err != nil {
+		return m, m.List.NewStatusMessage(internal.ErrorMessageStyle(fmt.Sprintf("Can not switch workspace: %s", msg.Err.Error())))
+	}
+	if len(msg.Jobs) == 0 && len(msg.Failures) > 0 {
+		return m, m.List.NewStatusMessage(internal.ErrorMessageStyle(fmt.Sprintf(
+			"Can not switch workspace, can not read the status of %s",
+			strings.Join(msg.Failures, "; "),
+		)))
+	}
+	if len(msg.Jobs) == 0 {
+		return m, m.List.NewStatusMessage(fmt.Sprintf("Workspace %s is already active", msg.Group))
+	}
+	var stop, start []string
+	for _, job := range msg.Jobs {
+		if job.action == bulkStop {
+			stop = append(stop, job.item.Name)
+		} else {
+			start = append(start, job.item.Name)
 		}
 	}
-	seq = append(seq, InstancesLoaded)
-	return m, tea.Sequence(seq...)
+	prompt := []string{fmt.Sprintf("Do you want to switch to workspace %s?", msg.Group), ""}
+	if len(stop) > 0 {
+		prompt = append(prompt, fmt.Sprintf("Stop: %s", strings.Join(stop, ", ")))
+	}
+	if len(start) > 0 {
+		prompt = append(prompt, fmt.Sprintf("Start: %s", strings.Join(start, ", ")))
+	}
+	if len(msg.Failures) > 0 {
+		prompt = append(prompt, fmt.Sprintf("Left unchanged, can not read the status: %s", strings.Join(msg.Failures, "; ")))
+	}
+	return m, ConfirmMsgCmd(
+		strings.Join(prompt, "\n"),
+		func() tea.Msg {
+			return BulkConfirmedMsg{
+				Description: fmt.Sprintf("switch to workspace %s for", msg.Group),
+				Jobs:        msg.Jobs,
+			}
+		},
+		nil,
+		true,
+	)
+}
+
+// UpgradeMsg upgrades the currently selected instance to the newest image tag
+type UpgradeMsg struct{}
+
+func Upgrade() tea.Msg {
+	return UpgradeMsg{}
+}
+
+// UpgradeHandler asks whether the currently selected instance should be upgraded to the newest image tag
+func UpgradeHandler(m MainModel) (MainModel, tea.Cmd) {
+	item := m.List.SelectedItem().(InstanceItem)
+	if item.Update == "" {
+		return m, m.List.NewStatusMessage(fmt.Sprintf("No update available for %s", item.Name))
+	}
+	return m, ConfirmMsgCmd(
+		fmt.Sprintf("Do you want to upgrade %s from %s to %s?", item.Name, item.State.Tag, item.Update),
+		func() tea.Msg {
+			return UpgradeConfirmedMsg{Item: item}
+		},
+		nil,
+		true,
+	)
+}
+
+// UpgradeConfirmedMsg is sent when the upgrade of an instance has been confirmed
+type UpgradeConfirmedMsg struct {
+	Item InstanceItem
+}
+
+// UpgradeConfirmedHandler sets the newest image tag in the compose file of an instance and restarts it if it is
+// running. A stopped instance is only reloaded and uses the new image with its next start
+func UpgradeConfirmedHandler(m MainModel, msg UpgradeConfirmedMsg) (MainModel, tea.Cmd) {
+	if err := upgradeTag(msg.Item); err != nil {
+		return m, m.List.NewStatusMessage(internal.ErrorMessageStyle(fmt.Sprintf("Can not upgrade %s: %s", msg.Item.Name, err.Error())))
+	}
+	item := msg.Item
+	if index := findItem(m, item.ID()); index >= 0 {
+		item = m.List.Items()[index].(InstanceItem)
+	}
+	if item.State.Running {
+		return m, RestartItem(item)
+	}
+	return LoadInstanceHandler(m, item.Path, item.Name)
+}
+
+// upgradeTag sets the newest image tag in the compose file of an instance
+func upgradeTag(item InstanceItem) error {
+	config, err := adapters.GetInstanceConfig(item.Path, item.Name)
+	if err != nil {
+		return err
+	}
+	newConfig := config
+	newConfig.Tag = item.Update
+	return adapters.SaveInstanceConfig(item.Path, item.Name, config, newConfig)
+}
+
+// BulkMsg runs an action on all marked instances
+type BulkMsg struct {
+	Action bulkAction
+}
+
+func Bulk(action bulkAction) tea.Cmd {
+	return func() tea.Msg {
+		return BulkMsg{Action: action}
+	}
+}
+
+// BulkHandler asks whether the action should be run on all marked instances
+func BulkHandler(m MainModel, msg BulkMsg) (MainModel, tea.Cmd) {
+	if m.bulk != nil {
+		return m, m.List.NewStatusMessage(internal.ErrorMessageStyle("Another action is running on the marked instances"))
+	}
+	items := markedItems(m)
+	return m, ConfirmMsgCmd(
+		fmt.Sprintf("Do you want to %s %d marked instances?", msg.Action, len(items)),
+		func() tea.Msg {
+			return BulkConfirmedMsg{Description: string(msg.Action), Jobs: newBulkJobs(msg.Action, items)}
+		},
+		nil,
+		true,
+	)
+}
+
+// BulkConfirmedMsg is sent when running actions on multiple instances has been confirmed
+type BulkConfirmedMsg struct {
+	// Description describes the actions in the summary
+	Description string
+	// Jobs holds the actions to run
+	Jobs []bulkJob
+}
+
+// BulkConfirmedHandler starts running actions on multiple instances. The number of instances processed at the
+// same time is limited by the parallelism setting
+func BulkConfirmedHandler(m MainModel, msg BulkConfirmedMsg) (MainModel, tea.Cmd) {
+	if len(msg.Jobs) == 0 {
+		return m, nil
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	m.bulk = &bulkRun{
+		description: msg.Description,
+		ctx:         ctx,
+		cancel:      cancel,
+		queue:       msg.Jobs,
+		total:       len(msg.Jobs),
+	}
+	var cmds []tea.Cmd
+	for _, job := range msg.Jobs {
+		m.Progress[job.item.ID()] = "Waiting"
+		cmds = append(cmds, updateItem(m, job.item.ID(), func(i *InstanceItem) {
+			i.Progress = "Waiting"
+		}))
+	}
+	var cmd tea.Cmd
+	m, cmd = startBulkJobs(m)
+	return m, tea.Batch(append(cmds, cmd)...)
+}
+
+// BulkJobFinishedMsg is sent when an action has finished on one of multiple instances
+type BulkJobFinishedMsg struct {
+	run  *bulkRun
+	Item InstanceItem
+	Err  error
+}
+
+// BulkJobFinishedHandler records the result of an instance, starts processing the next instance and shows a summary
+// after all instances have been processed
+func BulkJobFinishedHandler(m MainModel, msg BulkJobFinishedMsg) (MainModel, tea.Cmd) {
+	if msg.run != m.bulk {
+		return m, nil
+	}
+	run := m.bulk
+	run.running--
+	progress := "Done"
+	if msg.Err != nil {
+		progress = "Failed"
+		run.failures = append(run.failures, fmt.Sprintf("%s: %s", msg.Item.Name, msg.Err.Error()))
+	}
+	m.Progress[msg.Item.ID()] = progress
+	cmds := []tea.Cmd{
+		updateItem(m, msg.Item.ID(), func(i *InstanceItem) {
+			i.Progress = progress
+		}),
+	}
+	var cmd tea.Cmd
+	m, cmd = startBulkJobs(m)
+	cmds = append(cmds, cmd)
+	if run.running > 0 {
+		done := run.total - len(run.queue) - run.running - len(run.skipped)
+		return m, tea.Batch(append(cmds, m.List.NewStatusMessage(fmt.Sprintf("%d of %d instances done", done, run.total)))...)
+	}
+
+	// All instances have been processed
+	m, cmd = finishBulk(m)
+	return m, tea.Batch(append(cmds, cmd)...)
 }
 
 // The OpenCCCMsg triggers opening a browser to point at the CCC
@@ -124,25 +606,227 @@ func OpenCCC() tea.Msg {
 	return OpenCCCMsg{}
 }
 
-// The OpenCCCHandler lets the operating system open a browser pointing to the currently selected instance's CCC
+// The OpenCCCHandler lets the operating system open a browser pointing to the currently selected instance's CCC.
+// The ports of instances on remote machines are forwarded first
 func OpenCCCHandler(m MainModel) (MainModel, tea.Cmd) {
-	if cccPort, err := strconv.Atoi(m.List.SelectedItem().(InstanceItem).State.CCCPort); err == nil {
-		if err := browser.OpenURL(fmt.Sprintf("http://localhost:%d", cccPort)); err != nil {
-			return m, m.List.NewStatusMessage(internal.ErrorMessageStyle(fmt.Sprintf("Can not run browser: %s", err.Error())))
+	item := m.List.SelectedItem().(InstanceItem)
+	if _, err := strconv.Atoi(item.State.CCCPort); err == nil {
+		if !isForwarded(m, item) {
+			return m, ForwardPorts(m, item, true)
+		}
+		return m, openBrowser(m, portURL(m, item, item.State.CCCPort))
+	}
+	return m, nil
+}
+
+// openBrowser lets the operating system open a browser pointing to url
+func openBrowser(m MainModel, url string) tea.Cmd {
+	if err := browser.OpenURL(url); err != nil {
+		return m.List.NewStatusMessage(internal.ErrorMessageStyle(fmt.Sprintf("Can not run browser: %s", err.Error())))
+	}
+	return nil
+}
+
+// The PortsForwardedMsg is sent when the ports of an instance on a remote machine have been forwarded
+type PortsForwardedMsg struct {
+	ID       string
+	Ports    []string
+	Forwards []adapters.PortForward
+	Err      error
+	// CCCPort is the CCC port of the instance
+	CCCPort string
+	// cancel closes the tunnels
+	cancel context.CancelFunc
+}
+
+// ForwardPorts returns a tea.Cmd forwarding the CCC port and the port mappings of an instance on a remote machine to
+// the local machine unless they are forwarded already. If openCCC is set, the CCC is opened in a browser afterwards.
+// Only one forward per instance runs at a time
+func ForwardPorts(m MainModel, item InstanceItem, openCCC bool) tea.Cmd {
+	if isForwarded(m, item) {
+		return nil
+	}
+	if open, ok := m.pendingForwards[item.ID()]; ok {
+		m.pendingForwards[item.ID()] = open || openCCC
+		return nil
+	}
+	m.pendingForwards[item.ID()] = openCCC
+	ports := forwardedPorts(item)
+	return func() tea.Msg {
+		ctx, cancel := context.WithCancel(context.Background())
+		forwards, err := m.Adapter.ForwardPorts(ctx, item.Path, item.Name, ports)
+		if err != nil {
+			cancel()
 		}
+		return PortsForwardedMsg{
+			ID:       item.ID(),
+			Ports:    ports,
+			Forwards: forwards,
+			Err:      err,
+			CCCPort:  item.State.CCCPort,
+			cancel:   cancel,
+		}
+	}
+}
+
+// The PortsForwardedHandler replaces the tunnels of an instance with the new ones and opens the CCC if requested.
+// The tunnels are closed again if the instance has left the instance list or its ports have changed meanwhile
+func PortsForwardedHandler(m MainModel, msg PortsForwardedMsg) (MainModel, tea.Cmd) {
+	openCCC := m.pendingForwards[msg.ID]
+	delete(m.pendingForwards, msg.ID)
+	if msg.Err != nil {
+		return m, m.List.NewStatusMessage(internal.ErrorMessageStyle(fmt.Sprintf("Can not forward ports: %s", msg.Err.Error())))
+	}
+	index := findItem(m, msg.ID)
+	if index < 0 {
+		msg.cancel()
+		return m, nil
+	}
+	item := m.List.Items()[index].(InstanceItem)
+	if !slices.Equal(msg.Ports, forwardedPorts(item)) {
+		msg.cancel()
+		return m, ForwardPorts(m, item, openCCC)
+	}
+	closeForward(m, msg.ID)
+	f := &portForward{ports: msg.Ports, urls: map[string]string{}, cancel: msg.cancel}
+	for _, forward := range msg.Forwards {
+		f.urls[forward.Port] = forward.URL
+	}
+	m.forwards[msg.ID] = f
+	if openCCC {
+		return m, openBrowser(m, portURL(m, item, msg.CCCPort))
 	}
 	return m, nil
 }
 
-// The RefreshTickMsg is used to constantly (using tea.Tick) refresh a list of initializing/stopping/failing instances
+// The RefreshTickMsg is used to constantly (using tea.Tick) refresh the instances. While container events are
+// received, only initializing instances are refreshed
 type RefreshTickMsg time.Time
 
-func RefreshTick() tea.Cmd {
-	return tea.Tick(5*time.Second, func(t time.Time) tea.Msg {
+func RefreshTick(interval time.Duration) tea.Cmd {
+	return tea.Tick(interval, func(t time.Time) tea.Msg {
 		return RefreshTickMsg(t)
 	})
 }
 
+// The RefreshTickHandler reloads all instances that need refreshing. Container events only report changes of the
+// containers, so the CCC status of initializing instances is still polled, as well as instances whose status couldn't
+// be loaded. All instances are polled if no events are received. Of the other running instances, only the resource
+// usage is refreshed if it is shown
+func RefreshTickHandler(m MainModel) (MainModel, tea.Cmd) {
+	if !m.loadedItems {
+		return m, RefreshTick(m.Config.RefreshInterval)
+	}
+	var refreshCmds []tea.Cmd
+	for _, i := range m.List.Items() {
+		item := i.(InstanceItem)
+		initializing := item.State.Running && item.State.CCCStatus == adapters.CCCInit
+		// The detachable sessions shown in the list change without events
+		sessions := m.Config.DetachableSessions && len(item.Sessions) > 0
+		// Events of engines that can't be reached are missed
+		if m.events != nil && !initializing && !sessions && !item.LoadFailed {
+			if m.Config.ShowResources && item.State.Running {
+				refreshCmds = append(refreshCmds, m.loader.loadUsage(m.Adapter, item.Path, item.Name))
+			}
+			continue
+		}
+		refreshCmds = append(refreshCmds, func() tea.Msg {
+			return LoadInstanceMsg{
+				BasePath: item.Path,
+				Name:     item.Name,
+			}
+		})
+	}
+	return m, tea.Sequence(
+		tea.Batch(refreshCmds...),
+		RefreshTick(m.Config.RefreshInterval),
+	)
+}
+
+// SubscribeEventsMsg subscribes to the container events of the instances
+type SubscribeEventsMsg struct{}
+
+func SubscribeEvents() tea.Msg {
+	return SubscribeEventsMsg{}
+}
+
+// SubscribeEventsHandler starts streaming the container events of the instances
+func SubscribeEventsHandler(m MainModel) (MainModel, tea.Cmd) {
+	m.events = newEventStream(m.Adapter)
+	return m, m.events.Next()
+}
+
+// InstanceEventMsg is sent when a container of an instance has changed
+type InstanceEventMsg struct {
+	stream *eventStream
+	Event  adapters.InstanceEvent
+}
+
+// InstanceEventHandler reloads the instance of a container event if it belongs to one of the base paths
+func InstanceEventHandler(m MainModel, msg InstanceEventMsg) (MainModel, tea.Cmd) {
+	if msg.stream != m.events {
+		return m, nil
+	}
+	for _, p := range m.BasePath {
+		if absPath, err := filepath.Abs(p); err == nil && absPath == msg.Event.BasePath {
+			return m, tea.Batch(
+				m.events.Next(),
+				func() tea.Msg {
+					return LoadInstanceMsg{
+						BasePath: p,
+						Name:     msg.Event.Name,
+					}
+				},
+			)
+		}
+	}
+	return m, m.events.Next()
+}
+
+// EventStreamFailedMsg is sent when the container events of an engine can't be received while the events of the other
+// engines still are
+type EventStreamFailedMsg struct {
+	stream *eventStream
+	Err    error
+}
+
+// EventStreamFailedHandler shows the error of an engine and keeps receiving the events of the other engines. The
+// instances of the engine are polled while they can't be loaded
+func EventStreamFailedHandler(m MainModel, msg EventStreamFailedMsg) (MainModel, tea.Cmd) {
+	if msg.stream != m.events {
+		return m, nil
+	}
+	return m, tea.Batch(
+		m.List.NewStatusMessage(internal.ErrorMessageStyle(fmt.Sprintf("Can not receive container events: %s", msg.Err.Error()))),
+		m.events.Next(),
+	)
+}
+
+// EventStreamEndedMsg is sent when the container event stream has ended
+type EventStreamEndedMsg struct {
+	stream *eventStream
+	Err    error
+}
+
+// EventStreamEndedHandler falls back to polling all instances and subscribes to the container events again after
+// eventRetryInterval
+func EventStreamEndedHandler(m MainModel, msg EventStreamEndedMsg) (MainModel, tea.Cmd) {
+	if msg.stream != m.events {
+		return m, nil
+	}
+	m.events = nil
+	var statusCmd tea.Cmd
+	if msg.Err != nil {
+		statusCmd = m.List.NewStatusMessage(internal.ErrorMessageStyle(fmt.Sprintf("Can not receive container events, polling instances: %s", msg.Err.Error())))
+	}
+	return m, tea.Batch(
+		statusCmd,
+		tea.Tick(eventRetryInterval, func(_ time.Time) tea.Msg {
+			return SubscribeEventsMsg{}
+		}),
+	)
+}
+
 // The ReloadItemsMsg triggers reloading all instances
 type ReloadItemsMsg struct{}
 
@@ -150,13 +834,22 @@ func ReloadItems() tea.Msg {
 	return ReloadItemsMsg{}
 }
 
-// RestartMsg triggers restarting an instance
-type RestartMsg struct{}
+// RestartMsg triggers restarting an instance. The selected instance is restarted if Item is nil
+type RestartMsg struct {
+	Item *InstanceItem
+}
 
 func Restart() tea.Msg {
 	return RestartMsg{}
 }
 
+// RestartItem returns a tea.Cmd restarting a specific instance
+func RestartItem(item InstanceItem) tea.Cmd {
+	return func() tea.Msg {
+		return RestartMsg{Item: &item}
+	}
+}
+
 // The RunCloudControlMsg triggers running CloudControl
 type RunCloudControlMsg struct{}
 
@@ -180,25 +873,425 @@ func RunCloudControlHandler(m MainModel) tea.Cmd {
 		}
 	}
 
-	runCmds = append(runCmds, DisableList)
-	runCmds = append(runCmds, tea.ExitAltScreen)
-	runCmds = append(runCmds, tea.ClearScreen)
+	runCmds = append(runCmds, execCmds(m, item, adapters.ExecOptions{
+		Service: "cli",
+		Command: adapters.CloudControlCommand,
+	}, false)...)
+	return tea.Sequence(runCmds...)
+}
 
-	if c, err := m.Adapter.RunCloudControl(item.Path, item.Name, uint(m.Width), uint(m.Height)); err != nil {
-		return m.List.NewStatusMessage(internal.ErrorMessageStyle(err.Error()))
-	} else {
-		runCmds = append(runCmds, tea.Exec(c, func(err error) tea.Msg {
-			if err != nil {
-				return m.List.NewStatusMessage(internal.ErrorMessageStyle(err.Error()))
+// execCmds returns the tea.Cmds running a command in an instance. If sessions are embedded, the command runs in a
+// terminal tab next to the instance list. Otherwise the terminal is handed over to the command and the instance list
+// is shown again after the command has finished. The console size is set to the size of the screen then. If pause
+// is set, the output of the command stays visible until enter is pressed. Commands in the cli service run in
+// detachable sessions if they are enabled
+func execCmds(m MainModel, item InstanceItem, options adapters.ExecOptions, pause bool) []tea.Cmd {
+	if m.Config.RecordSessions {
+		options.RecordingFile = recordings.Path(m.Config.RecordingDir, item.Name, options.Service, time.Now())
+		options.RecordInput = m.Config.RecordInput
+	}
+	if m.Config.DetachableSessions && options.Service == "cli" && options.Session == "" {
+		options.Session = adapters.DetachableSession(options.Command)
+	}
+	if m.Config.EmbedSessions {
+		return []tea.Cmd{OpenSession(item, options)}
+	}
+	options.ConsoleWidth = uint(m.Width)
+	options.ConsoleHeight = uint(m.Height)
+	c, err := m.Adapter.Exec(context.Background(), item.Path, item.Name, options)
+	if err != nil {
+		return []tea.Cmd{m.List.NewStatusMessage(internal.ErrorMessageStyle(err.Error()))}
+	}
+	var command tea.ExecCommand = c
+	if pause {
+		command = &pausedExec{ContainerExec: c}
+	}
+	return []tea.Cmd{
+		DisableList,
+		tea.ExitAltScreen,
+		tea.ClearScreen,
+		tea.Exec(command, func(err error) tea.Msg {
+			return ExecFinishedMsg{Err: err}
+		}),
+		tea.EnterAltScreen,
+		EnableList,
+		// The detachable sessions of the instance may have changed
+		func() tea.Msg {
+			return LoadInstanceMsg{
+				BasePath: item.Path,
+				Name:     item.Name,
 			}
-			return nil
-		}))
+		},
 	}
+}
 
-	runCmds = append(runCmds, tea.EnterAltScreen)
-	runCmds = append(runCmds, EnableList)
+// ExecFinishedMsg is sent when a command running in an instance has finished
+type ExecFinishedMsg struct {
+	Err error
+}
 
-	return tea.Sequence(runCmds...)
+// ExecFinishedHandler shows the error of a failed command
+func ExecFinishedHandler(m MainModel, msg ExecFinishedMsg) (MainModel, tea.Cmd) {
+	if msg.Err == nil {
+		return m, nil
+	}
+	if m.ShowRecordings {
+		return m, m.Recordings.NewStatusMessage(internal.ErrorMessageStyle(msg.Err.Error()))
+	}
+	return m, m.List.NewStatusMessage(internal.ErrorMessageStyle(msg.Err.Error()))
+}
+
+// OpenSessionMsg opens a terminal tab running a command in an instance
+type OpenSessionMsg struct {
+	Item    InstanceItem
+	Options adapters.ExecOptions
+}
+
+func OpenSession(item InstanceItem, options adapters.ExecOptions) tea.Cmd {
+	return func() tea.Msg {
+		return OpenSessionMsg{Item: item, Options: options}
+	}
+}
+
+// OpenSessionHandler runs a command in a new terminal tab and focuses the tab. CloudControl and detachable sessions
+// aren't opened twice in the same instance, their running tab is focused instead
+func OpenSessionHandler(m MainModel, msg OpenSessionMsg) (MainModel, tea.Cmd) {
+	title := sessionTitle(msg.Item, msg.Options)
+	cloudControl := strings.Join(msg.Options.Command, " ") == strings.Join(adapters.CloudControlCommand, " ")
+	for i, s := range m.sessions {
+		if s.ended || s.item.ID() != msg.Item.ID() {
+			continue
+		}
+		if (msg.Options.Session != "" && s.session == msg.Options.Session) || (cloudControl && s.title == title) {
+			m.activeSession = i
+			m.SessionFocused = true
+			return m, nil
+		}
+	}
+	width, height := sessionSize(m)
+	msg.Options.ConsoleWidth = uint(width)
+	msg.Options.ConsoleHeight = uint(height)
+	c, err := m.Adapter.Exec(context.Background(), msg.Item.Path, msg.Item.Name, msg.Options)
+	if err != nil {
+		return m, m.List.NewStatusMessage(internal.ErrorMessageStyle(err.Error()))
+	}
+	s := newTerminalSession(c, msg.Item, title, width, height)
+	s.session = msg.Options.Session
+	m.sessions = append(m.sessions, s)
+	m.activeSession = len(m.sessions) - 1
+	m.SessionFocused = true
+	return layoutSessions(m), s.start()
+}
+
+// sessionTitle returns the title of the terminal tab of a command running in an instance. Tabs only attaching to a
+// detachable session show the name of the session
+func sessionTitle(item InstanceItem, options adapters.ExecOptions) string {
+	command := strings.Join(options.Command, " ")
+	switch {
+	case options.Session == adapters.CloudControlSession, command == strings.Join(adapters.CloudControlCommand, " "):
+		return item.Name
+	case options.Session == adapters.ShellSession, command == strings.Join(adapters.ShellCommand, " "):
+		return fmt.Sprintf("%s shell", item.Name)
+	case len(options.Command) == 0:
+		return fmt.Sprintf("%s: %s", item.Name, options.Session)
+	}
+	c := []rune(options.Command[len(options.Command)-1])
+	if len(c) > sessionTitleLength {
+		c = append(c[:sessionTitleLength-1], '…')
+	}
+	return fmt.Sprintf("%s: %s", item.Name, string(c))
+}
+
+// SessionUpdatedMsg is sent when the screen of a terminal tab has changed
+type SessionUpdatedMsg struct {
+	session *terminalSession
+}
+
+// SessionUpdatedHandler waits for the next change of the session. The screen is rendered by the following View call
+func SessionUpdatedHandler(m MainModel, msg SessionUpdatedMsg) (MainModel, tea.Cmd) {
+	return m, msg.session.Next()
+}
+
+// SessionEndedMsg is sent when the command of a terminal tab has finished
+type SessionEndedMsg struct {
+	session *terminalSession
+	Err     error
+}
+
+// SessionEndedHandler marks the session as ended. Its tab stays open until it is closed by a key press, so the
+// output of the command can be read. The tab of a detached session is closed right away
+func SessionEndedHandler(m MainModel, msg SessionEndedMsg) (MainModel, tea.Cmd) {
+	s := msg.session
+	s.ended = true
+	if s.session == "" {
+		return m, nil
+	}
+	// The detachable sessions of the instance have changed
+	loadCmd := func() tea.Msg {
+		return LoadInstanceMsg{
+			BasePath: s.item.Path,
+			Name:     s.item.Name,
+		}
+	}
+	if !s.detached {
+		return m, loadCmd
+	}
+	for i, other := range m.sessions {
+		if other == s {
+			m = closeSession(m, i)
+		}
+	}
+	return m, tea.Batch(loadCmd, m.List.NewStatusMessage(fmt.Sprintf("Detached from %s", s.title)))
+}
+
+// SessionDetachedMsg is sent when detaching from a detachable session has failed
+type SessionDetachedMsg struct {
+	session *terminalSession
+	Err     error
+}
+
+// detachSession returns a tea.Cmd detaching the terminal tab from its detachable session. The tab is closed when its
+// exec session has ended
+func detachSession(m MainModel, s *terminalSession) (MainModel, tea.Cmd) {
+	if s.session == "" {
+		return m, m.List.NewStatusMessage(internal.ErrorMessageStyle(fmt.Sprintf("%s is not detachable", s.title)))
+	}
+	s.detached = true
+	adapter, timeouts := m.Adapter, m.Timeouts
+	return m, func() tea.Msg {
+		ctx, cancel := context.WithTimeout(context.Background(), timeouts.Status)
+		defer cancel()
+		return SessionDetachedMsg{
+			session: s,
+			Err:     adapters.DetachSession(ctx, adapter, s.item.Path, s.item.Name, s.session),
+		}
+	}
+}
+
+// SessionDetachedHandler shows the error of a failed detach
+func SessionDetachedHandler(m MainModel, msg SessionDetachedMsg) (MainModel, tea.Cmd) {
+	if msg.Err == nil {
+		return m, nil
+	}
+	msg.session.detached = false
+	return m, m.List.NewStatusMessage(internal.ErrorMessageStyle(msg.Err.Error()))
+}
+
+// AttachMsg attaches to a detachable session of the currently selected instance
+type AttachMsg struct{}
+
+func Attach() tea.Msg {
+	return AttachMsg{}
+}
+
+// AttachHandler loads the detachable sessions of the currently selected instance in the background
+func AttachHandler(m MainModel) (MainModel, tea.Cmd) {
+	item := m.List.SelectedItem().(InstanceItem)
+	if !item.State.Running {
+		return m, m.List.NewStatusMessage(internal.ErrorMessageStyle(fmt.Sprintf("Instance %s is not running", item.Name)))
+	}
+	adapter, timeouts := m.Adapter, m.Timeouts
+	return m, func() tea.Msg {
+		ctx, cancel := context.WithTimeout(context.Background(), timeouts.Status)
+		defer cancel()
+		sessions, err := adapters.ListSessions(ctx, adapter, item.Path, item.Name)
+		return SessionsListedMsg{Item: item, Sessions: sessions, Err: err}
+	}
+}
+
+// SessionsListedMsg holds the detachable sessions of an instance to attach to
+type SessionsListedMsg struct {
+	Item     InstanceItem
+	Sessions []string
+	Err      error
+}
+
+// SessionsListedHandler attaches to the detachable session of an instance. If several sessions are running, the
+// session is chosen using the confirmation screen
+func SessionsListedHandler(m MainModel, msg SessionsListedMsg) (MainModel, tea.Cmd) {
+	item, sessions := msg.Item, msg.Sessions
+	if msg.Err != nil {
+		return m, m.List.NewStatusMessage(internal.ErrorMessageStyle(msg.Err.Error()))
+	}
+	attachCmd := func(session string) tea.Cmd {
+		return tea.Sequence(execCmds(m, item, adapters.ExecOptions{
+			Service: "cli",
+			Session: session,
+		}, false)...)
+	}
+	switch len(sessions) {
+	case 0:
+		return m, m.List.NewStatusMessage(fmt.Sprintf("No detachable sessions are running in %s", item.Name))
+	case 1:
+		return m, attachCmd(sessions[0])
+	}
+	m.RunningConfirm = true
+	m.ConfirmPrompt = fmt.Sprintf("Attach to which session of %s?", item.Name)
+	for len(m.Confirm.Items()) > 0 {
+		m.Confirm.RemoveItem(0)
+	}
+	for i, s := range sessions {
+		m.Confirm.InsertItem(i, ConfirmItem{
+			title:   s,
+			command: attachCmd(s),
+		})
+	}
+	m.Confirm.Select(0)
+	return m, DisableList
+}
+
+// selectSession shows the terminal tab with the given index. The index wraps around at both ends
+func selectSession(m MainModel, index int) MainModel {
+	if len(m.sessions) > 0 {
+		m.activeSession = (index + len(m.sessions)) % len(m.sessions)
+	}
+	return m
+}
+
+// closeSession closes the terminal tab with the given index. The instance list gets the whole screen again when the
+// last tab has been closed
+func closeSession(m MainModel, index int) MainModel {
+	m.sessions = append(m.sessions[:index:index], m.sessions[index+1:]...)
+	m.activeSession = max(min(m.activeSession, len(m.sessions)-1), 0)
+	if len(m.sessions) == 0 {
+		m.SessionFocused = false
+	}
+	return layoutSessions(m)
+}
+
+// sessionListWidth returns the width of the instance list while terminal tabs are open
+func sessionListWidth(m MainModel) int {
+	return m.Width / 3
+}
+
+// sessionSize returns the size of the virtual terminals of the terminal tabs. The pane of the tabs is framed by a
+// border and sits between the tab bar and the status line
+func sessionSize(m MainModel) (int, int) {
+	return m.Width - sessionListWidth(m) - 2, m.Height - 4
+}
+
+// layoutSessions sizes the instance list and the virtual terminals of the terminal tabs to the screen
+func layoutSessions(m MainModel) MainModel {
+	h, v := internal.AppStyle.GetFrameSize()
+	if len(m.sessions) == 0 {
+		m.List.SetSize(m.Width-h, m.Height-v)
+		return m
+	}
+	m.List.SetSize(sessionListWidth(m)-h, m.Height-v-1)
+	width, height := sessionSize(m)
+	for _, s := range m.sessions {
+		s.resize(width, height)
+	}
+	return m
+}
+
+// OpenShellMsg triggers opening a shell in the cli service of the currently selected instance
+type OpenShellMsg struct{}
+
+func OpenShell() tea.Msg {
+	return OpenShellMsg{}
+}
+
+// OpenShellHandler opens a shell in the cli service of the currently selected instance
+func OpenShellHandler(m MainModel) tea.Cmd {
+	item := m.List.SelectedItem().(InstanceItem)
+	if !item.State.Running {
+		return m.List.NewStatusMessage(internal.ErrorMessageStyle(fmt.Sprintf("Instance %s is not running", item.Name)))
+	}
+	return tea.Sequence(execCmds(m, item, adapters.ExecOptions{
+		Service: "cli",
+		Command: adapters.ShellCommand,
+	}, false)...)
+}
+
+// RunCommandMsg opens the prompt for a command to run in the currently selected instance
+type RunCommandMsg struct{}
+
+func RunCommand() tea.Msg {
+	return RunCommandMsg{}
+}
+
+// RunCommandHandler loads the services of the currently selected instance and shows the command prompt
+func RunCommandHandler(m MainModel) (MainModel, tea.Cmd) {
+	item := m.List.SelectedItem().(InstanceItem)
+	if !item.State.Running {
+		return m, m.List.NewStatusMessage(internal.ErrorMessageStyle(fmt.Sprintf("Instance %s is not running", item.Name)))
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), m.Timeouts.Status)
+	defer cancel()
+	services, err := m.Adapter.GetServices(ctx, item.Path, item.Name)
+	if err != nil {
+		return m, m.List.NewStatusMessage(internal.ErrorMessageStyle(fmt.Sprintf("Can not load services: %s", err.Error())))
+	}
+	m.Prompt = newCommandPrompt(item, services, m.History)
+	m.ShowPrompt = true
+	return m, DisableList
+}
+
+// ExecCommandHandler adds the command entered in the command prompt to the command history and runs it in the
+// selected service using sh -c
+func ExecCommandHandler(m MainModel) (MainModel, tea.Cmd) {
+	service, command, err := m.Prompt.Command()
+	if err != nil {
+		m.Prompt.Error = err.Error()
+		return m, nil
+	}
+	m.ShowPrompt = false
+	m.History = addToHistory(m.History, command)
+	cmds := execCmds(m, m.Prompt.Item, adapters.ExecOptions{
+		Service: service,
+		Command: []string{"/bin/sh", "-c", command},
+	}, true)
+	if err := saveHistory(m.Config.HistoryFile, m.History); err != nil {
+		cmds = append(cmds, m.List.NewStatusMessage(internal.ErrorMessageStyle(err.Error())))
+	}
+	return m, tea.Sequence(append([]tea.Cmd{EnableList}, cmds...)...)
+}
+
+// ShowRecordingsMsg shows the session recordings of the currently selected instance
+type ShowRecordingsMsg struct{}
+
+func ShowRecordings() tea.Msg {
+	return ShowRecordingsMsg{}
+}
+
+// ShowRecordingsHandler loads the session recordings of the currently selected instance and shows the recording list
+func ShowRecordingsHandler(m MainModel) (MainModel, tea.Cmd) {
+	item := m.List.SelectedItem().(InstanceItem)
+	found, err := recordings.List(m.Config.RecordingDir, item.Name)
+	if err != nil {
+		return m, m.List.NewStatusMessage(internal.ErrorMessageStyle(err.Error()))
+	}
+	var items []list.Item
+	for _, r := range found {
+		items = append(items, RecordingItem{Recording: r})
+	}
+	m.InfoItem = item
+	m.ShowRecordings = true
+	m.Recordings.Title = fmt.Sprintf("Recordings of %s", item.Name)
+	m.Recordings.ResetFilter()
+	cmds := []tea.Cmd{m.Recordings.SetItems(items)}
+	m.Recordings.Select(0)
+	if !m.Config.RecordSessions {
+		cmds = append(cmds, m.Recordings.NewStatusMessage("Session recording is disabled"))
+	}
+	return m, tea.Batch(cmds...)
+}
+
+// ReplayRecordingHandler replays the selected recording of the recording list in the terminal
+func ReplayRecordingHandler(m MainModel) (MainModel, tea.Cmd) {
+	item, ok := m.Recordings.SelectedItem().(RecordingItem)
+	if !ok {
+		return m, nil
+	}
+	return m, tea.Sequence(
+		tea.ExitAltScreen,
+		tea.ClearScreen,
+		tea.Exec(recordings.NewPlayer(item.Path), func(err error) tea.Msg {
+			return ExecFinishedMsg{Err: err}
+		}),
+		tea.EnterAltScreen,
+	)
 }
 
 // ShowInfoMsg is used to show information about the currently selected instance.
@@ -208,6 +1301,116 @@ func ShowInfo() tea.Msg {
 	return ShowInfoMsg{}
 }
 
+// ShowInfoHandler shows the info screen of the currently selected instance and loads its resource usage and volume
+// sizes. The ports of instances on remote machines are forwarded to show their local URLs
+func ShowInfoHandler(m MainModel) (MainModel, tea.Cmd) {
+	m.ShowInfo = true
+	m.InfoItem = m.List.SelectedItem().(InstanceItem)
+	item := m.InfoItem
+	return m, tea.Batch(ForwardPorts(m, item, false), func() tea.Msg {
+		ctx, cancel := context.WithTimeout(context.Background(), m.Timeouts.Status)
+		defer cancel()
+		msg := ResourcesLoadedMsg{ID: item.ID()}
+		if item.State.Running {
+			if usage, err := m.Adapter.GetResourceUsage(ctx, item.Path, item.Name); err == nil {
+				msg.Usage = &usage
+			} else {
+				msg.Err = err
+			}
+		}
+		if volumes, err := m.Adapter.GetVolumeSizes(ctx, item.Path, item.Name); err == nil {
+			msg.Volumes = volumes
+		} else if msg.Err == nil {
+			msg.Err = err
+		}
+		return msg
+	})
+}
+
+// ResourcesLoadedMsg is sent when the resource usage and volume sizes of the instance in the info screen have been
+// loaded
+type ResourcesLoadedMsg struct {
+	ID      string
+	Usage   *adapters.ResourceUsage
+	Volumes []adapters.VolumeSize
+	Err     error
+}
+
+// ResourcesLoadedHandler shows the resource usage and volume sizes in the info screen
+func ResourcesLoadedHandler(m MainModel, msg ResourcesLoadedMsg) (MainModel, tea.Cmd) {
+	if !m.ShowInfo || m.InfoItem.ID() != msg.ID {
+		return m, nil
+	}
+	m.InfoItem.State.Usage = msg.Usage
+	m.InfoItem.State.Volumes = msg.Volumes
+	m.InfoError = ""
+	if msg.Err != nil {
+		m.InfoError = msg.Err.Error()
+	}
+	return m, nil
+}
+
+// ShowDashboardMsg shows the resource dashboard
+type ShowDashboardMsg struct{}
+
+func ShowDashboard() tea.Msg {
+	return ShowDashboardMsg{}
+}
+
+// ShowDashboardHandler shows the resource dashboard and starts streaming the resource usage of all running instances
+func ShowDashboardHandler(m MainModel) (MainModel, tea.Cmd) {
+	var cmd tea.Cmd
+	m.dashboard, cmd = newDashboard(m)
+	m.ShowDashboard = true
+	return m, tea.Batch(tea.ClearScreen, cmd)
+}
+
+// closeDashboard hides the dashboard and stops streaming the resource usage
+func closeDashboard(m MainModel) MainModel {
+	if m.dashboard != nil {
+		m.dashboard.cancel()
+		m.dashboard = nil
+	}
+	m.ShowDashboard = false
+	return m
+}
+
+// ResourceUsageMsg is sent when the resource usage of an instance in the dashboard has been received
+type ResourceUsageMsg struct {
+	stream *usageStream
+	Usage  adapters.ResourceUsage
+}
+
+// ResourceUsageHandler adds the resource usage to the dashboard and waits for the next one
+func ResourceUsageHandler(m MainModel, msg ResourceUsageMsg) (MainModel, tea.Cmd) {
+	if msg.stream.dashboard != m.dashboard {
+		return m, nil
+	}
+	m.dashboard.add(msg.stream.id, msg.Usage)
+	return m, msg.stream.Next()
+}
+
+// UsageStreamEndedMsg is sent when the resource usage stream of an instance in the dashboard has ended
+type UsageStreamEndedMsg struct {
+	stream *usageStream
+	Err    error
+}
+
+// UsageStreamEndedHandler shows the error that ended the resource usage stream in the dashboard
+func UsageStreamEndedHandler(m MainModel, msg UsageStreamEndedMsg) (MainModel, tea.Cmd) {
+	if msg.stream.dashboard != m.dashboard {
+		return m, nil
+	}
+	if errors.Is(msg.Err, io.EOF) {
+		// The stream ends when the container is stopped
+		msg.Err = errors.New("stopped")
+	}
+	if msg.Err != nil {
+		m.dashboard.rows[msg.stream.id].err = msg.Err
+	}
+	return m, nil
+}
+
 // ShowLogMsg is sent to show the log of an instance
 type ShowLogMsg struct{}
 
@@ -215,23 +1418,97 @@ func ShowLog() tea.Msg {
 	return ShowLogMsg{}
 }
 
-// ShowLogHandler fetches the currently selected instance's log enables the logviewer
+// ShowLogHandler starts streaming the currently selected instance's log and enables the logviewer
 func ShowLogHandler(m MainModel) (MainModel, tea.Cmd) {
-	item := m.List.SelectedItem().(InstanceItem)
+	m.InfoItem = m.List.SelectedItem().(InstanceItem)
+	m.FollowLog = true
+	m.LogStatus = ""
+	m.LogSearch.SetValue("")
+	m.LogOptions = m.Config.LogOptions(m.InfoItem.Name)
 
-	if l, err := m.Adapter.GetLogs(item.Path, item.Name); err != nil {
-		m.LogViewer.SetContent(fmt.Sprintf("Error getting logs: %s", err.Error()))
+	ctx, cancel := context.WithTimeout(context.Background(), m.Timeouts.Status)
+	defer cancel()
+	if services, err := m.Adapter.GetServices(ctx, m.InfoItem.Path, m.InfoItem.Name); err == nil {
+		m.LogServices = services
 	} else {
-		m.LogViewer.SetContent(l)
+		m.LogServices = nil
 	}
 
+	m, readCmd := startLogStream(m)
+
 	m.ShowLog = true
-	return m, tea.Sequence(
-		tea.ClearScreen,
-		DisableList,
+	return m, tea.Batch(
+		tea.Sequence(
+			tea.ClearScreen,
+			DisableList,
+		),
+		readCmd,
 	)
 }
 
+// LogStreamOpenedHandler starts reading the opened log. Logs opened for a log screen that has been closed or
+// switched to another log meanwhile are closed right away
+func LogStreamOpenedHandler(m MainModel, msg LogStreamOpenedMsg) (MainModel, tea.Cmd) {
+	if msg.stream != m.logStream {
+		_ = msg.source.Close()
+		return m, nil
+	}
+	m.logStream.opened(msg.source)
+	return m, m.logStream.Next()
+}
+
+// LogLinesMsg is sent when new lines of the currently shown log have been read
+type LogLinesMsg struct {
+	stream *logStream
+	Lines  []string
+}
+
+// LogLinesHandler adds new lines to the log viewer and scrolls to the bottom if the log is followed
+func LogLinesHandler(m MainModel, msg LogLinesMsg) (MainModel, tea.Cmd) {
+	if msg.stream != m.logStream {
+		return m, nil
+	}
+	m.LogLines = append(m.LogLines, msg.Lines...)
+	if len(m.LogLines) > maxLogLines {
+		m.LogLines = m.LogLines[len(m.LogLines)-maxLogLines:]
+	}
+	m = refreshLogContent(m)
+	if m.FollowLog {
+		m.LogViewer.GotoBottom()
+	}
+	return m, m.logStream.Next()
+}
+
+// LogStreamEndedMsg is sent when the log stream of the currently shown log has ended
+type LogStreamEndedMsg struct {
+	stream *logStream
+	Err    error
+}
+
+// LogStreamEndedHandler adds errors that ended the log stream to the log viewer
+func LogStreamEndedHandler(m MainModel, msg LogStreamEndedMsg) (MainModel, tea.Cmd) {
+	if msg.stream != m.logStream {
+		return m, nil
+	}
+	if msg.Err != nil && !errors.Is(msg.Err, io.EOF) {
+		m.LogLines = append(m.LogLines, internal.ErrorMessageStyle(fmt.Sprintf("Error reading log: %s", msg.Err.Error())))
+		m = refreshLogContent(m)
+		if m.FollowLog {
+			m.LogViewer.GotoBottom()
+		}
+	}
+	return m, nil
+}
+
+// closeLogStream stops streaming the currently shown log
+func closeLogStream(m MainModel) MainModel {
+	if m.logStream != nil {
+		_ = m.logStream.Close()
+		m.logStream = nil
+	}
+	return m
+}
+
 // StartMsg starts an instance
 type StartMsg struct{}
 
@@ -239,12 +1516,17 @@ func Start() tea.Msg {
 	return StartMsg{}
 }
 
-// StartHandler uses adapters.BaseAdapter.StartCloudControl to start an instance
-func StartHandler(m MainModel) tea.Cmd {
-	item := m.List.SelectedItem().(InstanceItem)
+// StartHandler uses adapters.BaseAdapter.StartCloudControl to start an instance. The start can be cancelled using
+// the given context
+func StartHandler(ctx context.Context, m MainModel) tea.Cmd {
+	return startItem(ctx, m, m.List.SelectedItem().(InstanceItem))
+}
+
+// startItem returns a tea.Cmd starting an instance
+func startItem(ctx context.Context, m MainModel, item InstanceItem) tea.Cmd {
 	return func() tea.Msg {
 		var cmds []tea.Cmd
-		if err := m.Adapter.StartCloudControl(item.Path, item.Name); err != nil {
+		if err := m.Adapter.StartCloudControl(ctx, item.Path, item.Name); err != nil {
 			cmds = append(cmds, m.List.NewStatusMessage(internal.ErrorMessageStyle(fmt.Sprintf("Can not start CloudControl: %s", err.Error()))))
 		}
 		if cmds != nil {
@@ -261,12 +1543,17 @@ func Stop() tea.Msg {
 	return StopMsg{}
 }
 
-// StopHandler uses adapters.BaseAdapter.StopCloudControl to stop an instance.
-func StopHandler(m MainModel) tea.Cmd {
-	item := m.List.SelectedItem().(InstanceItem)
+// StopHandler uses adapters.BaseAdapter.StopCloudControl to stop an instance. The stop can be cancelled using
+// the given context
+func StopHandler(ctx context.Context, m MainModel) tea.Cmd {
+	return stopItem(ctx, m, m.List.SelectedItem().(InstanceItem))
+}
+
+// stopItem returns a tea.Cmd stopping an instance
+func stopItem(ctx context.Context, m MainModel, item InstanceItem) tea.Cmd {
 	return func() tea.Msg {
 		var cmds []tea.Cmd
-		if err := m.Adapter.StopCloudControl(item.Path, item.Name, true); err != nil {
+		if err := m.Adapter.StopCloudControl(ctx, item.Path, item.Name, true); err != nil {
 			cmds = append(cmds, m.List.NewStatusMessage(internal.ErrorMessageStyle(fmt.Sprintf("Can not start CloudControl: %s", err.Error()))))
 		}
 		if cmds != nil {
@@ -278,17 +1565,9 @@ func StopHandler(m MainModel) tea.Cmd {
 
 // getSubFolders walks through the given BasePath and returns a list of directory entries which are also directories.
 func getSubFolders(basePath string) []string {
-	entries, err := os.ReadDir(basePath)
+	items, err := discovery.GetSubFolders(basePath)
 	if err != nil {
 		log.Fatal(err)
 	}
-
-	var items []string
-
-	for _, e := range entries {
-		if e.Type().IsDir() {
-			items = append(items, e.Name())
-		}
-	}
 	return items
 }
@@ -0,0 +1,51 @@
+package models
+
+import (
+	"github.com/charmbracelet/bubbles/list"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// newUpgradeTestModel returns a model listing an instance using image tag 1.0.0 with update 2.0.0 available
+func newUpgradeTestModel(t *testing.T, running bool) (MainModel, InstanceItem) {
+	basePath := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(basePath, "test"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	compose := "services:\n  cli:\n    image: ghcr.io/dodevops/cloudcontrol-azure:1.0.0\n"
+	if err := os.WriteFile(filepath.Join(basePath, "test", "docker-compose.yml"), []byte(compose), 0644); err != nil {
+		t.Fatal(err)
+	}
+	item := InstanceItem{Name: "test", Path: basePath, Update: "2.0.0"}
+	item.State.Running = running
+	// Another instance is selected, the upgrade must not act on it
+	other := InstanceItem{Name: "other", Path: basePath}
+	m := MainModel{
+		List:   list.New([]list.Item{other, item}, list.NewDefaultDelegate(), 0, 0),
+		loader: newStatusLoader(1, false, false),
+	}
+	return m, item
+}
+
+func TestUpgradeRestartsRunningInstance(t *testing.T) {
+	m, item := newUpgradeTestModel(t, true)
+	_, cmd := UpgradeConfirmedHandler(m, UpgradeConfirmedMsg{Item: item})
+	msg, ok := cmd().(RestartMsg)
+	if !ok || msg.Item == nil || msg.Item.ID() != item.ID() {
+		t.Fatalf("got %#v, want a restart of the upgraded instance", msg)
+	}
+	compose, _ := os.ReadFile(filepath.Join(item.Path, "test", "docker-compose.yml"))
+	if !strings.Contains(string(compose), "cloudcontrol-azure:2.0.0") {
+		t.Errorf("the tag wasn't upgraded:\n%s", compose)
+	}
+}
+
+func TestUpgradeReloadsStoppedInstance(t *testing.T) {
+	m, item := newUpgradeTestModel(t, false)
+	m, _ = UpgradeConfirmedHandler(m, UpgradeConfirmedMsg{Item: item})
+	if len(m.loader.running) != 1 || !m.loader.running[item.ID()] {
+		t.Errorf("loading %v, want only the upgraded instance to be reloaded", m.loader.running)
+	}
+}
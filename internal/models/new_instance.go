@@ -0,0 +1,90 @@
+package models
+
+import (
+	"ccmanager/internal/templates"
+	"fmt"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// Indices of the fixed fields of the new instance wizard. The environment variables of the selected flavour follow
+// after wizardTag
+const (
+	wizardFlavour = iota
+	wizardName
+	wizardBasePath
+	wizardTag
+)
+
+// NewInstanceWizard holds the state of the form used to create new instances
+type NewInstanceWizard struct {
+	Form
+}
+
+// newInstanceWizard creates a wizard for the given base paths
+func newInstanceWizard(basePaths []string) NewInstanceWizard {
+	var flavours []string
+	for _, f := range templates.Flavours {
+		flavours = append(flavours, f.Name)
+	}
+	w := NewInstanceWizard{
+		Form: newForm(
+			formField{label: "Flavour", choices: flavours},
+			newTextField("Name", ""),
+			formField{label: "Base path", choices: basePaths},
+			newTextField("Image tag", "latest"),
+		),
+	}
+	return w.updateEnvironmentFields()
+}
+
+// flavour returns the currently selected flavour
+func (w NewInstanceWizard) flavour() templates.Flavour {
+	return templates.Flavours[w.fields[wizardFlavour].choice]
+}
+
+// updateEnvironmentFields replaces the environment variable fields with the ones of the selected flavour
+func (w NewInstanceWizard) updateEnvironmentFields() NewInstanceWizard {
+	w.fields = w.fields[:wizardTag+1]
+	for _, e := range w.flavour().Environment {
+		w.fields = append(w.fields, newTextField(e, ""))
+	}
+	return w
+}
+
+// Values returns the template values entered in the wizard or an error if the values are incomplete
+func (w NewInstanceWizard) Values() (string, templates.Values, error) {
+	values := templates.Values{
+		Name:        w.fields[wizardName].value(),
+		Flavour:     w.flavour(),
+		Image:       w.flavour().Image,
+		Tag:         w.fields[wizardTag].value(),
+		Environment: map[string]string{},
+	}
+	for _, f := range w.fields[wizardTag+1:] {
+		if f.value() == "" {
+			return "", values, fmt.Errorf("please set %s", f.label)
+		}
+		values.Environment[f.label] = f.value()
+	}
+	if values.Name == "" {
+		return "", values, fmt.Errorf("please set a name")
+	}
+	if err := templates.ValidateName(values.Name); err != nil {
+		return "", values, err
+	}
+	if values.Tag == "" {
+		return "", values, fmt.Errorf("please set an image tag")
+	}
+	return w.fields[wizardBasePath].value(), values, nil
+}
+
+// Update updates the form and replaces the environment variable fields if the flavour was changed
+func (w NewInstanceWizard) Update(msg tea.KeyMsg) (NewInstanceWizard, tea.Cmd) {
+	flavour := w.fields[wizardFlavour].choice
+	var cmd tea.Cmd
+	w.Form, cmd = w.Form.Update(msg)
+	if w.fields[wizardFlavour].choice != flavour {
+		w = w.updateEnvironmentFields()
+	}
+	return w, cmd
+}
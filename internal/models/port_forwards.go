@@ -0,0 +1,82 @@
+package models
+
+import (
+	"ccmanager/internal/adapters"
+	"context"
+	"slices"
+)
+
+// portForward holds the ports of an instance on a remote machine forwarded to the local machine. The tunnels stay
+// open while the instance is running with the same ports
+type portForward struct {
+	// ports holds the forwarded ports
+	ports []string
+	// urls holds the local URLs of the forwarded ports by port
+	urls map[string]string
+	// cancel closes the tunnels
+	cancel context.CancelFunc
+}
+
+// forwardedPorts returns the ports of an instance that need to be forwarded: the CCC port and the ports of the port
+// mappings of running instances on remote machines
+func forwardedPorts(item InstanceItem) []string {
+	if item.State.Host == "" || !item.State.Running {
+		return nil
+	}
+	var ports []string
+	if item.State.CCCPort != "n/a" && item.State.CCCPort != "" {
+		ports = append(ports, item.State.CCCPort)
+	}
+	for _, mapping := range item.State.PortMappings {
+		ports = append(ports, mapping.HostPort)
+	}
+	return ports
+}
+
+// isForwarded tells whether the ports of an instance are forwarded already or don't need to be forwarded
+func isForwarded(m MainModel, item InstanceItem) bool {
+	ports := forwardedPorts(item)
+	f, ok := m.forwards[item.ID()]
+	return len(ports) == 0 || ok && slices.Equal(f.ports, ports)
+}
+
+// portURL returns the URL a port published by an instance can be reached at from the local machine
+func portURL(m MainModel, item InstanceItem, port string) string {
+	if f, ok := m.forwards[item.ID()]; ok {
+		if url, ok := f.urls[port]; ok {
+			return url
+		}
+	}
+	return adapters.PublishedURL(item.State.Host, port)
+}
+
+// closeStaleForward closes the tunnels of an instance that has stopped or whose ports have changed
+func closeStaleForward(m MainModel, item InstanceItem) {
+	if f, ok := m.forwards[item.ID()]; ok && !slices.Equal(f.ports, forwardedPorts(item)) {
+		closeForward(m, item.ID())
+	}
+}
+
+// closeForward closes the tunnels of an instance
+func closeForward(m MainModel, id string) {
+	if f, ok := m.forwards[id]; ok {
+		f.cancel()
+		delete(m.forwards, id)
+	}
+}
+
+// closeRemovedForwards closes the tunnels of instances that aren't in the instance list anymore
+func closeRemovedForwards(m MainModel) {
+	for id := range m.forwards {
+		if findItem(m, id) < 0 {
+			closeForward(m, id)
+		}
+	}
+}
+
+// closeAllForwards closes the tunnels of all instances
+func closeAllForwards(m MainModel) {
+	for id := range m.forwards {
+		closeForward(m, id)
+	}
+}
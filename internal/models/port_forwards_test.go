@@ -0,0 +1,118 @@
+package models
+
+import (
+	"ccmanager/internal/adapters"
+	"errors"
+	"github.com/charmbracelet/bubbles/list"
+	"testing"
+)
+
+// newForwardTestModel returns a model listing running instances on a remote machine
+func newForwardTestModel(items ...InstanceItem) MainModel {
+	var listItems []list.Item
+	for _, item := range items {
+		listItems = append(listItems, item)
+	}
+	return MainModel{
+		List:            list.New(listItems, list.NewDefaultDelegate(), 0, 0),
+		forwards:        map[string]*portForward{},
+		pendingForwards: map[string]bool{},
+	}
+}
+
+// remoteItem returns a running instance on a remote machine with a CCC port
+func remoteItem(name string) InstanceItem {
+	return InstanceItem{Name: name, Path: "/p", State: adapters.CloudControlStatus{Host: "remote", Running: true, CCCPort: "8443"}}
+}
+
+// forwardedMsg returns the result of forwarding the ports of an item and a flag telling whether it was closed
+func forwardedMsg(item InstanceItem, ports ...string) (PortsForwardedMsg, *bool) {
+	closed := false
+	return PortsForwardedMsg{ID: item.ID(), Ports: ports, CCCPort: "8443", cancel: func() { closed = true }}, &closed
+}
+
+func TestForwardPortsPending(t *testing.T) {
+	item := remoteItem("a")
+	m := newForwardTestModel(item)
+	if ForwardPorts(m, item, false) == nil {
+		t.Fatal("the ports weren't forwarded")
+	}
+	if ForwardPorts(m, item, true) != nil {
+		t.Fatal("the ports were forwarded again while a forward is pending")
+	}
+	if !m.pendingForwards[item.ID()] {
+		t.Error("the CCC isn't opened after the pending forward")
+	}
+
+	msg, closed := forwardedMsg(item, "8443")
+	m, _ = PortsForwardedHandler(m, msg)
+	if _, ok := m.pendingForwards[item.ID()]; ok {
+		t.Error("the forward is still pending")
+	}
+	if *closed || m.forwards[item.ID()] == nil {
+		t.Error("the tunnels weren't kept")
+	}
+	if ForwardPorts(m, item, false) != nil {
+		t.Error("forwarded ports were forwarded again")
+	}
+}
+
+func TestPortsForwardedStale(t *testing.T) {
+	item := remoteItem("a")
+	m := newForwardTestModel(item)
+
+	msg, closed := forwardedMsg(remoteItem("gone"), "8443")
+	m, _ = PortsForwardedHandler(m, msg)
+	if !*closed || len(m.forwards) != 0 {
+		t.Error("the tunnels of an instance that left the list weren't closed")
+	}
+
+	msg, closed = forwardedMsg(item, "9443")
+	m, _ = PortsForwardedHandler(m, msg)
+	if !*closed || len(m.forwards) != 0 {
+		t.Error("the tunnels of changed ports weren't closed")
+	}
+}
+
+func TestCloseRemovedForwards(t *testing.T) {
+	kept, removed := remoteItem("kept"), remoteItem("removed")
+	m := newForwardTestModel(kept, removed)
+	msg, keptClosed := forwardedMsg(kept, "8443")
+	m, _ = PortsForwardedHandler(m, msg)
+	msg, removedClosed := forwardedMsg(removed, "8443")
+	m, _ = PortsForwardedHandler(m, msg)
+
+	m.List.RemoveItem(1)
+	closeRemovedForwards(m)
+	if *keptClosed || !*removedClosed {
+		t.Errorf("kept closed: %v, removed closed: %v, want only the removed instance closed", *keptClosed, *removedClosed)
+	}
+
+	m.Close()
+	if !*keptClosed || len(m.forwards) != 0 {
+		t.Error("the tunnels weren't closed with the model")
+	}
+}
+
+func TestFailedStatusKeepsForward(t *testing.T) {
+	item := remoteItem("a")
+	m := newForwardTestModel(item)
+	m.loader = newStatusLoader(1, false, false)
+	msg, closed := forwardedMsg(item, "8443")
+	m, _ = PortsForwardedHandler(m, msg)
+
+	failed := adapters.CloudControlStatus{Host: "remote", Error: errors.New("timeout")}
+	m, _ = InstanceLoadedHandler(m, InstanceLoadedMsg{BasePath: item.Path, Name: item.Name, State: failed, Failed: true})
+	if *closed {
+		t.Error("the tunnels were closed because of a failed status")
+	}
+	if got := m.List.Items()[0].(InstanceItem).State.Host; got != "remote" {
+		t.Errorf("host is %q after a failed status, want remote", got)
+	}
+
+	stopped := adapters.CloudControlStatus{Host: "remote"}
+	m, _ = InstanceLoadedHandler(m, InstanceLoadedMsg{BasePath: item.Path, Name: item.Name, State: stopped})
+	if !*closed {
+		t.Error("the tunnels of a stopped instance weren't closed")
+	}
+}
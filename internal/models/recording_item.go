@@ -0,0 +1,33 @@
+package models
+
+import (
+	"ccmanager/internal/recordings"
+	"fmt"
+	"github.com/charmbracelet/bubbles/list"
+	"github.com/docker/go-units"
+	"time"
+)
+
+// RecordingItem is an implementation of list.Item that describes a session recording in the recording list
+type RecordingItem struct {
+	recordings.Recording
+}
+
+var _ list.Item = RecordingItem{}
+
+func (r RecordingItem) FilterValue() string {
+	return fmt.Sprintf("%s %s", r.Started.Format(time.DateTime), r.Service())
+}
+
+func (r RecordingItem) Title() string {
+	return fmt.Sprintf("%s - %s", r.Started.Format(time.DateTime), r.Service())
+}
+
+func (r RecordingItem) Description() string {
+	return fmt.Sprintf(
+		"%s | %s | %s",
+		r.Header.Command,
+		r.Duration.Round(time.Second),
+		units.HumanSize(float64(r.Size)),
+	)
+}
@@ -0,0 +1,131 @@
+package models
+
+import (
+	"ccmanager/internal/adapters"
+	"context"
+	tea "github.com/charmbracelet/bubbletea"
+	"time"
+)
+
+// statusLoader fetches the status of instances in the background. At most parallelism instances are loaded at the
+// same time, the remaining ones wait in queue
+type statusLoader struct {
+	// parallelism is the maximum number of instances loaded at the same time
+	parallelism int
+	// queue holds the instances waiting to be loaded
+	queue []LoadInstanceMsg
+	// running holds the IDs of the instances currently loaded
+	running map[string]bool
+	// usage holds the IDs of the instances whose resource usage is currently loaded on its own
+	usage map[string]bool
+	// resources tells whether the resource usage of running instances is loaded as well
+	resources bool
+	// sessions tells whether the detachable sessions of running instances are loaded as well
+	sessions bool
+}
+
+func newStatusLoader(parallelism int, resources bool, sessions bool) *statusLoader {
+	return &statusLoader{
+		parallelism: max(parallelism, 1),
+		running:     map[string]bool{},
+		usage:       map[string]bool{},
+		resources:   resources,
+		sessions:    sessions,
+	}
+}
+
+// enqueue adds an instance to the queue unless it is already waiting
+func (l *statusLoader) enqueue(msg LoadInstanceMsg) {
+	for _, q := range l.queue {
+		if q == msg {
+			return
+		}
+	}
+	l.queue = append(l.queue, msg)
+}
+
+// next returns tea.Cmds loading queued instances until parallelism instances are loaded. Instances that are
+// currently loaded stay in the queue so results of the same instance can't overtake each other
+func (l *statusLoader) next(adapter adapters.BaseAdapter, timeouts adapters.Timeouts) tea.Cmd {
+	var cmds []tea.Cmd
+	var waiting []LoadInstanceMsg
+	for _, q := range l.queue {
+		id := q.BasePath + q.Name
+		if len(l.running) >= l.parallelism || l.running[id] {
+			waiting = append(waiting, q)
+			continue
+		}
+		l.running[id] = true
+		cmds = append(cmds, loadStatus(adapter, timeouts, q.BasePath, q.Name, l.resources, l.sessions))
+	}
+	l.queue = waiting
+	return tea.Batch(cmds...)
+}
+
+// done marks an instance as loaded
+func (l *statusLoader) done(basePath string, name string) {
+	delete(l.running, basePath+name)
+}
+
+// loadUsage returns a tea.Cmd fetching only the resource usage of a running instance, which changes without container
+// events. It returns nil if the instance or its resource usage is being loaded already
+func (l *statusLoader) loadUsage(adapter adapters.BaseAdapter, basePath string, name string) tea.Cmd {
+	id := basePath + name
+	if l.usage[id] || l.running[id] {
+		return nil
+	}
+	l.usage[id] = true
+	return func() tea.Msg {
+		msg := UsageLoadedMsg{BasePath: basePath, Name: name}
+		msg.Usage = loadUsage(adapter, basePath, name)
+		return msg
+	}
+}
+
+// usageDone marks the resource usage of an instance as loaded
+func (l *statusLoader) usageDone(basePath string, name string) {
+	delete(l.usage, basePath+name)
+}
+
+// usageTimeout limits fetching the resource usage of an instance. Docker samples the CPU usage for about a second or
+// two, so the usage gets its own timeout instead of sharing the one of the status
+const usageTimeout = 10 * time.Second
+
+// loadUsage fetches the resource usage of an instance or returns nil if it can't be fetched
+func loadUsage(adapter adapters.BaseAdapter, basePath string, name string) *adapters.ResourceUsage {
+	ctx, cancel := context.WithTimeout(context.Background(), usageTimeout)
+	defer cancel()
+	if usage, err := adapter.GetResourceUsage(ctx, basePath, name); err == nil {
+		return &usage
+	}
+	return nil
+}
+
+// loadStatus returns a tea.Cmd fetching the status and - if resources is set - the resource usage of an instance. If
+// sessions is set, the detachable sessions running in the instance are fetched as well
+func loadStatus(adapter adapters.BaseAdapter, timeouts adapters.Timeouts, basePath string, name string, resources bool, sessions bool) tea.Cmd {
+	return func() tea.Msg {
+		ctx, cancel := context.WithTimeout(context.Background(), timeouts.Status)
+		defer cancel()
+		msg := InstanceLoadedMsg{
+			BasePath: basePath,
+			Name:     name,
+		}
+		// The adapter keeps information like the host of the instance in the status if it fails
+		s, err := adapter.GetContainerStatus(ctx, basePath, name)
+		if err != nil {
+			s.Error = err
+			msg.Failed = true
+		}
+		msg.State = s
+		if resources && msg.State.Running {
+			msg.State.Usage = loadUsage(adapter, basePath, name)
+		}
+		if sessions && msg.State.Running {
+			if s, err := adapters.ListSessions(ctx, adapter, basePath, name); err == nil {
+				msg.Sessions = s
+			}
+		}
+		return msg
+	}
+}
@@ -0,0 +1,76 @@
+package models
+
+import (
+	"ccmanager/internal/adapters"
+	"ccmanager/internal/config"
+	"context"
+	"errors"
+	"github.com/charmbracelet/bubbles/list"
+	"testing"
+	"time"
+)
+
+// failingStatusAdapter fails to load statuses but reports the host of the instance like the Docker adapter does
+type failingStatusAdapter struct {
+	adapters.BaseAdapter
+}
+
+func (failingStatusAdapter) GetContainerStatus(context.Context, string, string) (adapters.CloudControlStatus, error) {
+	err := errors.New("timeout")
+	return adapters.CloudControlStatus{Host: "remote", Error: err}, err
+}
+
+func TestLoadStatusKeepsHostOnError(t *testing.T) {
+	msg := loadStatus(failingStatusAdapter{}, adapters.Timeouts{Status: time.Second}, "/p", "a", false, false)().(InstanceLoadedMsg)
+	if !msg.Failed || msg.State.Error == nil {
+		t.Error("the failed status wasn't reported")
+	}
+	if msg.State.Host != "remote" {
+		t.Errorf("host is %q, want the host returned by the adapter", msg.State.Host)
+	}
+}
+
+// usageAdapter reports a fixed resource usage and counts the status calls
+type usageAdapter struct {
+	adapters.BaseAdapter
+	statusCalls int
+}
+
+func (a *usageAdapter) GetContainerStatus(context.Context, string, string) (adapters.CloudControlStatus, error) {
+	a.statusCalls++
+	return adapters.CloudControlStatus{Running: true}, nil
+}
+
+func (a *usageAdapter) GetResourceUsage(context.Context, string, string) (adapters.ResourceUsage, error) {
+	return adapters.ResourceUsage{MemoryUsage: 42}, nil
+}
+
+func TestRefreshTickOnlyLoadsUsage(t *testing.T) {
+	adapter := &usageAdapter{}
+	item := InstanceItem{Name: "a", Path: "/p", State: adapters.CloudControlStatus{Running: true}}
+	m := MainModel{
+		List:        list.New([]list.Item{item}, list.NewDefaultDelegate(), 0, 0),
+		Adapter:     adapter,
+		Config:      config.Config{ShowResources: true, RefreshInterval: time.Hour},
+		loader:      newStatusLoader(1, true, false),
+		events:      &eventStream{},
+		loadedItems: true,
+	}
+	m, _ = RefreshTickHandler(m)
+	if len(m.loader.running) != 0 || len(m.loader.queue) != 0 || !m.loader.usage[item.ID()] {
+		t.Fatalf("loading %v and %v, usage %v, want only the usage to be loaded", m.loader.running, m.loader.queue,
+			m.loader.usage)
+	}
+	// The usage isn't loaded twice at the same time
+	if m.loader.loadUsage(adapter, item.Path, item.Name) != nil {
+		t.Error("the usage was loaded again while it is being loaded")
+	}
+
+	m, _ = UsageLoadedHandler(m, UsageLoadedMsg{BasePath: item.Path, Name: item.Name, Usage: loadUsage(adapter, item.Path, item.Name)})
+	if u := m.List.Items()[0].(InstanceItem).State.Usage; u == nil || u.MemoryUsage != 42 {
+		t.Errorf("usage is %v, want the loaded usage", u)
+	}
+	if len(m.loader.usage) != 0 || adapter.statusCalls != 0 {
+		t.Errorf("usage loads %v and %d status calls left, want none", m.loader.usage, adapter.statusCalls)
+	}
+}
@@ -0,0 +1,258 @@
+package models
+
+import (
+	"ccmanager/internal/adapters"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/tonistiigi/vt100"
+	"image/color"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// sessionInputBuffer is the number of key presses buffered while a session doesn't read its input
+const sessionInputBuffer = 256
+
+// sessionTitleLength is the maximum length of the command shown in the tab of a session
+const sessionTitleLength = 20
+
+// ansiColors holds the colours of the virtual terminal by their ANSI colour index
+var ansiColors = []color.RGBA{
+	vt100.Black,
+	vt100.Red,
+	vt100.Green,
+	vt100.Yellow,
+	vt100.Blue,
+	vt100.Magenta,
+	vt100.Cyan,
+	vt100.White,
+}
+
+// keySequences holds the bytes sent to a session for special keys
+var keySequences = map[tea.KeyType]string{
+	tea.KeyUp:        "\x1b[A",
+	tea.KeyDown:      "\x1b[B",
+	tea.KeyRight:     "\x1b[C",
+	tea.KeyLeft:      "\x1b[D",
+	tea.KeyShiftTab:  "\x1b[Z",
+	tea.KeyHome:      "\x1b[H",
+	tea.KeyEnd:       "\x1b[F",
+	tea.KeyPgUp:      "\x1b[5~",
+	tea.KeyPgDown:    "\x1b[6~",
+	tea.KeyDelete:    "\x1b[3~",
+	tea.KeyInsert:    "\x1b[2~",
+	tea.KeySpace:     " ",
+	tea.KeyCtrlUp:    "\x1b[1;5A",
+	tea.KeyCtrlDown:  "\x1b[1;5B",
+	tea.KeyCtrlRight: "\x1b[1;5C",
+	tea.KeyCtrlLeft:  "\x1b[1;5D",
+	tea.KeyF1:        "\x1bOP",
+	tea.KeyF2:        "\x1bOQ",
+	tea.KeyF3:        "\x1bOR",
+	tea.KeyF4:        "\x1bOS",
+	tea.KeyF5:        "\x1b[15~",
+	tea.KeyF6:        "\x1b[17~",
+	tea.KeyF7:        "\x1b[18~",
+	tea.KeyF8:        "\x1b[19~",
+	tea.KeyF9:        "\x1b[20~",
+	tea.KeyF10:       "\x1b[21~",
+	tea.KeyF11:       "\x1b[23~",
+	tea.KeyF12:       "\x1b[24~",
+}
+
+// terminalSession is an exec session in an instance that is rendered into a virtual terminal shown as a pane next to
+// the instance list
+type terminalSession struct {
+	// item is the instance the session runs in
+	item InstanceItem
+	// title is shown in the tab of the session
+	title string
+	// session is the name of the detachable session the tab is attached to. It is empty if the session isn't
+	// detachable
+	session string
+	// detached tells whether the tab is detaching from its detachable session
+	detached bool
+	// exec is the exec session
+	exec *adapters.ContainerExec
+	// lock guards screen, which is written by the exec session and read when rendering
+	lock sync.Mutex
+	// screen is the virtual terminal holding the output of the session
+	screen *vt100.VT100
+	// stdin is read by the exec session
+	stdin *io.PipeReader
+	// keys holds the key presses waiting to be sent to the session
+	keys chan []byte
+	// updated receives a value when the screen has changed
+	updated chan struct{}
+	// done is closed when the session has ended
+	done chan struct{}
+	// err holds the error of the ended session
+	err error
+	// ended tells whether the end of the session has been handled
+	ended bool
+}
+
+// newTerminalSession creates a session running the exec session on a virtual terminal of the given size
+func newTerminalSession(c *adapters.ContainerExec, item InstanceItem, title string, width int, height int) *terminalSession {
+	s := &terminalSession{
+		item:    item,
+		title:   title,
+		exec:    c,
+		screen:  vt100.NewVT100(max(height, 1), max(width, 1)),
+		keys:    make(chan []byte, sessionInputBuffer),
+		updated: make(chan struct{}, 1),
+		done:    make(chan struct{}),
+	}
+	stdin, input := io.Pipe()
+	s.stdin = stdin
+	c.SetStdin(stdin)
+	c.SetStdout(s)
+	go func() {
+		for {
+			select {
+			case k := <-s.keys:
+				if _, err := input.Write(k); err != nil {
+					return
+				}
+			case <-s.done:
+				return
+			}
+		}
+	}()
+	return s
+}
+
+// start runs the exec session in the background and returns a tea.Cmd waiting for the first change of the session
+func (s *terminalSession) start() tea.Cmd {
+	go func() {
+		s.err = s.exec.Run()
+		_ = s.stdin.Close()
+		close(s.done)
+	}()
+	return s.Next()
+}
+
+// Next returns a tea.Cmd that waits for the next change of the screen or the end of the session
+func (s *terminalSession) Next() tea.Cmd {
+	return func() tea.Msg {
+		select {
+		case <-s.updated:
+			return SessionUpdatedMsg{session: s}
+		case <-s.done:
+			return SessionEndedMsg{session: s, Err: s.err}
+		}
+	}
+}
+
+// Write writes output of the exec session to the virtual terminal
+func (s *terminalSession) Write(p []byte) (int, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	defer func() {
+		// The virtual terminal doesn't support all control sequences. Broken output must not end the application
+		_ = recover()
+	}()
+	_, _ = s.screen.Write(p)
+	select {
+	case s.updated <- struct{}{}:
+	default:
+	}
+	return len(p), nil
+}
+
+// send sends a key press to the session. Key presses are dropped if the session doesn't read them
+func (s *terminalSession) send(msg tea.KeyMsg) {
+	var b []byte
+	switch {
+	case msg.Type == tea.KeyRunes:
+		b = []byte(string(msg.Runes))
+	case msg.Type >= 0:
+		// Control keys are sent as they are
+		b = []byte{byte(msg.Type)}
+	default:
+		b = []byte(keySequences[msg.Type])
+	}
+	if len(b) == 0 {
+		return
+	}
+	if msg.Alt {
+		b = append([]byte{0x1b}, b...)
+	}
+	select {
+	case s.keys <- b:
+	default:
+	}
+}
+
+// resize resizes the virtual terminal and the tty of the session
+func (s *terminalSession) resize(width int, height int) {
+	width, height = max(width, 1), max(height, 1)
+	s.lock.Lock()
+	if width != s.screen.Width || height != s.screen.Height {
+		s.screen.Resize(height, width)
+		// Resize keeps the formats of removed lines, which breaks growing the terminal again
+		s.screen.Format = s.screen.Format[:s.screen.Height]
+		s.screen.Cursor.Y = min(s.screen.Cursor.Y, s.screen.Height-1)
+		s.screen.Cursor.X = min(s.screen.Cursor.X, s.screen.Width-1)
+	}
+	s.lock.Unlock()
+	s.exec.Resize(uint(width), uint(height))
+}
+
+// View renders the virtual terminal. The cursor is shown if focused is set
+func (s *terminalSession) View(focused bool) string {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	var lines []string
+	for y, row := range s.screen.Content {
+		var b strings.Builder
+		var current vt100.Format
+		for x, r := range row {
+			f := s.screen.Format[y][x]
+			if focused && !s.ended && y == s.screen.Cursor.Y && x == s.screen.Cursor.X {
+				f.Inverse = !f.Inverse
+			}
+			if x == 0 || f != current {
+				b.WriteString(sgrSequence(f))
+				current = f
+			}
+			b.WriteRune(r)
+		}
+		b.WriteString("\x1b[0m")
+		lines = append(lines, b.String())
+	}
+	return strings.Join(lines, "\n")
+}
+
+// sgrSequence returns the escape sequence selecting the format of a cell of the virtual terminal
+func sgrSequence(f vt100.Format) string {
+	codes := []string{"0"}
+	switch f.Intensity {
+	case vt100.Bright:
+		codes = append(codes, "1")
+	case vt100.Dim:
+		codes = append(codes, "2")
+	}
+	if f.Underscore {
+		codes = append(codes, "4")
+	}
+	if f.Blink {
+		codes = append(codes, "5")
+	}
+	if f.Inverse != f.Negative {
+		codes = append(codes, "7")
+	}
+	if f.Conceal {
+		codes = append(codes, "8")
+	}
+	for i, c := range ansiColors {
+		if f.Fg == c {
+			codes = append(codes, strconv.Itoa(30+i))
+		}
+		if f.Bg == c {
+			codes = append(codes, strconv.Itoa(40+i))
+		}
+	}
+	return "\x1b[" + strings.Join(codes, ";") + "m"
+}
@@ -0,0 +1,97 @@
+package recordings
+
+import (
+	"fmt"
+	"github.com/moby/term"
+	"github.com/muesli/cancelreader"
+	"io"
+	"time"
+)
+
+// maxIdleTime is the longest pause between two events during a replay
+const maxIdleTime = 2 * time.Second
+
+// Player replays the output of a recording in the terminal with the recorded timing. It implements tea.ExecCommand
+type Player struct {
+	// path is the path of the recording
+	path   string
+	stdin  io.Reader
+	stdout io.Writer
+}
+
+// NewPlayer creates a player for the recording at path
+func NewPlayer(path string) *Player {
+	return &Player{path: path}
+}
+
+// Run replays the recording. Pauses longer than maxIdleTime are shortened. A key press stops the replay, after the
+// replay another key press returns
+func (p *Player) Run() error {
+	_, events, err := Load(p.path)
+	if err != nil {
+		return err
+	}
+
+	fd, isTerminal := term.GetFdInfo(p.stdin)
+	if isTerminal {
+		originalState, err := term.SetRawTerminal(fd)
+		if err != nil {
+			return fmt.Errorf("can not set terminal to raw: %w", err)
+		}
+		defer func() {
+			_ = term.RestoreTerminal(fd, originalState)
+		}()
+	}
+
+	input, err := cancelreader.NewReader(p.stdin)
+	if err != nil {
+		return fmt.Errorf("can not read from terminal: %w", err)
+	}
+	defer input.Close()
+	keyPressed := make(chan struct{})
+	go func() {
+		defer close(keyPressed)
+		_, _ = input.Read(make([]byte, 16))
+	}()
+
+	last := 0.0
+	stopped := false
+	for _, e := range events {
+		if e.Type != EventOutput {
+			continue
+		}
+		delay := min(time.Duration((e.Time-last)*float64(time.Second)), maxIdleTime)
+		last = e.Time
+		select {
+		case <-time.After(delay):
+		case <-keyPressed:
+			stopped = true
+		}
+		if stopped {
+			break
+		}
+		if _, err := io.WriteString(p.stdout, e.Data); err != nil {
+			if input.Cancel() {
+				<-keyPressed
+			}
+			return fmt.Errorf("can not replay recording: %w", err)
+		}
+	}
+
+	if stopped {
+		return nil
+	}
+	_, _ = io.WriteString(p.stdout, "\r\nReplay finished. Press any key to return.")
+	<-keyPressed
+	return nil
+}
+
+func (p *Player) SetStdin(reader io.Reader) {
+	p.stdin = reader
+}
+
+func (p *Player) SetStdout(writer io.Writer) {
+	p.stdout = writer
+}
+
+func (p *Player) SetStderr(_ io.Writer) {}
@@ -0,0 +1,338 @@
+package recordings
+
+// Recordings of exec sessions in the asciicast v2 format (see
+// https://docs.asciinema.org/manual/asciicast/v2/)
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// fileExtension is the extension of recording files
+const fileExtension = ".cast"
+
+// The event types of asciicast v2 files
+const (
+	// EventOutput is data written to the terminal
+	EventOutput = "o"
+	// EventInput is data read from the terminal
+	EventInput = "i"
+	// EventResize is a resize of the terminal. The data is formatted as <width>x<height>
+	EventResize = "r"
+)
+
+// Header is the first line of an asciicast v2 file
+type Header struct {
+	Version   int               `json:"version"`
+	Width     uint              `json:"width"`
+	Height    uint              `json:"height"`
+	Timestamp int64             `json:"timestamp,omitempty"`
+	Command   string            `json:"command,omitempty"`
+	Title     string            `json:"title,omitempty"`
+	Env       map[string]string `json:"env,omitempty"`
+}
+
+// Event is an event line of an asciicast v2 file
+type Event struct {
+	// Time is the time of the event in seconds since the start of the recording
+	Time float64
+	// Type is one of EventOutput, EventInput or EventResize
+	Type string
+	// Data holds the data of the event
+	Data string
+}
+
+func (e Event) MarshalJSON() ([]byte, error) {
+	return json.Marshal([]interface{}{e.Time, e.Type, e.Data})
+}
+
+func (e *Event) UnmarshalJSON(data []byte) error {
+	var fields []interface{}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+	if len(fields) != 3 {
+		return fmt.Errorf("invalid event %s", string(data))
+	}
+	t, ok := fields[0].(float64)
+	eventType, typeOk := fields[1].(string)
+	eventData, dataOk := fields[2].(string)
+	if !ok || !typeOk || !dataOk {
+		return fmt.Errorf("invalid event %s", string(data))
+	}
+	e.Time, e.Type, e.Data = t, eventType, eventData
+	return nil
+}
+
+// timeFormat is the format of the start time in the names of recording files
+const timeFormat = "20060102-150405.000"
+
+// tailSize is the number of bytes at the end of a recording file searched for the last event
+const tailSize = 64 * 1024
+
+// maxNameAttempts is the number of names tried to create a recording file whose name is already used
+const maxNameAttempts = 100
+
+// Path returns the path of a new recording of a session in a service of an instance
+func Path(dir string, instance string, service string, started time.Time) string {
+	return filepath.Join(dir, instance, fmt.Sprintf("%s-%s%s", started.Format(timeFormat), service, fileExtension))
+}
+
+// numberedPath adds a number to the start time in the name of a recording file, so sessions started at the same
+// time get different files
+func numberedPath(path string, number int) string {
+	dir, name := filepath.Split(path)
+	if parts := strings.SplitN(name, "-", 3); len(parts) == 3 {
+		return filepath.Join(dir, fmt.Sprintf("%s-%s.%d-%s", parts[0], parts[1], number, parts[2]))
+	}
+	return filepath.Join(dir, fmt.Sprintf("%d-%s", number, name))
+}
+
+// Recorder writes the events of a session into an asciicast v2 file. Events are written as they happen, so the file
+// is usable even if CCmanager is terminated during the session. Recording stops at the first failed write
+type Recorder struct {
+	lock  sync.Mutex
+	file  *os.File
+	start time.Time
+	// err holds the error of the first failed write
+	err error
+}
+
+// Create creates the recording file at path including its directory and writes the header. If the file already
+// exists, a number is added to the start time in its name
+func Create(path string, header Header) (*Recorder, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, fmt.Errorf("can not create recording directory: %w", err)
+	}
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	for number := 2; errors.Is(err, os.ErrExist) && number <= maxNameAttempts; number++ {
+		file, err = os.OpenFile(numberedPath(path, number), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("can not create recording: %w", err)
+	}
+	r := &Recorder{file: file, start: time.Now()}
+	header.Version = 2
+	header.Timestamp = r.start.Unix()
+	if err := r.writeLine(header); err != nil {
+		_ = file.Close()
+		return nil, err
+	}
+	return r, nil
+}
+
+// Record writes an event with the current time
+func (r *Recorder) Record(eventType string, data string) error {
+	return r.writeLine(Event{
+		Time: time.Since(r.start).Seconds(),
+		Type: eventType,
+		Data: data,
+	})
+}
+
+// Resize records a resize of the terminal
+func (r *Recorder) Resize(width uint, height uint) error {
+	return r.Record(EventResize, fmt.Sprintf("%dx%d", width, height))
+}
+
+// Err returns the error that stopped the recording or nil if all events have been recorded
+func (r *Recorder) Err() error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return r.err
+}
+
+// Writer returns a writer that records everything written to it as events of the given type. Writes never fail, so
+// a failing recording doesn't interrupt the session it is teed from. The error is returned by Err instead
+func (r *Recorder) Writer(eventType string) io.Writer {
+	return &eventWriter{recorder: r, eventType: eventType}
+}
+
+// Close closes the recording file
+func (r *Recorder) Close() error {
+	return r.file.Close()
+}
+
+// writeLine writes a JSON encoded line into the recording file
+func (r *Recorder) writeLine(v interface{}) error {
+	line, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("can not encode recording: %w", err)
+	}
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if r.err != nil {
+		return r.err
+	}
+	if _, err := r.file.Write(append(line, '\n')); err != nil {
+		r.err = fmt.Errorf("can not write recording: %w", err)
+		return r.err
+	}
+	return nil
+}
+
+// eventWriter records the data written to it. Multibyte characters split between two writes are recorded with the
+// second write, because event data has to be valid UTF-8
+type eventWriter struct {
+	recorder  *Recorder
+	eventType string
+	pending   []byte
+}
+
+func (w *eventWriter) Write(p []byte) (int, error) {
+	data := append(w.pending, p...)
+	complete := len(data)
+	// Look for the start of an incomplete character in the last three bytes
+	for i := len(data) - 1; i >= 0 && i >= len(data)-utf8.UTFMax+1; i-- {
+		if utf8.RuneStart(data[i]) {
+			if !utf8.FullRune(data[i:]) {
+				complete = i
+			}
+			break
+		}
+	}
+	w.pending = append([]byte(nil), data[complete:]...)
+	if complete == 0 {
+		return len(p), nil
+	}
+	// Errors are kept by the recorder
+	_ = w.recorder.Record(w.eventType, string(data[:complete]))
+	return len(p), nil
+}
+
+// Recording describes a recorded session
+type Recording struct {
+	// Path is the path of the recording file
+	Path string
+	// Header is the header of the recording
+	Header Header
+	// Started is the time the session started
+	Started time.Time
+	// Duration is the duration of the session
+	Duration time.Duration
+	// Size is the size of the recording file in bytes
+	Size int64
+}
+
+// Service returns the service the session ran in
+func (r Recording) Service() string {
+	name := strings.TrimSuffix(filepath.Base(r.Path), fileExtension)
+	// The name starts with the date and time of the session (20060102-150405.000-<service>)
+	if parts := strings.SplitN(name, "-", 3); len(parts) == 3 {
+		return parts[2]
+	}
+	return ""
+}
+
+// List returns the recordings of an instance, the newest one first. A missing recording directory results in an
+// empty list. Files that can't be read or are no asciicast v2 recordings are skipped. Only the header and the end of
+// every file are read
+func List(dir string, instance string) ([]Recording, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, instance, "*"+fileExtension))
+	if err != nil {
+		return nil, fmt.Errorf("can not list recordings: %w", err)
+	}
+	var recordings []Recording
+	for _, path := range paths {
+		if r, err := readRecording(path); err == nil {
+			recordings = append(recordings, r)
+		}
+	}
+	sort.SliceStable(recordings, func(i, j int) bool {
+		return recordings[i].Started.After(recordings[j].Started)
+	})
+	return recordings, nil
+}
+
+// readRecording reads the header of a recording and takes its duration from the last complete event at the end of
+// the file
+func readRecording(path string) (Recording, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return Recording{}, fmt.Errorf("can not read recording: %w", err)
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return Recording{}, fmt.Errorf("can not read recording: %w", err)
+	}
+	header, err := readHeader(bufio.NewReader(file), path)
+	if err != nil {
+		return Recording{}, err
+	}
+	r := Recording{
+		Path:    path,
+		Header:  header,
+		Started: time.Unix(header.Timestamp, 0),
+		Size:    info.Size(),
+	}
+
+	offset := max(info.Size()-tailSize, 0)
+	tail := make([]byte, info.Size()-offset)
+	if _, err := file.ReadAt(tail, offset); err != nil && !errors.Is(err, io.EOF) {
+		return r, nil
+	}
+	// Only complete lines are events, the first line of the tail may be cut off and the last one may be incomplete
+	lines := strings.Split(string(tail), "\n")
+	for i := len(lines) - 2; i >= 0; i-- {
+		var e Event
+		if err := json.Unmarshal([]byte(lines[i]), &e); err == nil {
+			r.Duration = time.Duration(e.Time * float64(time.Second))
+			break
+		}
+	}
+	return r, nil
+}
+
+// readHeader reads the header line of a recording
+func readHeader(reader *bufio.Reader, path string) (Header, error) {
+	var header Header
+	line, err := reader.ReadBytes('\n')
+	if err != nil && !errors.Is(err, io.EOF) {
+		return header, fmt.Errorf("can not read recording: %w", err)
+	}
+	if err := json.Unmarshal(line, &header); err != nil || header.Version != 2 {
+		return header, fmt.Errorf("%s is no asciicast v2 recording", path)
+	}
+	return header, nil
+}
+
+// Load reads the header and the events of a recording. An incomplete last line, e.g. of a recording that is still
+// running, is ignored
+func Load(path string) (Header, []Event, error) {
+	var header Header
+	file, err := os.Open(path)
+	if err != nil {
+		return header, nil, fmt.Errorf("can not read recording: %w", err)
+	}
+	defer file.Close()
+	reader := bufio.NewReader(file)
+	if header, err = readHeader(reader, path); err != nil {
+		return header, nil, err
+	}
+	var events []Event
+	for {
+		line, err := reader.ReadBytes('\n')
+		if errors.Is(err, io.EOF) {
+			return header, events, nil
+		}
+		if err != nil {
+			return header, nil, fmt.Errorf("can not read recording: %w", err)
+		}
+		var e Event
+		if err := json.Unmarshal(line, &e); err != nil {
+			return header, nil, fmt.Errorf("can not read recording %s: %w", path, err)
+		}
+		events = append(events, e)
+	}
+}
@@ -0,0 +1,104 @@
+package recordings
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestCreateUniqueNames(t *testing.T) {
+	dir := t.TempDir()
+	path := Path(dir, "test", "cli", time.Date(2024, 1, 2, 13, 23, 37, 0, time.UTC))
+	for i := 0; i < 3; i++ {
+		r, err := Create(path, Header{Width: 80, Height: 24})
+		if err != nil {
+			t.Fatal(err)
+		}
+		_ = r.Close()
+	}
+
+	recordings, err := List(dir, "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(recordings) != 3 {
+		t.Fatalf("got %d recordings, want 3", len(recordings))
+	}
+	for _, r := range recordings {
+		if r.Service() != "cli" {
+			t.Errorf("service of %s is %q, want cli", r.Path, r.Service())
+		}
+	}
+}
+
+func TestList(t *testing.T) {
+	dir := t.TempDir()
+	r, err := Create(Path(dir, "test", "cli", time.Now()), Header{Width: 80, Height: 24})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, data := range []string{"first", "second"} {
+		if err := r.Record(EventOutput, data); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// An incomplete last line as written by a running session
+	if _, err := r.file.WriteString(`[99.5, "o", "inc`); err != nil {
+		t.Fatal(err)
+	}
+	_ = r.Close()
+	if err := os.WriteFile(filepath.Join(dir, "test", "20240102-132337.000-broken.cast"), []byte("no recording"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "test", "20240102-132338.000-empty.cast"), nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	recordings, err := List(dir, "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(recordings) != 1 {
+		t.Fatalf("got %d recordings, want only the valid one", len(recordings))
+	}
+	_, events, err := Load(recordings[0].Path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 2 {
+		t.Fatalf("got %d events, want 2", len(events))
+	}
+	if want := time.Duration(events[1].Time * float64(time.Second)); recordings[0].Duration != want {
+		t.Errorf("duration is %s, want %s", recordings[0].Duration, want)
+	}
+	if recordings[0].Header.Width != 80 || recordings[0].Size == 0 {
+		t.Errorf("got recording %+v", recordings[0])
+	}
+}
+
+func TestListMissingDirectory(t *testing.T) {
+	recordings, err := List(filepath.Join(t.TempDir(), "missing"), "test")
+	if err != nil || len(recordings) != 0 {
+		t.Errorf("List() = %v, %v, want no recordings", recordings, err)
+	}
+}
+
+func TestWriterKeepsWritingAfterFailure(t *testing.T) {
+	r, err := Create(Path(t.TempDir(), "test", "cli", time.Now()), Header{Width: 80, Height: 24})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// A closed file fails every write like a full disk
+	_ = r.Close()
+
+	w := r.Writer(EventOutput)
+	for i := 0; i < 2; i++ {
+		if n, err := w.Write([]byte("data")); n != 4 || err != nil {
+			t.Fatalf("write %d returned %d, %v, want 4, nil", i, n, err)
+		}
+	}
+	if r.Err() == nil {
+		t.Error("the failed write wasn't reported")
+	}
+}
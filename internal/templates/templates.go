@@ -0,0 +1,137 @@
+package templates
+
+// Templates used to create new CloudControl instances
+
+import (
+	"bytes"
+	"embed"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"text/template"
+)
+
+//go:embed compose/*.yaml.tmpl
+var embedded embed.FS
+
+// Flavour describes a CloudControl flavour
+type Flavour struct {
+	// Name is the name of the flavour as used in the image name
+	Name string
+	// Image is the image of the flavour without a tag
+	Image string
+	// Environment holds the environment variables the flavour requires
+	Environment []string
+}
+
+// Flavours holds all CloudControl flavours instances can be created with
+var Flavours = []Flavour{
+	{
+		Name:        "azure",
+		Image:       "ghcr.io/dodevops/cloudcontrol-azure",
+		Environment: []string{"AZ_SUBSCRIPTION", "AZ_TENANTID"},
+	},
+	{
+		Name:        "aws",
+		Image:       "ghcr.io/dodevops/cloudcontrol-aws",
+		Environment: []string{"AWS_ACCESS_KEY_ID", "AWS_SECRET_ACCESS_KEY", "AWS_DEFAULT_REGION"},
+	},
+	{
+		Name:        "gcloud",
+		Image:       "ghcr.io/dodevops/cloudcontrol-gcloud",
+		Environment: []string{"GCLOUD_PROJECTID"},
+	},
+	{
+		Name:        "tanzu",
+		Image:       "ghcr.io/dodevops/cloudcontrol-tanzu",
+		Environment: []string{"TANZU_HOST", "TANZU_USERNAME"},
+	},
+	{
+		Name:  "simple",
+		Image: "ghcr.io/dodevops/cloudcontrol-simple",
+	},
+}
+
+// Values holds the values used to render the template of a new instance
+type Values struct {
+	// Name is the name of the instance
+	Name string
+	// Flavour is the flavour of the instance
+	Flavour Flavour
+	// Image is the image of the instance without a tag
+	Image string
+	// Tag is the image tag of the instance
+	Tag string
+	// Environment holds the environment variables of the instance
+	Environment map[string]string
+}
+
+// DefaultTemplateDir returns the user directory that can hold templates overriding the embedded ones
+func DefaultTemplateDir() string {
+	if d, err := os.UserConfigDir(); err == nil {
+		return filepath.Join(d, "ccmanager", "templates")
+	}
+	return ""
+}
+
+// Render renders the docker compose file for a new instance. If templateDir contains a file named
+// <flavour>.yaml.tmpl, it is used instead of the embedded template
+func Render(templateDir string, values Values) ([]byte, error) {
+	fileName := fmt.Sprintf("%s.yaml.tmpl", values.Flavour.Name)
+	var content []byte
+	if c, err := os.ReadFile(filepath.Join(templateDir, fileName)); templateDir != "" && err == nil {
+		content = c
+	} else if c, err := embedded.ReadFile(fmt.Sprintf("compose/%s", fileName)); err == nil {
+		content = c
+	} else {
+		return nil, fmt.Errorf("no template found for flavour %s", values.Flavour.Name)
+	}
+
+	t, err := template.New(fileName).Funcs(template.FuncMap{"quote": strconv.Quote}).Parse(string(content))
+	if err != nil {
+		return nil, fmt.Errorf("can not parse template %s: %w", fileName, err)
+	}
+	var b bytes.Buffer
+	if err := t.Execute(&b, values); err != nil {
+		return nil, fmt.Errorf("can not render template %s: %w", fileName, err)
+	}
+	return b.Bytes(), nil
+}
+
+// namePattern matches the names docker compose accepts as project names
+var namePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+
+// ValidateName returns an error if name can not be used as instance name. Instance names are used as compose project
+// names, so they must start with a lowercase letter or digit and only contain lowercase letters, digits, dashes and
+// underscores
+func ValidateName(name string) error {
+	if !namePattern.MatchString(name) {
+		return fmt.Errorf("invalid instance name %q, please only use lowercase letters, digits, dashes and underscores", name)
+	}
+	return nil
+}
+
+// CreateInstance creates a new instance folder in basePath and writes its docker compose file
+func CreateInstance(templateDir string, basePath string, values Values) error {
+	if err := ValidateName(values.Name); err != nil {
+		return err
+	}
+	folder := filepath.Join(basePath, values.Name)
+	if _, err := os.Stat(folder); err == nil {
+		return fmt.Errorf("%s already exists", folder)
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	content, err := Render(templateDir, values)
+	if err != nil {
+		return err
+	}
+	if err := os.Mkdir(folder, 0755); err != nil {
+		return fmt.Errorf("can not create instance folder: %w", err)
+	}
+	return os.WriteFile(filepath.Join(folder, "docker-compose.yaml"), content, 0600)
+}
@@ -0,0 +1,249 @@
+package internal
+
+import (
+	"errors"
+	"fmt"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/muesli/termenv"
+	"gopkg.in/yaml.v3"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ThemeColors holds the colours and attributes of a styled element. Colours are given as hex codes or ANSI colour
+// numbers. Empty colours use the colour of the terminal
+type ThemeColors struct {
+	Foreground string `yaml:"foreground,omitempty"`
+	Background string `yaml:"background,omitempty"`
+	Bold       bool   `yaml:"bold,omitempty"`
+}
+
+// style applies the colours to a style
+func (c ThemeColors) style(s lipgloss.Style) lipgloss.Style {
+	if c.Foreground != "" {
+		s = s.Foreground(lipgloss.Color(c.Foreground))
+	}
+	if c.Background != "" {
+		s = s.Background(lipgloss.Color(c.Background))
+	}
+	return s.Bold(c.Bold)
+}
+
+// Theme holds the colours used by CCmanager
+type Theme struct {
+	// Base is the name of the built-in theme a user theme is based on
+	Base string `yaml:"base,omitempty"`
+	// NoColor disables all colours
+	NoColor bool `yaml:"noColor,omitempty"`
+	// Title is used for titles
+	Title ThemeColors `yaml:"title"`
+	// Item is used for the title of instances in the list
+	Item ThemeColors `yaml:"item"`
+	// ItemDescription is used for the description of instances in the list
+	ItemDescription ThemeColors `yaml:"itemDescription"`
+	// SelectedItem is used for the title of the selected instance and the focused field of forms
+	SelectedItem ThemeColors `yaml:"selectedItem"`
+	// SelectedItemDescription is used for the description and the border of the selected instance
+	SelectedItemDescription ThemeColors `yaml:"selectedItemDescription"`
+	// SelectedChoice is used for the selected choice of confirmations
+	SelectedChoice ThemeColors `yaml:"selectedChoice"`
+	// Border is used for the border of info boxes
+	Border ThemeColors `yaml:"border"`
+	// Error is used for error messages
+	Error ThemeColors `yaml:"error"`
+	// StatusLine is used for the status line of the info and log screens
+	StatusLine ThemeColors `yaml:"statusLine"`
+	// LogMatch is used for search matches in the log screen
+	LogMatch ThemeColors `yaml:"logMatch"`
+	// LogCurrentMatch is used for the selected search match in the log screen
+	LogCurrentMatch ThemeColors `yaml:"logCurrentMatch"`
+	// Labels is used for the labels of instances (AWS, Azure, GCP, Tanzu, Simple, Err, Update, Session and Host)
+	Labels map[string]ThemeColors `yaml:"labels"`
+}
+
+// labelTexts holds the texts of the instance labels
+var labelTexts = map[string]string{
+	"AWS":     "AWS",
+	"Azure":   "Azure",
+	"GCP":     "GCP",
+	"Tanzu":   "Tanzu",
+	"Simple":  "Simple",
+	"Err":     "Error",
+	"Update":  "Update available",
+	"Session": "Live session",
+}
+
+// defaultLabels holds the label colours of the dark and light themes
+var defaultLabels = map[string]ThemeColors{
+	"AWS":     {Foreground: "#ffffff", Background: "#ff9900"},
+	"Azure":   {Foreground: "#ffffff", Background: "#33b2e7"},
+	"GCP":     {Foreground: "#ffffff", Background: "#f04943"},
+	"Tanzu":   {Foreground: "#ffffff", Background: "#82c13d"},
+	"Simple":  {Foreground: "#ffffff", Background: "#aaaaaa"},
+	"Err":     {Foreground: "#ffffff", Background: "#ff0000"},
+	"Update":  {Foreground: "#ffffff", Background: "#00aa00"},
+	"Session": {Foreground: "#ffffff", Background: "#7a5cfa"},
+	"Host":    {Foreground: "#ffffff", Background: "#1f6feb"},
+}
+
+// Themes holds the built-in themes
+var Themes = map[string]Theme{
+	"dark": {
+		Title:                   ThemeColors{Foreground: "#FFFDF5", Background: "#25A065"},
+		Item:                    ThemeColors{Foreground: "#dddddd"},
+		ItemDescription:         ThemeColors{Foreground: "#777777"},
+		SelectedItem:            ThemeColors{Foreground: "#EE6FF8"},
+		SelectedItemDescription: ThemeColors{Foreground: "#AD58B4"},
+		SelectedChoice:          ThemeColors{Foreground: "#ffffff"},
+		Error:                   ThemeColors{Foreground: "#B5041a"},
+		StatusLine:              ThemeColors{Foreground: "#ffffff", Background: "#aa0000"},
+		LogMatch:                ThemeColors{Foreground: "#000000", Background: "#ffff00"},
+		LogCurrentMatch:         ThemeColors{Foreground: "#000000", Background: "#ff9900"},
+		Labels:                  defaultLabels,
+	},
+	"light": {
+		Title:                   ThemeColors{Foreground: "#FFFDF5", Background: "#25A065"},
+		Item:                    ThemeColors{Foreground: "#1a1a1a"},
+		ItemDescription:         ThemeColors{Foreground: "#A49FA5"},
+		SelectedItem:            ThemeColors{Foreground: "#EE6FF8"},
+		SelectedItemDescription: ThemeColors{Foreground: "#F793FF"},
+		SelectedChoice:          ThemeColors{Foreground: "#000000", Bold: true},
+		Error:                   ThemeColors{Foreground: "#B5041a"},
+		StatusLine:              ThemeColors{Foreground: "#ffffff", Background: "#aa0000"},
+		LogMatch:                ThemeColors{Foreground: "#000000", Background: "#ffff00"},
+		LogCurrentMatch:         ThemeColors{Foreground: "#000000", Background: "#ff9900"},
+		Labels:                  defaultLabels,
+	},
+	"high-contrast": {
+		Title:                   ThemeColors{Foreground: "#000000", Background: "#ffffff", Bold: true},
+		Item:                    ThemeColors{Foreground: "#ffffff"},
+		ItemDescription:         ThemeColors{Foreground: "#ffffff"},
+		SelectedItem:            ThemeColors{Foreground: "#ffff00", Bold: true},
+		SelectedItemDescription: ThemeColors{Foreground: "#ffff00"},
+		SelectedChoice:          ThemeColors{Foreground: "#ffff00", Bold: true},
+		Border:                  ThemeColors{Foreground: "#ffffff"},
+		Error:                   ThemeColors{Foreground: "#ff5555", Bold: true},
+		StatusLine:              ThemeColors{Foreground: "#000000", Background: "#ffffff"},
+		LogMatch:                ThemeColors{Foreground: "#000000", Background: "#ffff00"},
+		LogCurrentMatch:         ThemeColors{Foreground: "#000000", Background: "#00ffff"},
+		Labels: map[string]ThemeColors{
+			"AWS":     {Foreground: "#000000", Background: "#ffffff", Bold: true},
+			"Azure":   {Foreground: "#000000", Background: "#ffffff", Bold: true},
+			"GCP":     {Foreground: "#000000", Background: "#ffffff", Bold: true},
+			"Tanzu":   {Foreground: "#000000", Background: "#ffffff", Bold: true},
+			"Simple":  {Foreground: "#000000", Background: "#ffffff", Bold: true},
+			"Err":     {Foreground: "#ffffff", Background: "#ff0000", Bold: true},
+			"Update":  {Foreground: "#000000", Background: "#00ff00", Bold: true},
+			"Session": {Foreground: "#000000", Background: "#ff00ff", Bold: true},
+			"Host":    {Foreground: "#000000", Background: "#00ffff", Bold: true},
+		},
+	},
+	"no-colour": {
+		NoColor: true,
+	},
+}
+
+// ThemeNames returns the names of the built-in themes
+func ThemeNames() []string {
+	return []string{"auto", "dark", "light", "high-contrast", "no-colour"}
+}
+
+// DefaultThemeDir returns the user directory that can hold theme files
+func DefaultThemeDir() string {
+	if d, err := os.UserConfigDir(); err == nil {
+		return filepath.Join(d, "ccmanager", "themes")
+	}
+	return ""
+}
+
+// LoadTheme returns the theme with the given name. The name is either the name of a built-in theme, the name of a
+// file <name>.yaml in themeDir or the path to a theme file. "auto" selects the dark or light theme depending on the
+// terminal background. If NO_COLOR is set, the no-colour theme is always used
+func LoadTheme(name string, themeDir string) (Theme, error) {
+	if os.Getenv("NO_COLOR") != "" {
+		return Themes["no-colour"], nil
+	}
+	if name == "" || name == "auto" {
+		if lipgloss.HasDarkBackground() {
+			return Themes["dark"], nil
+		}
+		return Themes["light"], nil
+	}
+	if t, ok := Themes[name]; ok {
+		return t, nil
+	}
+
+	path := name
+	if !strings.ContainsRune(name, os.PathSeparator) && filepath.Ext(name) == "" {
+		path = filepath.Join(themeDir, fmt.Sprintf("%s.yaml", name))
+	}
+	content, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return Theme{}, fmt.Errorf("unknown theme %s", name)
+	} else if err != nil {
+		return Theme{}, fmt.Errorf("can not read theme %s: %w", name, err)
+	}
+
+	// User themes override the colours of their base theme
+	var base struct {
+		Base string `yaml:"base"`
+	}
+	if err := yaml.Unmarshal(content, &base); err != nil {
+		return Theme{}, fmt.Errorf("can not parse theme %s: %w", name, err)
+	}
+	if base.Base == "" {
+		base.Base = "dark"
+	}
+	t, ok := Themes[base.Base]
+	if !ok {
+		return Theme{}, fmt.Errorf("unknown base theme %s in theme %s", base.Base, name)
+	}
+	labels := map[string]ThemeColors{}
+	for k, v := range t.Labels {
+		labels[k] = v
+	}
+	t.Labels = labels
+	if err := yaml.Unmarshal(content, &t); err != nil {
+		return Theme{}, fmt.Errorf("can not parse theme %s: %w", name, err)
+	}
+	return t, nil
+}
+
+// ApplyTheme sets up all styles using the given theme
+func ApplyTheme(t Theme) {
+	if t.NoColor {
+		lipgloss.SetColorProfile(termenv.Ascii)
+	}
+
+	TitleStyle = t.Title.style(lipgloss.NewStyle().Padding(0, 1))
+
+	ItemTitleStyle = t.Item.style(lipgloss.NewStyle().Padding(0, 0, 0, 2))
+	ItemDescriptionStyle = t.ItemDescription.style(ItemTitleStyle.Copy())
+
+	SelectedItemTitleStyle = t.SelectedItem.style(lipgloss.NewStyle().
+		Border(lipgloss.NormalBorder(), false, false, false, true).
+		BorderForeground(lipgloss.Color(t.SelectedItemDescription.Foreground)).
+		Padding(0, 0, 0, 1))
+	SelectedItemDescriptionStyle = t.SelectedItemDescription.style(SelectedItemTitleStyle.Copy())
+
+	SelectedChoiceStyle = t.SelectedChoice.style(lipgloss.NewStyle().Padding(0))
+
+	ErrorMessageStyle = t.Error.style(lipgloss.NewStyle()).Render
+
+	InfoBoxStyle = lipgloss.NewStyle().
+		Border(lipgloss.NormalBorder()).
+		BorderForeground(lipgloss.Color(t.Border.Foreground)).
+		Padding(0, 1)
+
+	StatusLineStyle = t.StatusLine.style(lipgloss.NewStyle().PaddingLeft(1))
+
+	LogMatchStyle = t.LogMatch.style(lipgloss.NewStyle())
+	LogCurrentMatchStyle = t.LogCurrentMatch.style(lipgloss.NewStyle())
+
+	Labels = map[string]string{}
+	for name, text := range labelTexts {
+		Labels[name] = t.Labels[name].style(lipgloss.NewStyle().Padding(0, 1)).Render(text)
+	}
+	HostLabelStyle = t.Labels["Host"].style(lipgloss.NewStyle().Padding(0, 1))
+}
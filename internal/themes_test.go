@@ -0,0 +1,88 @@
+package internal
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// writeTheme writes a theme file into dir
+func writeTheme(t *testing.T, dir string, name string, content string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadThemeBuiltIn(t *testing.T) {
+	t.Setenv("NO_COLOR", "")
+	theme, err := LoadTheme("high-contrast", t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if theme.Title != Themes["high-contrast"].Title {
+		t.Errorf("got title %+v, want the one of the built-in theme", theme.Title)
+	}
+}
+
+func TestLoadThemeNoColor(t *testing.T) {
+	t.Setenv("NO_COLOR", "1")
+	theme, err := LoadTheme("dark", t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !theme.NoColor {
+		t.Error("NO_COLOR doesn't select the no-colour theme")
+	}
+}
+
+func TestLoadThemeFile(t *testing.T) {
+	t.Setenv("NO_COLOR", "")
+	dir := t.TempDir()
+	writeTheme(t, dir, "solarized.yaml", `
+base: light
+item:
+  foreground: "#586e75"
+labels:
+  AWS:
+    foreground: "#000000"
+    background: "#b58900"
+`)
+	for _, name := range []string{"solarized", filepath.Join(dir, "solarized.yaml")} {
+		t.Run(name, func(t *testing.T) {
+			theme, err := LoadTheme(name, dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if theme.Item.Foreground != "#586e75" {
+				t.Errorf("item colour %q, want the one of the file", theme.Item.Foreground)
+			}
+			// Unset colours come from the base theme
+			if theme.ItemDescription != Themes["light"].ItemDescription || theme.Labels["GCP"] != defaultLabels["GCP"] {
+				t.Error("colours missing in the file don't use the base theme")
+			}
+			if theme.Labels["AWS"].Background != "#b58900" {
+				t.Errorf("AWS label %+v, want the colours of the file", theme.Labels["AWS"])
+			}
+		})
+	}
+	if defaultLabels["AWS"].Background != "#ff9900" {
+		t.Error("loading a theme changed the labels of the built-in themes")
+	}
+}
+
+func TestLoadThemeErrors(t *testing.T) {
+	t.Setenv("NO_COLOR", "")
+	dir := t.TempDir()
+	writeTheme(t, dir, "broken.yaml", "item: [")
+	writeTheme(t, dir, "unknown-base.yaml", "base: sepia")
+	for _, name := range []string{"missing", "broken", "unknown-base"} {
+		t.Run(name, func(t *testing.T) {
+			if _, err := LoadTheme(name, dir); err == nil {
+				t.Errorf("loading theme %s didn't fail", name)
+			}
+		})
+	}
+}
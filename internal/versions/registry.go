@@ -0,0 +1,124 @@
+package versions
+
+import (
+	"context"
+	"fmt"
+	resty "github.com/go-resty/resty/v2"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+// dockerHub is the registry host of images without a registry
+const dockerHub = "registry-1.docker.io"
+
+// authParameterRegexp matches the parameters of a WWW-Authenticate header
+var authParameterRegexp = regexp.MustCompile(`(\w+)="([^"]*)"`)
+
+// linkRegexp matches the URL of the next page in a Link header
+var linkRegexp = regexp.MustCompile(`<([^>]+)>;\s*rel="next"`)
+
+// RegistryTagLister lists tags using the registry HTTP API (https://distribution.github.io/distribution/spec/api/).
+// Anonymous tokens are requested for registries that require them
+type RegistryTagLister struct{}
+
+var _ TagLister = RegistryTagLister{}
+
+// ListTags lists the tags of an image like ghcr.io/dodevops/cloudcontrol-azure
+func (r RegistryTagLister) ListTags(ctx context.Context, image string) ([]string, error) {
+	baseURL, repository := splitRegistry(image)
+	c := resty.New()
+	c.SetCloseConnection(true)
+
+	var tags []string
+	token := ""
+	authenticated := false
+	next := fmt.Sprintf("/v2/%s/tags/list?n=1000", repository)
+	for next != "" {
+		result := struct {
+			Tags []string `json:"tags"`
+		}{}
+		request := c.R().SetContext(ctx).SetResult(&result).ForceContentType("application/json")
+		if token != "" {
+			request.SetAuthToken(token)
+		}
+		resp, err := request.Get(baseURL + next)
+		if err != nil {
+			return nil, fmt.Errorf("can not list tags of %s: %w", image, err)
+		}
+		if resp.StatusCode() == http.StatusUnauthorized && !authenticated {
+			authenticated = true
+			if token, err = getToken(ctx, c, resp.Header().Get("WWW-Authenticate")); err != nil {
+				return nil, fmt.Errorf("can not list tags of %s: %w", image, err)
+			}
+			continue
+		}
+		if resp.IsError() {
+			return nil, fmt.Errorf("can not list tags of %s: %s", image, resp.Status())
+		}
+		tags = append(tags, result.Tags...)
+
+		next = ""
+		if m := linkRegexp.FindStringSubmatch(resp.Header().Get("Link")); m != nil {
+			next = strings.TrimPrefix(m[1], baseURL)
+		}
+	}
+	return tags, nil
+}
+
+// getToken requests an anonymous token using the parameters of a WWW-Authenticate header
+func getToken(ctx context.Context, c *resty.Client, authenticate string) (string, error) {
+	if !strings.HasPrefix(strings.ToLower(authenticate), "bearer ") {
+		return "", fmt.Errorf("unsupported authentication %s", authenticate)
+	}
+	parameters := map[string]string{}
+	for _, m := range authParameterRegexp.FindAllStringSubmatch(authenticate, -1) {
+		parameters[m[1]] = m[2]
+	}
+	realm := parameters["realm"]
+	if realm == "" {
+		return "", fmt.Errorf("no realm in %s", authenticate)
+	}
+	delete(parameters, "realm")
+
+	result := struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}{}
+	resp, err := c.R().
+		SetContext(ctx).
+		SetQueryParams(parameters).
+		SetResult(&result).
+		ForceContentType("application/json").
+		Get(realm)
+	if err != nil {
+		return "", fmt.Errorf("can not get token: %w", err)
+	}
+	if resp.IsError() {
+		return "", fmt.Errorf("can not get token: %s", resp.Status())
+	}
+	if result.Token != "" {
+		return result.Token, nil
+	}
+	return result.AccessToken, nil
+}
+
+// splitRegistry splits an image into the base URL of its registry and the repository. Registries on localhost are
+// accessed using plain HTTP
+func splitRegistry(image string) (string, string) {
+	host := dockerHub
+	repository := image
+	if parts := strings.SplitN(image, "/", 2); len(parts) == 2 &&
+		(strings.ContainsAny(parts[0], ".:") || parts[0] == "localhost") {
+		host = parts[0]
+		repository = parts[1]
+	}
+	if host == dockerHub && !strings.Contains(repository, "/") {
+		repository = fmt.Sprintf("library/%s", repository)
+	}
+	scheme := "https"
+	if hostname := strings.Split(host, ":")[0]; hostname == "localhost" || hostname == "127.0.0.1" {
+		scheme = "http"
+	}
+	return fmt.Sprintf("%s://%s", scheme, host), repository
+}
@@ -0,0 +1,79 @@
+package versions
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestRegistryTagLister(t *testing.T) {
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.URL.Path == "/token":
+			if r.URL.Query().Get("scope") != "repository:dodevops/cloudcontrol-azure:pull" {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			_, _ = fmt.Fprint(w, `{"token": "secret"}`)
+		case r.Header.Get("Authorization") != "Bearer secret":
+			w.Header().Set("WWW-Authenticate", fmt.Sprintf(
+				`Bearer realm="%s/token",service="registry",scope="repository:dodevops/cloudcontrol-azure:pull"`,
+				server.URL,
+			))
+			w.WriteHeader(http.StatusUnauthorized)
+		case r.URL.Path != "/v2/dodevops/cloudcontrol-azure/tags/list":
+			w.WriteHeader(http.StatusNotFound)
+		case r.URL.Query().Get("last") == "":
+			w.Header().Set("Link", `</v2/dodevops/cloudcontrol-azure/tags/list?n=1000&last=5.0.0>; rel="next"`)
+			_, _ = fmt.Fprint(w, `{"tags": ["4.0.0", "5.0.0"]}`)
+		default:
+			_, _ = fmt.Fprint(w, `{"tags": ["5.1.0", "latest"]}`)
+		}
+	}))
+	defer server.Close()
+
+	image := fmt.Sprintf("%s/dodevops/cloudcontrol-azure", strings.TrimPrefix(server.URL, "http://"))
+	tags, err := RegistryTagLister{}.ListTags(context.Background(), image)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"4.0.0", "5.0.0", "5.1.0", "latest"}; !reflect.DeepEqual(tags, want) {
+		t.Errorf("ListTags() = %v, want %v", tags, want)
+	}
+}
+
+func TestRegistryTagListerError(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	defer server.Close()
+
+	image := fmt.Sprintf("%s/dodevops/missing", strings.TrimPrefix(server.URL, "http://"))
+	if _, err := (RegistryTagLister{}).ListTags(context.Background(), image); err == nil {
+		t.Error("ListTags() didn't fail for a missing repository")
+	}
+}
+
+func TestSplitRegistry(t *testing.T) {
+	tests := []struct {
+		image      string
+		baseURL    string
+		repository string
+	}{
+		{"ghcr.io/dodevops/cloudcontrol-azure", "https://ghcr.io", "dodevops/cloudcontrol-azure"},
+		{"dodevops/cloudcontrol-azure", "https://registry-1.docker.io", "dodevops/cloudcontrol-azure"},
+		{"postgres", "https://registry-1.docker.io", "library/postgres"},
+		{"localhost:5000/cloudcontrol", "http://localhost:5000", "cloudcontrol"},
+	}
+	for _, test := range tests {
+		baseURL, repository := splitRegistry(test.image)
+		if baseURL != test.baseURL || repository != test.repository {
+			t.Errorf("splitRegistry(%q) = %q, %q, want %q, %q", test.image, baseURL, repository, test.baseURL,
+				test.repository)
+		}
+	}
+}
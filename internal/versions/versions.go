@@ -0,0 +1,104 @@
+package versions
+
+// Detection of newer CloudControl images
+
+import (
+	"context"
+	"github.com/Masterminds/semver/v3"
+	"sync"
+	"time"
+)
+
+// TagLister lists the tags of an image
+type TagLister interface {
+	// ListTags returns all tags of an image (without a tag) in its registry
+	ListTags(ctx context.Context, image string) ([]string, error)
+}
+
+// DefaultCheckInterval is the time after which the tags of an image are listed again
+const DefaultCheckInterval = time.Hour
+
+// checkResult holds the cached tags of an image
+type checkResult struct {
+	// tags holds the tags of the image
+	tags []string
+	// checked is the time when the tags were listed
+	checked time.Time
+	// pending tells whether the tags are currently being listed
+	pending bool
+}
+
+// Checker checks for newer tags of images and caches the tags of every image
+type Checker struct {
+	// lister lists the tags of an image
+	lister TagLister
+	// interval is the time after which the tags of an image are listed again
+	interval time.Duration
+	// mutex guards results
+	mutex sync.Mutex
+	// results holds the cached tags by image
+	results map[string]checkResult
+}
+
+// NewChecker creates a Checker using the given TagLister
+func NewChecker(lister TagLister, interval time.Duration) *Checker {
+	return &Checker{
+		lister:   lister,
+		interval: interval,
+		results:  map[string]checkResult{},
+	}
+}
+
+// Lookup returns the newest tag of an image that is newer than the given tag from the cache. It returns an empty
+// string if no newer tag is known. needsCheck is true if the tags of the image are not cached or outdated. In that
+// case, Check should be called. Subsequent lookups don't request another check until Check has finished
+func (c *Checker) Lookup(image string, tag string) (newerTag string, needsCheck bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	r, ok := c.results[image]
+	if !r.pending && (!ok || time.Since(r.checked) > c.interval) {
+		r.pending = true
+		c.results[image] = r
+		needsCheck = true
+	}
+	return NewestTag(r.tags, tag), needsCheck
+}
+
+// Check lists the tags of an image and caches them. Failed checks are cached as well, so they are only retried
+// after the check interval
+func (c *Checker) Check(ctx context.Context, image string) error {
+	tags, err := c.lister.ListTags(ctx, image)
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	r := c.results[image]
+	if err == nil {
+		r.tags = tags
+	}
+	r.checked = time.Now()
+	r.pending = false
+	c.results[image] = r
+	return err
+}
+
+// NewestTag returns the newest tag of tags that is a newer semantic version than current. Pre-releases are ignored.
+// If current is no semantic version (e.g. "latest") or no newer tag exists, an empty string is returned
+func NewestTag(tags []string, current string) string {
+	currentVersion, err := semver.NewVersion(current)
+	if err != nil {
+		return ""
+	}
+	var newest *semver.Version
+	for _, tag := range tags {
+		v, err := semver.NewVersion(tag)
+		if err != nil || v.Prerelease() != "" || !v.GreaterThan(currentVersion) {
+			continue
+		}
+		if newest == nil || v.GreaterThan(newest) {
+			newest = v
+		}
+	}
+	if newest == nil {
+		return ""
+	}
+	return newest.Original()
+}
@@ -0,0 +1,124 @@
+package versions
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeTagLister returns fixed tags and counts the calls
+type fakeTagLister struct {
+	lock  sync.Mutex
+	tags  []string
+	err   error
+	calls int
+}
+
+func (f *fakeTagLister) ListTags(_ context.Context, _ string) ([]string, error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.calls++
+	return f.tags, f.err
+}
+
+func TestNewestTag(t *testing.T) {
+	tags := []string{"latest", "4.2.0", "5.0.0", "5.10.0", "5.9.1", "6.0.0-rc.1", "5.2.0-beta", "main"}
+	tests := []struct {
+		current string
+		want    string
+	}{
+		{current: "5.0.0", want: "5.10.0"},
+		{current: "5.9.1", want: "5.10.0"},
+		{current: "5.10.0", want: ""},
+		{current: "6.0.0-rc.1", want: ""},
+		{current: "4", want: "5.10.0"},
+		{current: "latest", want: ""},
+		{current: "", want: ""},
+	}
+	for _, test := range tests {
+		if got := NewestTag(tags, test.current); got != test.want {
+			t.Errorf("NewestTag(%q) = %q, want %q", test.current, got, test.want)
+		}
+	}
+}
+
+func TestNewestTagKeepsOriginal(t *testing.T) {
+	if got := NewestTag([]string{"v1.1", "1.0.1"}, "1.0.0"); got != "v1.1" {
+		t.Errorf("NewestTag() = %q, want v1.1", got)
+	}
+}
+
+func TestCheckerLookup(t *testing.T) {
+	lister := &fakeTagLister{tags: []string{"1.0.0", "1.1.0", "2.0.0-alpha"}}
+	c := NewChecker(lister, time.Hour)
+
+	if newer, needsCheck := c.Lookup("image", "1.0.0"); newer != "" || !needsCheck {
+		t.Fatalf("first Lookup() = %q, %v, want \"\", true", newer, needsCheck)
+	}
+	if _, needsCheck := c.Lookup("image", "1.0.0"); needsCheck {
+		t.Error("Lookup() requested a second check while the first one is pending")
+	}
+	if err := c.Check(context.Background(), "image"); err != nil {
+		t.Fatal(err)
+	}
+	if newer, needsCheck := c.Lookup("image", "1.0.0"); newer != "1.1.0" || needsCheck {
+		t.Errorf("Lookup() after Check() = %q, %v, want 1.1.0, false", newer, needsCheck)
+	}
+	if _, needsCheck := c.Lookup("other", "1.0.0"); !needsCheck {
+		t.Error("Lookup() of another image didn't request a check")
+	}
+	if lister.calls != 1 {
+		t.Errorf("listed tags %d times, want 1", lister.calls)
+	}
+}
+
+func TestCheckerInterval(t *testing.T) {
+	lister := &fakeTagLister{tags: []string{"1.1.0"}}
+	c := NewChecker(lister, time.Hour)
+	c.Lookup("image", "1.0.0")
+	if err := c.Check(context.Background(), "image"); err != nil {
+		t.Fatal(err)
+	}
+	if _, needsCheck := c.Lookup("image", "1.0.0"); needsCheck {
+		t.Error("Lookup() requested a check within the check interval")
+	}
+
+	// Age the cached result beyond the check interval
+	r := c.results["image"]
+	r.checked = time.Now().Add(-2 * time.Hour)
+	c.results["image"] = r
+	newer, needsCheck := c.Lookup("image", "1.0.0")
+	if !needsCheck {
+		t.Error("Lookup() didn't request a check after the check interval")
+	}
+	if newer != "1.1.0" {
+		t.Errorf("Lookup() = %q, want the cached 1.1.0 until the check finished", newer)
+	}
+}
+
+func TestCheckerCachesErrors(t *testing.T) {
+	lister := &fakeTagLister{tags: []string{"1.1.0"}}
+	c := NewChecker(lister, time.Hour)
+	c.Lookup("image", "1.0.0")
+	if err := c.Check(context.Background(), "image"); err != nil {
+		t.Fatal(err)
+	}
+
+	r := c.results["image"]
+	r.checked = time.Time{}
+	c.results["image"] = r
+	lister.err = errors.New("registry unavailable")
+	c.Lookup("image", "1.0.0")
+	if err := c.Check(context.Background(), "image"); err == nil {
+		t.Fatal("Check() didn't return the error of the lister")
+	}
+	newer, needsCheck := c.Lookup("image", "1.0.0")
+	if needsCheck {
+		t.Error("Lookup() requested a check right after a failed check")
+	}
+	if newer != "1.1.0" {
+		t.Errorf("Lookup() = %q, want the tags of the last successful check", newer)
+	}
+}